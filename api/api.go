@@ -0,0 +1,437 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"alex-j-butler.com/tf2-booking/booking"
+	"alex-j-butler.com/tf2-booking/events"
+	"alex-j-butler.com/tf2-booking/history"
+	"alex-j-butler.com/tf2-booking/logging"
+	"alex-j-butler.com/tf2-booking/servers"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/bwmarrin/discordgo"
+	"github.com/gorilla/mux"
+)
+
+// DefaultHistoryLimit is the number of history entries returned when no limit is requested.
+const DefaultHistoryLimit = 50
+
+// Client is an API client, authenticated by its key.
+type Client struct {
+	Name string
+	Key  string
+
+	// UserID is the Discord user the client books servers as.
+	UserID string
+
+	// Admin clients may act as any user with the 'user_id' query parameter,
+	// force unbook any server & read everyone's history.
+	Admin bool
+}
+
+// Bookings performs the booking operations, the same as the Discord commands.
+type Bookings interface {
+	Book(user *discordgo.User, serverName string, preset string, duration time.Duration) (*booking.Booking, error)
+	Unbook(userID string) (*servers.Server, error)
+	Release(server *servers.Server, reason events.EndReason) error
+	Extend(userID string, duration time.Duration) (*servers.Server, time.Duration, error)
+	Password(userID string) (*servers.Server, string, error)
+}
+
+// History lists the finished bookings.
+type History interface {
+	List(userID string, limit int) ([]history.Entry, error)
+}
+
+// Server is the REST API for the booking operations.
+type Server struct {
+	Pool     servers.ServerPool
+	Bookings Bookings
+	History  History
+	Clients  []Client
+
+	// User returns the Discord user with the ID, used to name the booker of API bookings.
+	// If nil or it fails, the booker is only known by their ID.
+	User func(userID string) (*discordgo.User, error)
+}
+
+// Handler returns the HTTP handler of the API routes.
+func (s *Server) Handler() http.Handler {
+	r := mux.NewRouter()
+
+	r.HandleFunc("/servers", s.authenticated(s.listServers)).Methods(http.MethodGet)
+	r.HandleFunc("/servers/{name}/booking", s.authenticated(s.admin(s.forceUnbook))).Methods(http.MethodDelete)
+
+	r.HandleFunc("/booking", s.authenticated(s.user(s.getBooking))).Methods(http.MethodGet)
+	r.HandleFunc("/booking", s.authenticated(s.user(s.book))).Methods(http.MethodPost)
+	r.HandleFunc("/booking", s.authenticated(s.user(s.unbook))).Methods(http.MethodDelete)
+	r.HandleFunc("/booking/extend", s.authenticated(s.user(s.extend))).Methods(http.MethodPost)
+
+	r.HandleFunc("/history", s.authenticated(s.userOrAdmin(s.listHistory))).Methods(http.MethodGet)
+
+	return r
+}
+
+// clientHandlerFunc is a handler of an authenticated request.
+// The user ID is the Discord user the request acts as.
+type clientHandlerFunc func(w http.ResponseWriter, r *http.Request, client *Client, userID string)
+
+// authenticated checks the request's API key, from either the 'Authorization: Bearer <key>'
+// or 'X-API-Key' headers.
+func (s *Server) authenticated(handler clientHandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		client := s.client(requestKey(r))
+		if client == nil {
+			writeError(w, http.StatusUnauthorized, "invalid API key")
+			return
+		}
+
+		userID := client.UserID
+		if client.Admin && r.URL.Query().Get("user_id") != "" {
+			userID = r.URL.Query().Get("user_id")
+		}
+
+		handler(w, r, client, userID)
+	}
+}
+
+// admin only allows admin clients to call the handler.
+func (s *Server) admin(handler clientHandlerFunc) clientHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, client *Client, userID string) {
+		if !client.Admin {
+			writeError(w, http.StatusForbidden, "admin API key required")
+			return
+		}
+
+		handler(w, r, client, userID)
+	}
+}
+
+// user only calls the handler for requests acting as a user,
+// which admin clients without their own user must choose with 'user_id'.
+func (s *Server) user(handler clientHandlerFunc) clientHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, client *Client, userID string) {
+		if userID == "" {
+			writeError(w, http.StatusBadRequest, "user_id is required")
+			return
+		}
+
+		handler(w, r, client, userID)
+	}
+}
+
+// userOrAdmin only calls the handler for requests acting as a user, or from admin clients,
+// which act on every user when they don't choose one with 'user_id'.
+func (s *Server) userOrAdmin(handler clientHandlerFunc) clientHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, client *Client, userID string) {
+		if userID == "" && !client.Admin {
+			writeError(w, http.StatusForbidden, "API key has no user")
+			return
+		}
+
+		handler(w, r, client, userID)
+	}
+}
+
+// client returns the client with the key, nil if none.
+func (s *Server) client(key string) *Client {
+	if key == "" {
+		return nil
+	}
+
+	for i := range s.Clients {
+		if subtle.ConstantTimeCompare([]byte(s.Clients[i].Key), []byte(key)) == 1 {
+			return &s.Clients[i]
+		}
+	}
+
+	return nil
+}
+
+func requestKey(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+
+	return r.Header.Get("X-API-Key")
+}
+
+// serverState is a server in the server list.
+type serverState struct {
+	Name      string     `json:"name"`
+	Address   string     `json:"address"`
+	Available bool       `json:"available"`
+	Booked    bool       `json:"booked"`
+	Booker    string     `json:"booker,omitempty"`
+	BookedAt  *time.Time `json:"booked_at,omitempty"`
+	ReturnAt  *time.Time `json:"return_at,omitempty"`
+}
+
+func newServerState(server *servers.Server) serverState {
+	state := serverState{
+		Name:      server.Name,
+		Address:   server.Address,
+		Available: server.Available() && !server.IsBooked(),
+		Booked:    server.IsBooked(),
+	}
+
+	if state.Booked {
+		state.Booker = server.Booker
+		state.BookedAt = timePtr(server.BookedDate)
+		state.ReturnAt = timePtr(server.ReturnDate)
+	}
+
+	return state
+}
+
+func (s *Server) listServers(w http.ResponseWriter, r *http.Request, client *Client, userID string) {
+	states := []serverState{}
+	for _, server := range s.Pool.GetServers() {
+		states = append(states, newServerState(server))
+	}
+
+	writeJSON(w, http.StatusOK, states)
+}
+
+// bookingDetails are the connect details of a booked server.
+type bookingDetails struct {
+	Server       string     `json:"server"`
+	Address      string     `json:"address"`
+	STVAddress   string     `json:"stv_address,omitempty"`
+	Password     string     `json:"password"`
+	RCONPassword string     `json:"rcon_password"`
+	Connect      string     `json:"connect"`
+	BookedAt     *time.Time `json:"booked_at,omitempty"`
+	ReturnAt     *time.Time `json:"return_at,omitempty"`
+	TimeLeft     int64      `json:"time_left,omitempty"`
+}
+
+func newBookingDetails(server *servers.Server, password string, rconPassword string) bookingDetails {
+	details := bookingDetails{
+		Server:       server.Name,
+		Address:      server.Address,
+		STVAddress:   server.STVAddress,
+		Password:     password,
+		RCONPassword: rconPassword,
+		Connect:      "connect " + server.Address + "; password " + password,
+		BookedAt:     timePtr(server.BookedDate),
+		ReturnAt:     timePtr(server.ReturnDate),
+	}
+
+	if !server.ReturnDate.IsZero() {
+		details.TimeLeft = int64(server.TimeLeft() / time.Second)
+	}
+
+	return details
+}
+
+func (s *Server) getBooking(w http.ResponseWriter, r *http.Request, client *Client, userID string) {
+	server, password, err := s.Bookings.Password(userID)
+	if err != nil {
+		writeBookingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newBookingDetails(server, password, server.RCONPassword))
+}
+
+// bookRequest is the body of a booking request, all fields are optional.
+type bookRequest struct {
+	Server string `json:"server"`
+	Preset string `json:"preset"`
+
+	// Duration is a duration string, eg. "2h30m".
+	Duration string `json:"duration"`
+}
+
+func (s *Server) book(w http.ResponseWriter, r *http.Request, client *Client, userID string) {
+	var req bookRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+
+	duration, err := parseDuration(req.Duration)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid duration")
+		return
+	}
+
+	b, err := s.Bookings.Book(s.discordUser(userID), req.Server, req.Preset, duration)
+	if err != nil {
+		writeBookingError(w, err)
+		return
+	}
+
+	b.Server.Log().WithField("client", client.Name).Info("API client booked server")
+
+	writeJSON(w, http.StatusCreated, newBookingDetails(b.Server, b.ServerPassword, b.RCONPassword))
+}
+
+func (s *Server) unbook(w http.ResponseWriter, r *http.Request, client *Client, userID string) {
+	server, err := s.Bookings.Unbook(userID)
+	if err != nil {
+		writeBookingError(w, err)
+		return
+	}
+
+	logging.User(userID).WithFields(log.Fields{logging.ServerField: server.Name, "client": client.Name}).Info("API client unbooked server")
+
+	writeJSON(w, http.StatusOK, map[string]string{"server": server.Name})
+}
+
+// extendRequest is the body of an extend request.
+type extendRequest struct {
+	// Duration is a duration string, eg. "30m", the configured idle time if empty.
+	Duration string `json:"duration"`
+}
+
+func (s *Server) extend(w http.ResponseWriter, r *http.Request, client *Client, userID string) {
+	var req extendRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+
+	duration, err := parseDuration(req.Duration)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid duration")
+		return
+	}
+
+	server, extended, err := s.Bookings.Extend(userID, duration)
+	if err != nil {
+		writeBookingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"server":    server.Name,
+		"extended":  int64(extended / time.Second),
+		"time_left": int64(server.TimeLeft() / time.Second),
+	})
+}
+
+func (s *Server) forceUnbook(w http.ResponseWriter, r *http.Request, client *Client, userID string) {
+	server, err := s.Pool.GetServerByName(mux.Vars(r)["name"])
+	if err != nil || server == nil {
+		writeError(w, http.StatusNotFound, "server not found")
+		return
+	}
+
+	if !server.IsBooked() {
+		writeError(w, http.StatusConflict, "server isn't booked")
+		return
+	}
+
+	if err := s.Bookings.Release(server, events.Forced); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to unbook the server")
+		return
+	}
+
+	server.Log().WithField("client", client.Name).Info("API client force unbooked server")
+
+	writeJSON(w, http.StatusOK, map[string]string{"server": server.Name})
+}
+
+// listHistory returns the finished bookings of the user.
+// Admin clients see every user's bookings, unless they request a user with 'user_id'.
+func (s *Server) listHistory(w http.ResponseWriter, r *http.Request, client *Client, userID string) {
+	limit := DefaultHistoryLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		l, err := strconv.Atoi(value)
+		if err != nil || l < 0 {
+			writeError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = l
+	}
+
+	entries, err := s.History.List(userID, limit)
+	if err != nil {
+		log.WithError(err).WithField("client", client.Name).Error("Failed to list booking history")
+		writeError(w, http.StatusInternalServerError, "failed to list booking history")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// discordUser returns the Discord user to book as.
+func (s *Server) discordUser(userID string) *discordgo.User {
+	if s.User != nil {
+		if user, err := s.User(userID); err == nil && user != nil {
+			return user
+		}
+	}
+
+	return &discordgo.User{ID: userID}
+}
+
+// parseDuration parses a duration string, or a number of minutes.
+// An empty string returns zero, for the default duration.
+func parseDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	if minutes, err := strconv.Atoi(value); err == nil {
+		return time.Duration(minutes) * time.Minute, nil
+	}
+
+	return time.ParseDuration(value)
+}
+
+// bookingErrorStatuses are the HTTP statuses of the booking errors.
+var bookingErrorStatuses = map[error]int{
+	booking.ErrAlreadyBooked:      http.StatusConflict,
+	booking.ErrNotBooked:          http.StatusNotFound,
+	booking.ErrNoServersAvailable: http.StatusServiceUnavailable,
+	booking.ErrServerUnavailable:  http.StatusConflict,
+	booking.ErrUnknownPreset:      http.StatusBadRequest,
+	booking.ErrBookFailed:         http.StatusInternalServerError,
+	booking.ErrMaxDuration:        http.StatusConflict,
+}
+
+func writeBookingError(w http.ResponseWriter, err error) {
+	status, ok := bookingErrorStatuses[err]
+	if !ok {
+		log.WithError(err).Error("API booking error")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	writeError(w, status, strings.TrimPrefix(err.Error(), "booking: "))
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.WithError(err).Warn("Failed to write API response")
+	}
+}
+
+// timePtr returns a pointer to the time, nil if it's zero so it's omitted from the response.
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
@@ -0,0 +1,246 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"alex-j-butler.com/tf2-booking/booking"
+	"alex-j-butler.com/tf2-booking/events"
+	"alex-j-butler.com/tf2-booking/history"
+	"alex-j-butler.com/tf2-booking/servers"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// bookings is a booking manager with a single server, booked by at most one user.
+type bookings struct {
+	server   *servers.Server
+	bookedBy string
+	released bool
+}
+
+func (b *bookings) Book(user *discordgo.User, serverName string, preset string, duration time.Duration) (*booking.Booking, error) {
+	if preset != "" && preset != "ultiduo" {
+		return nil, booking.ErrUnknownPreset
+	}
+	if b.bookedBy != "" {
+		return nil, booking.ErrNoServersAvailable
+	}
+
+	b.bookedBy = user.ID
+	b.server.ReturnDate = time.Now().Add(duration)
+	return &booking.Booking{Server: b.server, ServerPassword: "password", RCONPassword: "rcon"}, nil
+}
+
+func (b *bookings) Unbook(userID string) (*servers.Server, error) {
+	if b.bookedBy != userID {
+		return nil, booking.ErrNotBooked
+	}
+
+	b.bookedBy = ""
+	return b.server, nil
+}
+
+func (b *bookings) Release(server *servers.Server, reason events.EndReason) error {
+	b.released = true
+	return nil
+}
+
+func (b *bookings) Extend(userID string, duration time.Duration) (*servers.Server, time.Duration, error) {
+	if b.bookedBy != userID {
+		return nil, 0, booking.ErrNotBooked
+	}
+
+	return b.server, duration, nil
+}
+
+func (b *bookings) Password(userID string) (*servers.Server, string, error) {
+	if b.bookedBy != userID {
+		return nil, "", booking.ErrNotBooked
+	}
+
+	return b.server, "password", nil
+}
+
+// historyList is a history of fixed entries.
+type historyList []history.Entry
+
+func (h historyList) List(userID string, limit int) ([]history.Entry, error) {
+	entries := []history.Entry{}
+	for _, entry := range h {
+		if userID == "" || entry.UserID == userID {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func newTestServer() (*Server, *bookings) {
+	b := &bookings{server: &servers.Server{Name: "Server 1", Address: "127.0.0.1:27015"}}
+
+	return &Server{
+		Bookings: b,
+		History: historyList{
+			{Server: "Server 1", UserID: "1", Reason: "returned"},
+			{Server: "Server 1", UserID: "2", Reason: "idle"},
+		},
+		Clients: []Client{
+			{Name: "website", Key: "user-key", UserID: "1"},
+			{Name: "scripts", Key: "admin-key", Admin: true},
+			{Name: "misconfigured", Key: "no-user-key"},
+		},
+	}, b
+}
+
+func request(t *testing.T, handler http.Handler, method string, path string, key string, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var response map[string]interface{}
+	json.Unmarshal(rec.Body.Bytes(), &response)
+
+	return rec, response
+}
+
+func TestAuthentication(t *testing.T) {
+	s, _ := newTestServer()
+	handler := s.Handler()
+
+	if rec, _ := request(t, handler, http.MethodGet, "/booking", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a request without a key to be unauthorised, got %d", rec.Code)
+	}
+	if rec, _ := request(t, handler, http.MethodGet, "/booking", "wrong-key", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a request with an invalid key to be unauthorised, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/booking", nil)
+	req.Header.Set("X-API-Key", "user-key")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected the X-API-Key header to authenticate, got %d", rec.Code)
+	}
+}
+
+func TestBookingLifecycle(t *testing.T) {
+	s, b := newTestServer()
+	handler := s.Handler()
+
+	rec, response := request(t, handler, http.MethodPost, "/booking", "user-key", `{"preset": "ultiduo", "duration": "2h"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected the booking to be created, got %d: %v", rec.Code, response)
+	}
+	if b.bookedBy != "1" {
+		t.Errorf("Expected the server to be booked as the client's user, got \"%s\"", b.bookedBy)
+	}
+	if response["connect"] != "connect 127.0.0.1:27015; password password" {
+		t.Errorf("Unexpected connect string %v", response["connect"])
+	}
+
+	if rec, response := request(t, handler, http.MethodGet, "/booking", "user-key", ""); rec.Code != http.StatusOK || response["password"] != "password" {
+		t.Errorf("Expected the connect details, got %d: %v", rec.Code, response)
+	}
+
+	if rec, response := request(t, handler, http.MethodPost, "/booking/extend", "user-key", `{"duration": "30m"}`); rec.Code != http.StatusOK || response["extended"] != float64(1800) {
+		t.Errorf("Expected the booking to be extended by 30 minutes, got %d: %v", rec.Code, response)
+	}
+
+	if rec, _ := request(t, handler, http.MethodDelete, "/booking", "user-key", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected the booking to be returned, got %d", rec.Code)
+	}
+
+	if rec, response := request(t, handler, http.MethodDelete, "/booking", "user-key", ""); rec.Code != http.StatusNotFound || response["error"] != "user hasn't booked a server" {
+		t.Errorf("Expected returning again to fail, got %d: %v", rec.Code, response)
+	}
+}
+
+func TestBookingErrors(t *testing.T) {
+	s, _ := newTestServer()
+	handler := s.Handler()
+
+	if rec, _ := request(t, handler, http.MethodPost, "/booking", "user-key", `{"preset": "unknown"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected an unknown preset to be a bad request, got %d", rec.Code)
+	}
+	if rec, _ := request(t, handler, http.MethodPost, "/booking", "user-key", `{"duration": "soon"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected an invalid duration to be a bad request, got %d", rec.Code)
+	}
+	if rec, _ := request(t, handler, http.MethodPost, "/booking", "user-key", `{`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected an invalid body to be a bad request, got %d", rec.Code)
+	}
+}
+
+func TestAdminActsAsUser(t *testing.T) {
+	s, b := newTestServer()
+	handler := s.Handler()
+
+	if rec, _ := request(t, handler, http.MethodPost, "/booking", "admin-key", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected an admin booking without a user to be a bad request, got %d", rec.Code)
+	}
+
+	if rec, _ := request(t, handler, http.MethodPost, "/booking?user_id=2", "admin-key", ""); rec.Code != http.StatusCreated || b.bookedBy != "2" {
+		t.Errorf("Expected the admin to book as user 2, got %d booked by \"%s\"", rec.Code, b.bookedBy)
+	}
+
+	// Only admins can choose the user.
+	if rec, _ := request(t, handler, http.MethodDelete, "/booking?user_id=2", "user-key", ""); rec.Code != http.StatusNotFound || b.bookedBy != "2" {
+		t.Errorf("Expected the user_id of a non-admin client to be ignored, got %d booked by \"%s\"", rec.Code, b.bookedBy)
+	}
+}
+
+func TestForceUnbookRequiresAdmin(t *testing.T) {
+	s, b := newTestServer()
+	handler := s.Handler()
+
+	if rec, _ := request(t, handler, http.MethodDelete, "/servers/Server%201/booking", "user-key", ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a non-admin force unbook to be forbidden, got %d", rec.Code)
+	}
+	if b.released {
+		t.Errorf("Expected the server to not be released")
+	}
+}
+
+func TestHistory(t *testing.T) {
+	s, _ := newTestServer()
+	handler := s.Handler()
+
+	for _, test := range []struct {
+		key      string
+		path     string
+		expected int
+	}{
+		{"user-key", "/history", 1},
+		{"admin-key", "/history", 2},
+		{"admin-key", "/history?user_id=2", 1},
+	} {
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		req.Header.Set("Authorization", "Bearer "+test.key)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		var entries []history.Entry
+		json.Unmarshal(rec.Body.Bytes(), &entries)
+
+		if rec.Code != http.StatusOK || len(entries) != test.expected {
+			t.Errorf("Expected %d entries for %s with %s, got %d: %d", test.expected, test.path, test.key, rec.Code, len(entries))
+		}
+	}
+
+	// A non-admin client without a user can't list every user's history.
+	if rec, _ := request(t, handler, http.MethodGet, "/history", "no-user-key", ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a non-admin client without a user to be forbidden, got %d", rec.Code)
+	}
+	if rec, _ := request(t, handler, http.MethodGet, "/history?user_id=2", "no-user-key", ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a non-admin client to not choose a user, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,280 @@
+package audit
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"alex-j-butler.com/tf2-booking/util"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/bwmarrin/discordgo"
+)
+
+// EventType is the kind of booking event.
+type EventType string
+
+// Booking event types.
+const (
+	Book           EventType = "book"
+	Unbook         EventType = "unbook"
+	IdleUnbook     EventType = "idle_unbook"
+	Extend         EventType = "extend"
+	StartFailed    EventType = "start_failed"
+	ErrorThreshold EventType = "error_threshold"
+	Report         EventType = "report"
+	AdminAction    EventType = "admin_action"
+)
+
+// titles are the embed titles of each event type.
+var titles = map[EventType]string{
+	Book:           "Server booked",
+	Unbook:         "Server unbooked",
+	IdleUnbook:     "Server automatically unbooked",
+	Extend:         "Booking extended",
+	StartFailed:    "Server failed to start",
+	ErrorThreshold: "Server error threshold reached",
+	Report:         "Server reported",
+	AdminAction:    "Admin action",
+}
+
+// colours are the embed colours of each event type.
+var colours = map[EventType]int{
+	Book:           0x2ecc71,
+	Unbook:         0x95a5a6,
+	IdleUnbook:     0xf1c40f,
+	Extend:         0x3498db,
+	StartFailed:    0xe74c3c,
+	ErrorThreshold: 0xe74c3c,
+	Report:         0xe67e22,
+	AdminAction:    0x9b59b6,
+}
+
+// MaxEmbeds is the number of embeds Discord allows in a single message.
+const MaxEmbeds = 10
+
+// DefaultInterval is how long events are batched for before they're posted.
+const DefaultInterval = 5 * time.Second
+
+// discordIDRegex matches Discord user IDs, which are mentioned in the embed.
+var discordIDRegex = regexp.MustCompile(`^\d+$`)
+
+// Event is a single booking event.
+type Event struct {
+	Type EventType
+
+	// UserID is the Discord user ID, or Steam ID of the player that caused the event.
+	UserID   string
+	Username string
+
+	Server   string
+	Duration time.Duration
+	Reason   string
+
+	Time time.Time
+}
+
+// Embed builds the embed posted to the audit channel for the event.
+func (e Event) Embed() *discordgo.MessageEmbed {
+	title, ok := titles[e.Type]
+	if !ok {
+		title = string(e.Type)
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Type:      "rich",
+		Title:     title,
+		Color:     colours[e.Type],
+		Timestamp: e.Time.Format(time.RFC3339),
+	}
+
+	if user := e.user(); user != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "User", Value: user, Inline: true})
+	}
+	if e.Server != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Server", Value: fmt.Sprintf("`%s`", e.Server), Inline: true})
+	}
+	if e.Duration > 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Duration", Value: util.ToHuman(&e.Duration), Inline: true})
+	}
+	if e.Reason != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Reason", Value: e.Reason, Inline: false})
+	}
+
+	return embed
+}
+
+// user returns the user field of the embed, mentioning Discord users.
+func (e Event) user() string {
+	switch {
+	case discordIDRegex.MatchString(e.UserID) && e.Username != "":
+		return fmt.Sprintf("<@%s> (%s)", e.UserID, e.Username)
+	case discordIDRegex.MatchString(e.UserID):
+		return fmt.Sprintf("<@%s>", e.UserID)
+	case e.UserID != "" && e.Username != "":
+		return fmt.Sprintf("%s (`%s`)", e.Username, e.UserID)
+	case e.UserID != "":
+		return fmt.Sprintf("`%s`", e.UserID)
+	}
+
+	return e.Username
+}
+
+// Recorder receives booking events.
+type Recorder interface {
+	Log(event Event)
+}
+
+// Recorders sends booking events to each of the recorders.
+type Recorders []Recorder
+
+// Log sends the event to each of the recorders, with the same time.
+func (r Recorders) Log(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	for _, recorder := range r {
+		recorder.Log(event)
+	}
+}
+
+// Logger posts booking events as embeds, batching events that happen close together into a single message.
+// A nil Logger discards events, for when no audit channel is configured.
+type Logger struct {
+	// Send posts a batch of embeds, of at most MaxEmbeds.
+	Send func(embeds []*discordgo.MessageEmbed) error
+
+	// Interval is how long events are batched for before they're posted.
+	Interval time.Duration
+
+	mu      sync.Mutex
+	pending []*discordgo.MessageEmbed
+	timer   *time.Timer
+}
+
+// New creates a Logger that posts events using the send function.
+func New(send func(embeds []*discordgo.MessageEmbed) error) *Logger {
+	return &Logger{
+		Send:     send,
+		Interval: DefaultInterval,
+	}
+}
+
+// NewChannel creates a Logger that posts events to a Discord channel.
+func NewChannel(session *discordgo.Session, channelID string) *Logger {
+	return New(func(embeds []*discordgo.MessageEmbed) error {
+		_, err := session.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{Embeds: embeds})
+		return err
+	})
+}
+
+// Log queues the event to be posted with the next batch.
+func (l *Logger) Log(event Event) {
+	if l == nil {
+		return
+	}
+
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.pending = append(l.pending, event.Embed())
+
+	if len(l.pending) >= MaxEmbeds {
+		// The batch is full, post it now.
+		go l.Flush()
+	} else if l.timer == nil {
+		l.timer = time.AfterFunc(l.Interval, l.Flush)
+	}
+}
+
+// Flush posts any pending events immediately.
+func (l *Logger) Flush() {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	pending := l.pending
+	l.pending = nil
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+	}
+	l.mu.Unlock()
+
+	for len(pending) > 0 {
+		n := len(pending)
+		if n > MaxEmbeds {
+			n = MaxEmbeds
+		}
+
+		if err := l.Send(pending[:n]); err != nil {
+			log.WithError(err).WithField("events", n).Error("Failed to post audit events")
+		}
+		pending = pending[n:]
+	}
+}
+
+// DefaultRecent is the number of events kept by a Recent recorder.
+const DefaultRecent = 50
+
+// Recent keeps the most recent booking events in memory, for the dashboard.
+type Recent struct {
+	mu     sync.Mutex
+	events []Event
+	next   int
+	full   bool
+}
+
+// NewRecent creates a Recent recorder that keeps the last size events.
+func NewRecent(size int) *Recent {
+	return &Recent{events: make([]Event, size)}
+}
+
+// Log keeps the event, replacing the oldest event once full.
+func (r *Recent) Log(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.events) == 0 {
+		return
+	}
+
+	r.events[r.next] = event
+	r.next = (r.next + 1) % len(r.events)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Events returns the kept events, newest first.
+func (r *Recent) Events() []Event {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := r.next
+	if r.full {
+		n = len(r.events)
+	}
+
+	events := make([]Event, 0, n)
+	for i := 1; i <= n; i++ {
+		events = append(events, r.events[(r.next-i+len(r.events))%len(r.events)])
+	}
+
+	return events
+}
@@ -0,0 +1,129 @@
+package audit
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type recorder struct {
+	mu      sync.Mutex
+	batches [][]*discordgo.MessageEmbed
+}
+
+func (r *recorder) send(embeds []*discordgo.MessageEmbed) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.batches = append(r.batches, embeds)
+	return nil
+}
+
+func (r *recorder) sizes() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sizes := make([]int, len(r.batches))
+	for i, batch := range r.batches {
+		sizes[i] = len(batch)
+	}
+	return sizes
+}
+
+func TestLoggerBatchesEvents(t *testing.T) {
+	r := &recorder{}
+	l := New(r.send)
+	l.Interval = time.Hour
+
+	for i := 0; i < 3; i++ {
+		l.Log(Event{Type: Book, UserID: "1", Server: "Server 1"})
+	}
+
+	if sizes := r.sizes(); len(sizes) != 0 {
+		t.Fatalf("Expected no batches before the interval, got %v", sizes)
+	}
+
+	l.Flush()
+
+	if sizes := r.sizes(); len(sizes) != 1 || sizes[0] != 3 {
+		t.Fatalf("Expected a single batch of 3 events, got %v", sizes)
+	}
+}
+
+func TestLoggerPostsFullBatches(t *testing.T) {
+	r := &recorder{}
+	l := New(r.send)
+	l.Interval = time.Hour
+
+	for i := 0; i < MaxEmbeds; i++ {
+		l.Log(Event{Type: Extend, UserID: "1", Server: "Server 1", Duration: time.Hour})
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(r.sizes()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if sizes := r.sizes(); len(sizes) != 1 || sizes[0] != MaxEmbeds {
+		t.Fatalf("Expected a full batch to be posted immediately, got %v", sizes)
+	}
+}
+
+func TestNilLoggerDiscards(t *testing.T) {
+	var l *Logger
+	l.Log(Event{Type: Book})
+	l.Flush()
+}
+
+func TestEventEmbed(t *testing.T) {
+	embed := Event{
+		Type:     Book,
+		UserID:   "1234",
+		Username: "alex",
+		Server:   "Server 1",
+		Duration: 3 * time.Hour,
+		Reason:   "Preset `6v6`",
+	}.Embed()
+
+	if embed.Title != "Server booked" {
+		t.Fatalf("Unexpected title: %s", embed.Title)
+	}
+
+	expected := map[string]string{
+		"User":     "<@1234> (alex)",
+		"Server":   "`Server 1`",
+		"Duration": "3 hours",
+		"Reason":   "Preset `6v6`",
+	}
+	if len(embed.Fields) != len(expected) {
+		t.Fatalf("Expected %d fields, got %d", len(expected), len(embed.Fields))
+	}
+	for _, field := range embed.Fields {
+		if expected[field.Name] != field.Value {
+			t.Fatalf("Expected field %s to be %q, got %q", field.Name, expected[field.Name], field.Value)
+		}
+	}
+}
+
+func TestRecentKeepsNewestEvents(t *testing.T) {
+	r := NewRecent(3)
+	if events := r.Events(); len(events) != 0 {
+		t.Fatalf("Expected no events, got %v", events)
+	}
+
+	for _, server := range []string{"Server 1", "Server 2", "Server 3", "Server 4"} {
+		r.Log(Event{Type: Book, Server: server})
+	}
+
+	events := r.Events()
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 events, got %d", len(events))
+	}
+	for i, expected := range []string{"Server 4", "Server 3", "Server 2"} {
+		if events[i].Server != expected {
+			t.Errorf("Expected event %d to be for %s, got %s", i, expected, events[i].Server)
+		}
+	}
+}
@@ -0,0 +1,413 @@
+package booking
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"alex-j-butler.com/tf2-booking/config"
+	"alex-j-butler.com/tf2-booking/events"
+	"alex-j-butler.com/tf2-booking/logging"
+	"alex-j-butler.com/tf2-booking/servers"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/bwmarrin/discordgo"
+	redis "gopkg.in/redis.v5"
+)
+
+var (
+	// ErrAlreadyBooked is returned when a user tries to book a second server.
+	ErrAlreadyBooked = errors.New("booking: user has already booked a server")
+
+	// ErrNotBooked is returned when a user without a booking tries to operate on one.
+	ErrNotBooked = errors.New("booking: user hasn't booked a server")
+
+	// ErrNoServersAvailable is returned when there are no servers left to book.
+	ErrNoServersAvailable = errors.New("booking: no servers are available")
+
+	// ErrServerUnavailable is returned when a specifically requested server can't be booked.
+	ErrServerUnavailable = errors.New("booking: server is unavailable")
+
+	// ErrUnknownPreset is returned when a requested preset isn't configured.
+	ErrUnknownPreset = errors.New("booking: unknown preset")
+
+	// ErrBookFailed is returned when the server couldn't be setup for the booking.
+	ErrBookFailed = errors.New("booking: failed to setup server")
+
+	// ErrMaxDuration is returned when a booking that has reached the maximum duration is extended.
+	ErrMaxDuration = errors.New("booking: booking has reached the maximum duration")
+)
+
+// Booking holds the details of a newly booked server.
+type Booking struct {
+	Server         *servers.Server
+	RCONPassword   string
+	ServerPassword string
+}
+
+// Manager performs the booking operations shared by the Discord text & slash commands.
+type Manager struct {
+	Pool  servers.ServerPool
+	Redis *redis.Client
+
+	// Events receives the booking events, nil to discard them.
+	Events *events.Bus
+
+	// Redis script to retrieve a key, and if that key does not exist, then set a default value.
+	getDefaultValue *redis.Script
+}
+
+// New creates a booking manager for the specified server pool.
+func New(pool servers.ServerPool, redisClient *redis.Client) *Manager {
+	return &Manager{
+		Pool:  pool,
+		Redis: redisClient,
+		getDefaultValue: redis.NewScript(`
+			local value = redis.call("GET", KEYS[1])
+			if (not value) then
+				redis.call("SET", KEYS[1], ARGV[1])
+				return ARGV[1]
+			end
+			return value
+		`),
+	}
+}
+
+func userKey(userID string) string {
+	return fmt.Sprintf("user.%s", userID)
+}
+
+// bookedUUID returns the UUID of the server the user has booked, or an empty string if none.
+func (m *Manager) bookedUUID(userID string) (string, error) {
+	bookingInfo, err := m.getDefaultValue.Run(m.Redis, []string{userKey(userID)}, nil).Result()
+	if err != nil {
+		return "", err
+	}
+
+	return bookingInfo.(string), nil
+}
+
+// ClearUser removes the user's booked state, without unbooking their server.
+func (m *Manager) ClearUser(userID string) error {
+	if err := m.Redis.Set(userKey(userID), "", 0).Err(); err != nil {
+		logging.User(userID).WithError(err).Error("Failed to set user information")
+		return err
+	}
+
+	return nil
+}
+
+// BookedServer returns the server booked by the user.
+// If the user's booked state points at a server that doesn't exist,
+// the state is reset and ErrNotBooked is returned.
+func (m *Manager) BookedServer(userID string) (*servers.Server, error) {
+	uuid, err := m.bookedUUID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if uuid == "" {
+		return nil, ErrNotBooked
+	}
+
+	server, err := m.Pool.GetServerByUUID(uuid)
+	if err != nil || server == nil {
+		// We're in an invalid state, reset back to normal.
+		m.ClearUser(userID)
+
+		return nil, ErrNotBooked
+	}
+
+	return server, nil
+}
+
+// Duration returns the booking duration to use for a requested duration,
+// applying the configured default and maximum.
+func Duration(requested time.Duration) time.Duration {
+	duration := requested
+	if duration <= 0 {
+		duration = config.Get().Booking.DefaultDuration.Duration
+	}
+
+	if max := config.Get().Booking.MaxDuration.Duration; max > 0 && duration > max {
+		duration = max
+	}
+
+	return duration
+}
+
+// Book books a server for the user and starts it in the background.
+// If serverName is empty, the next available server is booked.
+// If preset is not empty, the preset's command is sent to the server once it has started.
+// A zero duration books the server for the configured default duration.
+func (m *Manager) Book(user *discordgo.User, serverName string, preset string, duration time.Duration) (*Booking, error) {
+	presetCommand := ""
+	if preset != "" {
+		command, ok := config.Get().Booking.Presets[preset]
+		if !ok {
+			return nil, ErrUnknownPreset
+		}
+		presetCommand = command
+	}
+
+	uuid, err := m.bookedUUID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if uuid != "" {
+		return nil, ErrAlreadyBooked
+	}
+
+	var server *servers.Server
+	if serverName != "" {
+		server, err = m.Pool.GetServerByName(serverName)
+		if err != nil || server.IsBooked() || !server.Available() {
+			return nil, ErrServerUnavailable
+		}
+	} else {
+		// Get the next available server.
+		server = m.Pool.GetAvailableServer()
+		if server == nil {
+			return nil, ErrNoServersAvailable
+		}
+	}
+
+	// Book the server.
+	rconPassword, serverPassword, err := server.Book(user)
+	if err != nil {
+		server.Log().WithError(err).WithField(logging.UserIDField, user.ID).Error("Failed to book server")
+		return nil, ErrBookFailed
+	}
+
+	if duration = Duration(duration); duration > 0 {
+		server.ReturnDate = server.BookedDate.Add(duration)
+		server.Update(m.Redis)
+	}
+
+	// Start the server.
+	go func(server *servers.Server, user *discordgo.User) {
+		err := server.Start()
+
+		if err != nil {
+			server.Log().WithError(err).Error("Failed to start server")
+
+			// Reset the user's booked state.
+			m.ClearUser(user.ID)
+
+			m.Events.Publish(&events.BookingStartFailed{
+				Server:   server,
+				UserID:   user.ID,
+				Username: user.Username,
+				Err:      err,
+			})
+
+			return
+		}
+
+		if presetCommand != "" {
+			server.SendCommand(presetCommand)
+		}
+	}(server, user)
+
+	// Add the user's booked state.
+	if err := m.Redis.Set(userKey(user.ID), server.UUID, 0).Err(); err != nil {
+		server.Log().WithError(err).Error("Failed to set user information")
+	}
+
+	server.Log().WithFields(log.Fields{"preset": preset, "duration": duration.String()}).Info("Booked server")
+
+	m.Events.Publish(&events.BookingStarted{
+		Server:   server,
+		UserID:   user.ID,
+		Username: user.Username,
+		Preset:   preset,
+		Duration: duration,
+	})
+
+	return &Booking{
+		Server:         server,
+		RCONPassword:   rconPassword,
+		ServerPassword: serverPassword,
+	}, nil
+}
+
+// Unbook returns the server booked by the user, stopping it in the background.
+func (m *Manager) Unbook(userID string) (*servers.Server, error) {
+	server, err := m.BookedServer(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.UnbookServer(server, events.Returned, ""); err != nil {
+		return nil, err
+	}
+
+	return server, nil
+}
+
+// UnbookServer returns a server on behalf of its booker, stopping it in the background.
+// By is the name of the player that returned the server, for servers returned ingame.
+func (m *Manager) UnbookServer(server *servers.Server, reason events.EndReason, by string) error {
+	userID := server.Booker
+	ended := m.ended(server, reason, by)
+
+	// Log with the booking's fields, which are cleared by releasing the server.
+	entry := server.Log()
+
+	// Stop the server.
+	go func(server *servers.Server) {
+		err := server.Stop()
+
+		if err != nil {
+			entry.WithError(err).Error("Failed to stop server")
+
+			m.Events.Publish(&events.ServerStopFailed{
+				Server: server,
+				UserID: userID,
+				Err:    err,
+			})
+		}
+	}(server)
+
+	if err := m.release(server); err != nil {
+		return err
+	}
+
+	entry.WithFields(log.Fields{"reason": reason, "by": by}).Info("Unbooked server")
+
+	m.Events.Publish(ended)
+
+	return nil
+}
+
+// Release forcibly unbooks & stops a server, regardless of who booked it.
+func (m *Manager) Release(server *servers.Server, reason events.EndReason) error {
+	ended := m.ended(server, reason, "")
+
+	if err := m.release(server); err != nil {
+		return err
+	}
+
+	server.Stop()
+
+	m.Events.Publish(ended)
+
+	return nil
+}
+
+// UnbookStopped unbooks a server that has already stopped, without stopping it again.
+// The booker's booked state is only removed if it still points at the server.
+func (m *Manager) UnbookStopped(server *servers.Server) error {
+	ended := m.ended(server, events.Stopped, "")
+
+	if uuid, err := m.Redis.Get(userKey(server.Booker)).Result(); err == nil && uuid == server.UUID {
+		if err := m.ClearUser(server.Booker); err != nil {
+			return err
+		}
+	}
+
+	entry := server.Log()
+	server.Unbook()
+	entry.WithField("reason", events.Stopped).Info("Unbooked server")
+
+	m.Events.Publish(ended)
+
+	return nil
+}
+
+// ended creates the event of the server's booking ending, before the booking details are cleared.
+func (m *Manager) ended(server *servers.Server, reason events.EndReason, by string) *events.BookingEnded {
+	return &events.BookingEnded{
+		Server:      server,
+		UserID:      server.Booker,
+		UserMention: server.BookerMention,
+		Reason:      reason,
+		By:          by,
+		BookedFor:   time.Since(server.BookedDate),
+	}
+}
+
+// release removes the booker's booked state and unbooks the server.
+func (m *Manager) release(server *servers.Server) error {
+	// Remove the user's booked state.
+	if err := m.ClearUser(server.Booker); err != nil {
+		return err
+	}
+
+	// Unbook the server.
+	server.Unbook()
+
+	return nil
+}
+
+// Extend extends the booking of the server booked by the user.
+// A zero duration extends the booking by the configured idle time.
+func (m *Manager) Extend(userID string, duration time.Duration) (*servers.Server, time.Duration, error) {
+	server, err := m.BookedServer(userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	duration, err = m.ExtendServer(server, duration)
+	return server, duration, err
+}
+
+// Extension returns the duration to extend the server's booking by for a requested duration,
+// applying the configured default, and limiting the booking to the configured maximum duration.
+func Extension(server *servers.Server, requested time.Duration) time.Duration {
+	duration := requested
+	if duration <= 0 {
+		duration = time.Duration(config.Get().Booking.MaxIdleMinutes) * time.Minute
+	}
+
+	// Bookings that don't expire can't pass the maximum.
+	max := config.Get().Booking.MaxDuration.Duration
+	if max <= 0 || server.ReturnDate.IsZero() {
+		return duration
+	}
+
+	if remaining := server.BookedDate.Add(max).Sub(server.ReturnDate); duration > remaining {
+		duration = remaining
+	}
+	if duration < 0 {
+		duration = 0
+	}
+
+	return duration
+}
+
+// ExtendServer extends the booking of the server, returning the duration it was extended by,
+// which is shorter than requested if the booking would pass the maximum duration.
+// A zero duration extends the booking by the configured idle time.
+func (m *Manager) ExtendServer(server *servers.Server, duration time.Duration) (time.Duration, error) {
+	duration = Extension(server, duration)
+	if duration <= 0 {
+		return 0, ErrMaxDuration
+	}
+
+	server.ExtendBooking(duration)
+
+	m.Events.Publish(&events.BookingExtended{
+		Server:   server,
+		UserID:   server.Booker,
+		Duration: duration,
+	})
+
+	return duration, nil
+}
+
+// Password returns the server booked by the user, and its current server password.
+func (m *Manager) Password(userID string) (*servers.Server, string, error) {
+	server, err := m.BookedServer(userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	serverPassword, err := server.GetCurrentPassword()
+	if err != nil {
+		return server, "", err
+	}
+
+	return server, serverPassword, nil
+}
@@ -0,0 +1,76 @@
+package booking
+
+import (
+	"testing"
+	"time"
+
+	"alex-j-butler.com/tf2-booking/config"
+	"alex-j-butler.com/tf2-booking/servers"
+	"alex-j-butler.com/tf2-booking/util"
+)
+
+func setupConfig(maxIdleMinutes int, maxDuration time.Duration) {
+	conf := &config.Config{}
+	conf.Booking.MaxIdleMinutes = maxIdleMinutes
+	conf.Booking.MaxDuration = util.DurationUtil{Duration: maxDuration}
+	config.Set(conf)
+}
+
+func TestExtension(t *testing.T) {
+	setupConfig(15, 6*time.Hour)
+
+	bookedDate := time.Now().Add(-time.Hour)
+	server := &servers.Server{Booked: true, BookedDate: bookedDate, ReturnDate: bookedDate.Add(3 * time.Hour)}
+
+	tests := []struct {
+		returnDate time.Time
+		requested  time.Duration
+		expected   time.Duration
+	}{
+		// The requested duration is granted while it's within the maximum.
+		{bookedDate.Add(3 * time.Hour), time.Hour, time.Hour},
+		{bookedDate.Add(3 * time.Hour), 3 * time.Hour, 3 * time.Hour},
+		// The default is the idle time.
+		{bookedDate.Add(3 * time.Hour), 0, 15 * time.Minute},
+		// The booking is limited to the maximum.
+		{bookedDate.Add(3 * time.Hour), 4 * time.Hour, 3 * time.Hour},
+		{bookedDate.Add(5*time.Hour + 50*time.Minute), 0, 10 * time.Minute},
+		{bookedDate.Add(6 * time.Hour), time.Hour, 0},
+		// Bookings that don't expire can always be extended.
+		{time.Time{}, 24 * time.Hour, 24 * time.Hour},
+	}
+
+	for _, test := range tests {
+		server.ReturnDate = test.returnDate
+		if duration := Extension(server, test.requested); duration != test.expected {
+			t.Errorf("Expected extending a booking returned at %s by %s to grant %s, got %s",
+				test.returnDate.Sub(bookedDate), test.requested, test.expected, duration)
+		}
+	}
+}
+
+func TestExtensionWithoutMaximum(t *testing.T) {
+	setupConfig(15, 0)
+
+	bookedDate := time.Now().Add(-time.Hour)
+	server := &servers.Server{Booked: true, BookedDate: bookedDate, ReturnDate: bookedDate.Add(24 * time.Hour)}
+
+	if duration := Extension(server, 24*time.Hour); duration != 24*time.Hour {
+		t.Errorf("Expected the requested duration without a maximum, got %s", duration)
+	}
+}
+
+func TestExtendServerAtMaximum(t *testing.T) {
+	setupConfig(15, 6*time.Hour)
+
+	bookedDate := time.Now().Add(-time.Hour)
+	server := &servers.Server{Booked: true, BookedDate: bookedDate, ReturnDate: bookedDate.Add(6 * time.Hour)}
+
+	m := &Manager{}
+	if _, err := m.ExtendServer(server, time.Hour); err != ErrMaxDuration {
+		t.Errorf("Expected extending a booking at the maximum to fail, got %v", err)
+	}
+	if !server.ReturnDate.Equal(bookedDate.Add(6 * time.Hour)) {
+		t.Errorf("Expected the return date not to change, got %s", server.ReturnDate)
+	}
+}
@@ -0,0 +1,563 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"alex-j-butler.com/tf2-booking/booking"
+	"alex-j-butler.com/tf2-booking/config"
+	"alex-j-butler.com/tf2-booking/events"
+	"alex-j-butler.com/tf2-booking/globals"
+	"alex-j-butler.com/tf2-booking/servers"
+
+	"github.com/alex-j-butler/tablewriter"
+	"github.com/codegangsta/cli"
+	redis "gopkg.in/redis.v5"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Output formats of the admin subcommands.
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+)
+
+// StateKeys are the patterns of the Redis keys included in a state dump.
+var StateKeys = []string{"server.*", "user.*", "locale.*", StatusBoardKey}
+
+var formatFlag = cli.StringFlag{
+	Name:  "format, f",
+	Value: FormatTable,
+	Usage: "output format, \"table\" or \"json\"",
+}
+
+// AdminCommands are the subcommands for managing the bot's state from a shell,
+// talking directly to Redis & the booking API without connecting to Discord.
+var AdminCommands = []cli.Command{
+	{
+		Name:  "servers",
+		Usage: "manage the servers in the pool",
+		Subcommands: []cli.Command{
+			{
+				Name:   "list",
+				Usage:  "list the servers & their booking state",
+				Flags:  []cli.Flag{formatFlag},
+				Action: ListServersCommand,
+			},
+		},
+	},
+	{
+		Name:  "bookings",
+		Usage: "manage the bookings",
+		Subcommands: []cli.Command{
+			{
+				Name:   "list",
+				Usage:  "list the booked servers",
+				Flags:  []cli.Flag{formatFlag},
+				Action: ListBookingsCommand,
+			},
+		},
+	},
+	{
+		Name:      "unbook",
+		Usage:     "force unbook & stop a server",
+		ArgsUsage: "<server>",
+		Flags:     []cli.Flag{formatFlag},
+		Action:    UnbookCommand,
+	},
+	{
+		Name:  "user",
+		Usage: "manage the users' booked state",
+		Subcommands: []cli.Command{
+			{
+				Name:      "clear",
+				Usage:     "clear the user's booked state, without unbooking their server",
+				ArgsUsage: "<discord id>",
+				Flags:     []cli.Flag{formatFlag},
+				Action:    ClearUserCommand,
+			},
+		},
+	},
+	{
+		Name:  "state",
+		Usage: "back up & restore the state stored in Redis",
+		Subcommands: []cli.Command{
+			{
+				Name:  "dump",
+				Usage: "dump the servers, users & preferences",
+				Flags: []cli.Flag{
+					cli.StringFlag{Name: "output, o", Usage: "file to write the dump to, instead of stdout"},
+					// Dumps are JSON by default, as that's what 'state restore' reads.
+					cli.StringFlag{Name: "format, f", Value: FormatJSON, Usage: "output format, \"json\" or \"table\""},
+				},
+				Action: DumpStateCommand,
+			},
+			{
+				Name:      "restore",
+				Usage:     "restore a state dump",
+				ArgsUsage: "<file>",
+				Flags: []cli.Flag{
+					formatFlag,
+					cli.BoolFlag{Name: "clean", Usage: "remove the state keys that aren't in the dump"},
+				},
+				Action: RestoreStateCommand,
+			},
+		},
+	},
+	{
+		Name:  "config",
+		Usage: "manage the configuration",
+		Subcommands: []cli.Command{
+			{
+				Name:      "validate",
+				Usage:     "check the configuration file for problems",
+				ArgsUsage: "[file]",
+				Flags:     []cli.Flag{formatFlag},
+				Action:    ValidateConfigCommand,
+			},
+			{
+				Name:      "show",
+				Usage:     "show the configuration with the overrides & defaults applied, and the secrets redacted",
+				ArgsUsage: "[file]",
+				Action:    ShowConfigCommand,
+			},
+		},
+	},
+}
+
+// output writes the rows as a table, or the value as JSON.
+func output(ctx *cli.Context, w io.Writer, header []string, rows [][]string, value interface{}) error {
+	switch ctx.String("format") {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	case FormatTable, "":
+		table := tablewriter.NewWriter(w)
+		table.SetHeader(header)
+		table.SetAutoFormatHeaders(false)
+		table.AppendBulk(rows)
+		table.Render()
+		return nil
+	default:
+		return cli.NewExitError(fmt.Sprintf("Unknown format \"%s\"", ctx.String("format")), 2)
+	}
+}
+
+// connectRedis loads the configuration & connects to Redis.
+func connectRedis(ctx *cli.Context) (*redis.Client, error) {
+	if err := Configure(ctx); err != nil {
+		return nil, cli.NewExitError(err.Error(), 1)
+	}
+
+	client, err := ConnectRedis()
+	if err != nil {
+		return nil, cli.NewExitError(fmt.Sprintf("Redis ping failed: %s", err), 1)
+	}
+
+	return client, nil
+}
+
+// connect loads the configuration & connects to Redis & the booking API, for the subcommands that need the server pool.
+func connect(ctx *cli.Context) error {
+	client, err := connectRedis(ctx)
+	if err != nil {
+		return err
+	}
+	globals.RedisClient = client
+
+	pool, err = NewServerPool(NewBookingClient())
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Failed to initialise the server pool: %s", err), 1)
+	}
+
+	for _, server := range pool.GetServers() {
+		server.Synchronise(globals.RedisClient)
+	}
+
+	return nil
+}
+
+// serverRecord is a server in the servers & bookings lists.
+type serverRecord struct {
+	Name        string     `json:"name"`
+	UUID        string     `json:"uuid"`
+	Address     string     `json:"address"`
+	State       string     `json:"state"`
+	Maintenance bool       `json:"maintenance"`
+	Booker      string     `json:"booker,omitempty"`
+	BookerName  string     `json:"booker_name,omitempty"`
+	BookedAt    *time.Time `json:"booked_at,omitempty"`
+	ReturnAt    *time.Time `json:"return_at,omitempty"`
+}
+
+func newServerRecord(server *servers.Server) serverRecord {
+	record := serverRecord{
+		Name:        server.Name,
+		UUID:        server.UUID,
+		Address:     server.Address,
+		State:       serverState(server),
+		Maintenance: server.InMaintenance(),
+	}
+
+	if server.Booked {
+		record.Booker = server.Booker
+		record.BookerName = server.BookerFullname
+		record.BookedAt = &server.BookedDate
+		if !server.ReturnDate.IsZero() {
+			record.ReturnAt = &server.ReturnDate
+		}
+	}
+
+	return record
+}
+
+func (r serverRecord) row() []string {
+	row := []string{r.Name, r.UUID, r.Address, r.State, r.Booker, r.BookerName, "-", "-"}
+	if r.BookedAt != nil {
+		row[6] = r.BookedAt.Format(time.RFC3339)
+	}
+	if r.ReturnAt != nil {
+		row[7] = r.ReturnAt.Format(time.RFC3339)
+	}
+
+	return row
+}
+
+var serverRecordHeader = []string{"Server name", "UUID", "Address", "State", "Booker ID", "Booker name", "Booked at", "Returns at"}
+
+// serverState returns the state of the server, as known to Redis & the booking API.
+func serverState(server *servers.Server) string {
+	switch {
+	case server.IsBooked():
+		return "Booked"
+	case server.Booked:
+		// Booked in Redis, but the booking API doesn't think the server is running.
+		return "Booked (stopped)"
+	case server.InMaintenance():
+		return "Maintenance"
+	case server.Available():
+		return "Available"
+	}
+
+	return "Unavailable"
+}
+
+// ListServersCommand lists the servers of the pool.
+func ListServersCommand(ctx *cli.Context) error {
+	if err := connect(ctx); err != nil {
+		return err
+	}
+
+	records := []serverRecord{}
+	rows := [][]string{}
+	for _, server := range pool.GetServers() {
+		record := newServerRecord(server)
+		records = append(records, record)
+		rows = append(rows, record.row())
+	}
+
+	return output(ctx, os.Stdout, serverRecordHeader, rows, records)
+}
+
+// ListBookingsCommand lists the booked servers.
+func ListBookingsCommand(ctx *cli.Context) error {
+	if err := connect(ctx); err != nil {
+		return err
+	}
+
+	records := []serverRecord{}
+	rows := [][]string{}
+	for _, server := range pool.GetServers() {
+		if !server.Booked {
+			continue
+		}
+
+		record := newServerRecord(server)
+		records = append(records, record)
+		rows = append(rows, record.row())
+	}
+
+	return output(ctx, os.Stdout, serverRecordHeader, rows, records)
+}
+
+// UnbookCommand force unbooks & stops a server.
+// The booker isn't notified, as the bot isn't connected to Discord.
+func UnbookCommand(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return cli.NewExitError("Usage: unbook <server>", 2)
+	}
+
+	if err := connect(ctx); err != nil {
+		return err
+	}
+
+	server, err := pool.GetServerByName(ctx.Args().First())
+	if err != nil || server == nil {
+		return cli.NewExitError(fmt.Sprintf("Unknown server \"%s\"", ctx.Args().First()), 1)
+	}
+
+	if !server.Booked {
+		return cli.NewExitError(fmt.Sprintf("Server \"%s\" isn't booked", server.Name), 1)
+	}
+
+	record := newServerRecord(server)
+	if err := booking.New(pool, globals.RedisClient).Release(server, events.Forced); err != nil {
+		return cli.NewExitError(fmt.Sprintf("Failed to unbook server \"%s\": %s", server.Name, err), 1)
+	}
+
+	return output(ctx, os.Stdout, serverRecordHeader, [][]string{record.row()}, record)
+}
+
+// ClearUserCommand clears a user's booked state, for users left unable to book by a failure.
+func ClearUserCommand(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return cli.NewExitError("Usage: user clear <discord id>", 2)
+	}
+
+	client, err := connectRedis(ctx)
+	if err != nil {
+		return err
+	}
+
+	userID := ctx.Args().First()
+	previous, err := client.Get(fmt.Sprintf("user.%s", userID)).Result()
+	if err != nil && err != redis.Nil {
+		return cli.NewExitError(fmt.Sprintf("Failed to read the user's booked state: %s", err), 1)
+	}
+
+	if err := booking.New(nil, client).ClearUser(userID); err != nil {
+		return cli.NewExitError(fmt.Sprintf("Failed to clear the user's booked state: %s", err), 1)
+	}
+
+	result := map[string]string{"user_id": userID, "previous_server_uuid": previous}
+	return output(ctx, os.Stdout, []string{"User ID", "Previous server UUID"}, [][]string{{userID, previous}}, result)
+}
+
+// State is the bot's state stored in Redis, as dumped by 'state dump'.
+type State struct {
+	DumpedAt time.Time         `json:"dumped_at"`
+	Keys     map[string]string `json:"keys"`
+}
+
+// DumpState reads the keys matching the patterns.
+func DumpState(client *redis.Client, patterns []string) (*State, error) {
+	state := &State{DumpedAt: time.Now(), Keys: make(map[string]string)}
+
+	for _, pattern := range patterns {
+		var cursor uint64
+		for {
+			keys, next, err := client.Scan(cursor, pattern, 100).Result()
+			if err != nil {
+				return nil, err
+			}
+
+			for _, key := range keys {
+				value, err := client.Get(key).Result()
+				if err == redis.Nil {
+					// The key was removed while dumping.
+					continue
+				} else if err != nil {
+					return nil, err
+				}
+				state.Keys[key] = value
+			}
+
+			cursor = next
+			if cursor == 0 {
+				break
+			}
+		}
+	}
+
+	return state, nil
+}
+
+// RestoreState writes the keys of the state, overwriting existing keys.
+// If clean is set, the keys matching StateKeys that aren't in the state are removed,
+// so the state is exactly as it was dumped. The removed keys are returned.
+func RestoreState(client *redis.Client, state *State, clean bool) ([]string, error) {
+	var removed []string
+	if clean {
+		current, err := DumpState(client, StateKeys)
+		if err != nil {
+			return nil, err
+		}
+
+		for key := range current.Keys {
+			if _, ok := state.Keys[key]; !ok {
+				removed = append(removed, key)
+			}
+		}
+		sort.Strings(removed)
+	}
+
+	pipe := client.Pipeline()
+	defer pipe.Close()
+
+	if len(removed) > 0 {
+		pipe.Del(removed...)
+	}
+	for key, value := range state.Keys {
+		pipe.Set(key, value, 0)
+	}
+
+	_, err := pipe.Exec()
+	return removed, err
+}
+
+// DumpStateCommand writes the state stored in Redis, as JSON to be restored or a table of the keys.
+func DumpStateCommand(ctx *cli.Context) error {
+	client, err := connectRedis(ctx)
+	if err != nil {
+		return err
+	}
+
+	state, err := DumpState(client, StateKeys)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Failed to dump the state: %s", err), 1)
+	}
+
+	var w io.Writer = os.Stdout
+	if path := ctx.String("output"); path != "" {
+		// The dump contains the RCON passwords of the servers.
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("Failed to write the dump: %s", err), 1)
+		}
+		defer file.Close()
+
+		w = file
+	}
+
+	return output(ctx, w, []string{"Key", "Value"}, state.rows(), state)
+}
+
+// rows returns the keys & values of the state, sorted by key.
+func (state *State) rows() [][]string {
+	keys := make([]string, 0, len(state.Keys))
+	for key := range state.Keys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	rows := make([][]string, len(keys))
+	for i, key := range keys {
+		rows[i] = []string{key, state.Keys[key]}
+	}
+
+	return rows
+}
+
+// RestoreStateCommand restores a state dump, written by 'state dump'.
+func RestoreStateCommand(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return cli.NewExitError("Usage: state restore <file>", 2)
+	}
+
+	serialised, err := ioutil.ReadFile(ctx.Args().First())
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Failed to read the dump: %s", err), 1)
+	}
+
+	state := &State{}
+	if err := json.Unmarshal(serialised, state); err != nil {
+		return cli.NewExitError(fmt.Sprintf("Invalid dump: %s", err), 1)
+	}
+
+	client, err := connectRedis(ctx)
+	if err != nil {
+		return err
+	}
+
+	removed, err := RestoreState(client, state, ctx.Bool("clean"))
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Failed to restore the state: %s", err), 1)
+	}
+
+	keys := []string{}
+	for key := range state.Keys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	rows := make([][]string, 0, len(keys)+len(removed))
+	for _, key := range keys {
+		rows = append(rows, []string{key, "Restored"})
+	}
+	for _, key := range removed {
+		rows = append(rows, []string{key, "Removed"})
+	}
+
+	result := map[string][]string{"restored": keys, "removed": removed}
+	return output(ctx, os.Stdout, []string{"Key", "Change"}, rows, result)
+}
+
+// ValidateConfigCommand checks the configuration file, exiting with an error if there are problems.
+func ValidateConfigCommand(ctx *cli.Context) error {
+	path := ctx.GlobalString("config")
+	if ctx.NArg() > 0 {
+		path = ctx.Args().First()
+	}
+
+	var errs []error
+	conf, err := config.Load(path)
+	if loadErrs, ok := err.(config.Errors); ok {
+		errs = loadErrs
+	} else if err != nil {
+		errs = []error{err}
+	} else {
+		errs = conf.Validate()
+	}
+
+	// Check the servers of the file pool, which are otherwise only loaded when the bot starts.
+	if conf != nil && len(errs) == 0 && conf.Servers.Pool == config.ServerPoolFile {
+		// The booking API client is created from the configuration being validated.
+		config.Set(conf)
+		if _, err := servers.LoadServerFile(conf.Servers.File, NewBookingClient()); err != nil {
+			errs = []error{err}
+		}
+	}
+
+	problems := make([]string, len(errs))
+	rows := make([][]string, len(errs))
+	for i, err := range errs {
+		problems[i] = err.Error()
+		rows[i] = []string{err.Error()}
+	}
+
+	if err := output(ctx, os.Stdout, []string{"Problem"}, rows, map[string]interface{}{"valid": len(problems) == 0, "problems": problems}); err != nil {
+		return err
+	}
+
+	if len(problems) > 0 {
+		return cli.NewExitError("Configuration is invalid", 1)
+	}
+	return nil
+}
+
+// ShowConfigCommand writes the configuration as YAML, as the bot would load it, with the secrets redacted.
+func ShowConfigCommand(ctx *cli.Context) error {
+	path := ctx.GlobalString("config")
+	if ctx.NArg() > 0 {
+		path = ctx.Args().First()
+	}
+
+	conf, err := config.Load(path)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	out, err := yaml.Marshal(conf.Redact())
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Failed to write configuration: %s", err), 1)
+	}
+
+	_, err = os.Stdout.Write(out)
+	return err
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"reflect"
+	"testing"
+
+	"github.com/codegangsta/cli"
+	redis "gopkg.in/redis.v5"
+)
+
+func formatContext(format string) *cli.Context {
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	set.String("format", format, "")
+	return cli.NewContext(nil, set, nil)
+}
+
+func TestOutputJSON(t *testing.T) {
+	var buf bytes.Buffer
+	err := output(formatContext(FormatJSON), &buf, []string{"User ID"}, [][]string{{"1"}}, map[string]string{"user_id": "1"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	expected := "{\n  \"user_id\": \"1\"\n}\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestOutputUnknownFormat(t *testing.T) {
+	var buf bytes.Buffer
+	if err := output(formatContext("xml"), &buf, nil, nil, nil); err == nil {
+		t.Errorf("Expected an unknown format to fail")
+	}
+}
+
+func TestDumpState(t *testing.T) {
+	redisServer := newFakeRedis(t)
+	defer redisServer.listener.Close()
+	client := redis.NewClient(&redis.Options{Addr: redisServer.listener.Addr().String()})
+
+	redisServer.values["server.1"] = `{"Booked":true}`
+	redisServer.values["user.1"] = "1"
+	redisServer.values[StatusBoardKey] = "message"
+	redisServer.values["unrelated"] = "value"
+
+	state, err := DumpState(client, StateKeys)
+	if err != nil {
+		t.Fatalf("Expected the state to be dumped, got %s", err)
+	}
+
+	expected := map[string]string{"server.1": `{"Booked":true}`, "user.1": "1", StatusBoardKey: "message"}
+	if !reflect.DeepEqual(state.Keys, expected) {
+		t.Errorf("Expected only the state keys to be dumped, got %v", state.Keys)
+	}
+
+	rows := state.rows()
+	if len(rows) != 3 || rows[0][0] != "server.1" || rows[2][0] != "user.1" {
+		t.Errorf("Expected the rows sorted by key, got %v", rows)
+	}
+}
+
+func TestRestoreState(t *testing.T) {
+	redisServer := newFakeRedis(t)
+	defer redisServer.listener.Close()
+	client := redis.NewClient(&redis.Options{Addr: redisServer.listener.Addr().String()})
+
+	state := &State{Keys: map[string]string{"server.1": `{"Booked":true}`, "user.1": "1"}}
+
+	for _, test := range []struct {
+		clean   bool
+		removed []string
+		kept    bool
+	}{
+		{false, nil, true},
+		{true, []string{"user.2"}, false},
+	} {
+		redisServer.values["server.1"] = `{"Booked":false}`
+		redisServer.values["user.2"] = "2"
+		redisServer.values["unrelated"] = "value"
+
+		removed, err := RestoreState(client, state, test.clean)
+		if err != nil {
+			t.Fatalf("Expected the state to be restored, got %s", err)
+		}
+		if !reflect.DeepEqual(removed, test.removed) {
+			t.Errorf("Expected %v to be removed with clean=%t, got %v", test.removed, test.clean, removed)
+		}
+
+		if redisServer.get("server.1") != `{"Booked":true}` || redisServer.get("user.1") != "1" {
+			t.Errorf("Expected the dumped keys to be restored with clean=%t", test.clean)
+		}
+		if redisServer.has("user.2") != test.kept {
+			t.Errorf("Expected user.2 to be kept=%t with clean=%t", test.kept, test.clean)
+		}
+		if redisServer.get("unrelated") != "value" {
+			t.Errorf("Expected keys outside the state to be left alone with clean=%t", test.clean)
+		}
+	}
+}
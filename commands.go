@@ -2,71 +2,68 @@ package main
 
 import (
 	"fmt"
-	"log"
-	"net/url"
+	"sort"
 	"strings"
 
 	"bytes"
 
+	"alex-j-butler.com/tf2-booking/audit"
+	"alex-j-butler.com/tf2-booking/booking"
+	"alex-j-butler.com/tf2-booking/commands"
 	"alex-j-butler.com/tf2-booking/config"
+	"alex-j-butler.com/tf2-booking/events"
 	"alex-j-butler.com/tf2-booking/globals"
+	"alex-j-butler.com/tf2-booking/logging"
+	"alex-j-butler.com/tf2-booking/messages"
 	"alex-j-butler.com/tf2-booking/servers"
 	"alex-j-butler.com/tf2-booking/util"
 	"alex-j-butler.com/tf2-booking/wait"
+	log "github.com/Sirupsen/logrus"
 	"github.com/alex-j-butler/tablewriter"
 	"github.com/bwmarrin/discordgo"
 	"github.com/google/go-github/github"
 )
 
-func sendServerDetails(channelID string, serv *servers.Server, serverPassword, rconPassword string) {
-	Session.ChannelMessageSendEmbedWithContent(
-		channelID,
-		"**Here are the details for your booked server:**",
+// serverDetailsEmbeds builds the embeds containing the connection details of a booked server.
+func serverDetailsEmbeds(locale string, serv *servers.Server, serverPassword, rconPassword string) []*discordgo.MessageEmbed {
+	return []*discordgo.MessageEmbed{
 		&discordgo.MessageEmbed{
 			Color: 12763842,
 			Type:  "rich",
 			Fields: []*discordgo.MessageEmbedField{
 				&discordgo.MessageEmbedField{
-					Name:   "Server Address",
+					Name:   messages.Render(locale, "details.address", nil),
 					Value:  fmt.Sprintf("`%s`", serv.Address),
 					Inline: true,
 				},
 				&discordgo.MessageEmbedField{
-					Name:   "Server Password",
+					Name:   messages.Render(locale, "details.password", nil),
 					Value:  fmt.Sprintf("`%s`", serverPassword),
 					Inline: true,
 				},
 				&discordgo.MessageEmbedField{
-					Name:   "RCON Password",
+					Name:   messages.Render(locale, "details.rcon_password", nil),
 					Value:  fmt.Sprintf("`%s`", rconPassword),
 					Inline: true,
 				},
 			},
 		},
-	)
-	Session.ChannelMessageSendEmbedWithContent(
-		channelID,
-		"",
 		&discordgo.MessageEmbed{
 			Color: 321378,
 			Type:  "rich",
 			Fields: []*discordgo.MessageEmbedField{
 				&discordgo.MessageEmbedField{
-					Name:   "Connect String",
+					Name:   messages.Render(locale, "details.connect", nil),
 					Value:  fmt.Sprintf("`connect %s; password %s; rcon_password %s`", serv.Address, serverPassword, rconPassword),
 					Inline: false,
 				},
 				&discordgo.MessageEmbedField{
-					Name:   "STV String",
+					Name:   messages.Render(locale, "details.stv", nil),
 					Value:  fmt.Sprintf("`connect %s`", serv.STVAddress),
 					Inline: false,
 				},
 			},
 		},
-	)
-	Session.ChannelMessageSendEmbedWithContent(
-		channelID,
-		"",
 		&discordgo.MessageEmbed{
 			Color: 12763842,
 			Type:  "rich",
@@ -74,65 +71,129 @@ func sendServerDetails(channelID string, serv *servers.Server, serverPassword, r
 				Name: ChooseRandomTip(),
 			},
 		},
-	)
+	}
+}
+
+// serverDetailsContent returns the content of the message containing the server details.
+func serverDetailsContent(locale string) string {
+	return messages.Render(locale, "details.content", nil)
 }
 
-func Version(m *discordgo.MessageCreate, command string, args []string) {
-	User := &util.PatchUser{m.Author}
-	Session.ChannelMessageSend(m.ChannelID, fmt.Sprintf("%s: `tf2-booking` running git revision `%s`", User.GetMention(), version))
+func sendServerDetails(locale string, channelID string, serv *servers.Server, serverPassword, rconPassword string) {
+	Session.ChannelMessageSendComplex(
+		channelID,
+		&discordgo.MessageSend{
+			Content:    serverDetailsContent(locale),
+			Embeds:     serverDetailsEmbeds(locale, serv, serverPassword, rconPassword),
+			Components: bookingButtons(locale, serv, false),
+		},
+	)
 }
 
-func SyncServers(m *discordgo.MessageCreate, command string, args []string) {
-	User := &util.PatchUser{m.Author}
+// bookingErrorMessage returns the message to reply with for an error returned by the booking manager.
+func bookingErrorMessage(locale string, err error) string {
+	key := "error"
+	switch err {
+	case booking.ErrAlreadyBooked:
+		key = "booking.already_booked"
+	case booking.ErrNotBooked:
+		key = "booking.not_booked"
+	case booking.ErrNoServersAvailable:
+		key = "booking.no_servers"
+	case booking.ErrServerUnavailable:
+		key = "booking.server_unavailable"
+	case booking.ErrUnknownPreset:
+		key = "booking.unknown_preset"
+	case booking.ErrBookFailed:
+		key = "booking.failed"
+	case booking.ErrMaxDuration:
+		key = "booking.max_duration"
+	}
 
-	for i, server := range servers.Servers {
-		// Synchronise the server from Redis, to get information for existing servers.
-		err := server.Synchronise(globals.RedisClient)
+	return messages.Render(locale, key, nil)
+}
+
+// RequireBooking is command middleware that loads the booking linked to the caller into the context,
+// replying instead of running the command if they haven't booked a server.
+func RequireBooking(next commands.CommandFunction) commands.CommandFunction {
+	return func(ctx *commands.Context) {
+		serv, err := ctx.GetBooking()
 		if err != nil {
-			panic(err)
+			ctx.Reply(bookingErrorMessage(UserLocale(ctx.GetUserID()), err))
+			return
 		}
 
-		// Put the modified server back.
-		servers.Servers[i] = server
+		ctx.Server = serv
+		next(ctx)
 	}
+}
 
-	Session.ChannelMessageSend(m.ChannelID, fmt.Sprintf("%s: Synchronised all servers.", User.GetMention()))
+// NotifyCommandPanic notifies the admins of a command that panicked.
+func NotifyCommandPanic(ctx *commands.Context, recovered interface{}) {
+	NotifyAdmins(AdminText("command.panicked", messages.Data{
+		"Command":  ctx.Command,
+		"Username": ctx.GetUsername(),
+		"UserID":   ctx.GetUserID(),
+		"Channel":  ctx.GetChannelID(),
+		"Error":    recovered,
+	}))
 }
 
-func Help(m *discordgo.MessageCreate, command string, args []string) {
-	User := &util.PatchUser{m.Author}
+func Version(ctx *commands.Context) {
+	ctx.Reply(Text(ctx.GetUserID(), "version", messages.Data{"Version": version}))
+}
 
-	helpMessage := `book            - Book a new server
-unbook          - Unbook your current server
-send password   - Send the updated server details
-demos           - Send the link to the uploaded demos
-help            - Display the help message (you're reading it!)
+// SyncServers command handler.
+// Called when an admin types the 'sync' command into the Discord channel.
+// This function runs the state reconciler and replies with what it found.
+func SyncServers(ctx *commands.Context) {
+	reconciliations, err := ReconcileState()
+	if err != nil {
+		log.WithError(err).Error("State reconciliation failed")
+		ctx.Reply(Text(ctx.GetUserID(), "sync.failed", nil))
+		return
+	}
 
-For help, ping @Alex_#7324 in this channel.
+	ReportReconciliations(reconciliations)
 
-Note: Ozfortress booking commands also are accepted by this bot.`
+	if len(reconciliations) > 0 {
+		UpdateGameString()
+	}
 
-	Session.ChannelMessageSend(m.ChannelID, fmt.Sprintf("%s: **Qixalite Bookable Help**:```%s```", User.GetMention(), helpMessage))
+	ctx.Reply(Text(ctx.GetUserID(), "sync.done", messages.Data{"Count": len(reconciliations)}))
+}
+
+// Help command handler.
+// Called when the user types the 'help' command into the Discord channel.
+// This function lists the commands the user can run in the channel, or the details
+// of a single command if one is given.
+func Help(ctx *commands.Context) {
+	if ctx.Args.Has("command") {
+		handler, ok := Command.Lookup(ctx.Args.String("command"))
+		if !ok || !Command.CanRun(ctx.Session, ctx.Message, handler, ctx.Permissions) {
+			ctx.Reply(Text(ctx.GetUserID(), "help.unknown", messages.Data{"Command": ctx.Args.String("command")}))
+			return
+		}
+
+		ctx.Reply(Command.HelpCommand(handler))
+		return
+	}
+
+	helpMessage := Command.HelpList(Command.Available(ctx.Session, ctx.Message, ctx.Permissions))
+
+	ctx.Reply(Text(ctx.GetUserID(), "help.list", messages.Data{"Commands": helpMessage}))
 }
 
 // DemoLink command handler.
 // Called when the user types the 'demo' command into the Discord channel.
 // This function should send them the link to the Qixalite demo store.
-func DemoLink(m *discordgo.MessageCreate, command string, args []string) {
-	User := &util.PatchUser{m.Author}
-
-	demosTarget := strings.Join(args, " ")
+func DemoLink(ctx *commands.Context) {
+	demosTarget := ctx.Args.String("target")
 	if demosTarget == "" {
-		demosTarget = User.GetFullname()
+		demosTarget = ctx.User.GetFullname()
 	}
 
-	Session.ChannelMessageSend(m.ChannelID,
-		fmt.Sprintf(
-			"%s: https://stv.qixalite.com/?q=%s",
-			User.GetMention(),
-			url.QueryEscape(demosTarget),
-		),
-	)
+	ctx.Reply(Text(ctx.GetUserID(), "demos.link", messages.Data{"Target": demosTarget}))
 }
 
 // BookServer command handler
@@ -140,282 +201,112 @@ func DemoLink(m *discordgo.MessageCreate, command string, args []string) {
 // This function checks whether the user has a server booked, if not,
 // it books a new server, preventing it from being used by another user,
 // sets up the RCON password & Server Password and finally starts the TF2 server.
-func BookServer(m *discordgo.MessageCreate, command string, args []string) {
-	User := &util.PatchUser{m.Author}
+func BookServer(ctx *commands.Context) {
+	locale := UserLocale(ctx.GetUserID())
 
-	bookingInfo, err := GetDefaultValue.Run(globals.RedisClient, []string{fmt.Sprintf("user.%s", m.Author.ID)}, nil).Result()
+	b, err := Bookings.Book(ctx.Message.Author, "", "", 0)
 	if err != nil {
-		// Send a message to let the user know an error occurred.
-		Session.ChannelMessageSend(m.ChannelID, fmt.Sprintf("%s: Oops, looked like an error has occurred. Please contact an admin for assistance.", User.GetMention()))
+		ctx.Reply(bookingErrorMessage(locale, err))
 		return
 	}
 
-	if len(bookingInfo.(string)) != 0 {
-		// Send a message to let the user know they've already booked a server.
-		Session.ChannelMessageSend(m.ChannelID, fmt.Sprintf("%s: You've already booked a server. Type `unbook` to return the server.", User.GetMention()))
-		return
-	}
+	// Send message to public channel, without server details.
+	ctx.Reply(messages.Render(locale, "book.details_sent", nil))
 
-	// Get the next available server.
-	Serv := pool.GetAvailableServer()
-
-	if Serv != nil {
-		// Book the server.
-		RCONPassword, ServerPassword, err := Serv.Book(m.Author)
-		if err != nil {
-			Session.ChannelMessageSend(m.ChannelID, fmt.Sprintf("%s: Something went wrong while trying to book your server, please try again later.", User.GetMention()))
-			log.Println(fmt.Sprintf("Failed to book server \"%s\" from \"%s\":", Serv.Name, m.Author.ID), err)
-		} else {
-			// Start the server.
-			go func(Serv *servers.Server, m *discordgo.MessageCreate) {
-				err := Serv.Start()
-
-				if err != nil {
-					UserChannel, _ := Session.UserChannelCreate(m.Author.ID)
-					Session.ChannelMessageSend(
-						UserChannel.ID,
-						fmt.Sprintf(
-							"Uh oh! The server failed to start, contact an admin for further information.",
-						),
-					)
-					log.Println("Failed to book server:", err)
-
-					// Reset the user's booked state.
-					if err := globals.RedisClient.Set(fmt.Sprintf("user.%s", m.Author.ID), "", 0).Err(); err != nil {
-						log.Println("Redis error:", err)
-						log.Println("Failed to set user information for user:", m.Author.ID)
-						return
-					}
-
-					UpdateGameString()
-
-					log.Println(fmt.Sprintf("Failed to start server \"%s\" from \"%s\"", Serv.Name, m.Author.ID))
-				}
-			}(Serv, m)
-
-			// Send message to public channel, without server details.
-			Session.ChannelMessageSend(m.ChannelID, fmt.Sprintf("%s: Server details have been sent via private message.", User.GetMention()))
-
-			// Create the private DM channel, and then send the server details (and a small tip).
-			UserChannel, _ := Session.UserChannelCreate(m.Author.ID)
-			sendServerDetails(UserChannel.ID, Serv, ServerPassword, RCONPassword)
-
-			// Add the user's booked state.
-			if err := globals.RedisClient.Set(fmt.Sprintf("user.%s", m.Author.ID), Serv.UUID, 0).Err(); err != nil {
-				log.Println("Redis error:", err)
-				log.Println("Failed to set user information for user:", m.Author.ID)
-				return
-			}
-
-			UpdateGameString()
-
-			log.Println(fmt.Sprintf("Booked server \"%s\" from \"%s\"", Serv.Name, m.Author.ID))
-		}
+	// Create the private DM channel, and then send the server details (and a small tip).
+	channelID, err := ctx.DMChannel()
+	if err != nil {
+		b.Server.Log().WithError(err).Error("Failed to create user channel")
 	} else {
-		Session.ChannelMessageSend(m.ChannelID, fmt.Sprintf("%s: No servers are currently available.", User.GetMention()))
+		sendServerDetails(locale, channelID, b.Server, b.ServerPassword, b.RCONPassword)
 	}
 }
 
 // UnbookServer command handler
-// Called when a user types the 'unbook' command into the Discord channel.
-// This function checks whether the user has a server booked, if so,
-// it unbooks it, allowing it for use by another user, and shutting down
-// the TF2 server.
-func UnbookServer(m *discordgo.MessageCreate, command string, args []string) {
-	User := &util.PatchUser{m.Author}
-
-	bookingInfo, err := GetDefaultValue.Run(globals.RedisClient, []string{fmt.Sprintf("user.%s", m.Author.ID)}, nil).Result()
-	if err != nil {
-		// Send a message to let the user know an error occurred.
-		Session.ChannelMessageSend(m.ChannelID, fmt.Sprintf("%s: Oops, looked like an error has occurred. Please contact an admin for assistance.", User.GetMention()))
-		return
+// Called when a user types the 'unbook' command into the Discord channel, or ingame.
+// This function unbooks the user's server, allowing it for use by another user,
+// and shutting down the TF2 server.
+func UnbookServer(ctx *commands.Context) {
+	locale := UserLocale(ctx.GetUserID())
+
+	// The booker is notified by the subscribers when their server is returned from ingame.
+	reason, by := events.Returned, ""
+	if ctx.Ingame() {
+		reason, by = events.ReturnedIngame, ctx.GetUsername()
 	}
-	bookingInfoStr := bookingInfo.(string)
 
-	if len(bookingInfoStr) == 0 {
-		// Send a message to let the user know they do not have a server booked.
-		Session.ChannelMessageSend(m.ChannelID, fmt.Sprintf("%s: You haven't booked a server. Type `book` to book a server.", User.GetMention()))
+	if err := Bookings.UnbookServer(ctx.Server, reason, by); err != nil {
+		ctx.Reply(bookingErrorMessage(locale, err))
 		return
 	}
 
-	Serv, err := pool.GetServerByUUID(bookingInfoStr)
-
-	if err == nil && Serv != nil {
-		// Stop the server.
-		go func(Serv *servers.Server, m *discordgo.MessageCreate) {
-			err := Serv.Stop()
-
-			if err != nil {
-				UserChannel, _ := Session.UserChannelCreate(m.Author.ID)
-				Session.ChannelMessageSend(
-					UserChannel.ID,
-					fmt.Sprintf(
-						"Uh oh! The server failed to stop, contact an admin for further information, or leave us to handle it.",
-					),
-				)
-			}
-		}(Serv, m)
-
-		// Remove the user's booked state.
-		if err := globals.RedisClient.Set(fmt.Sprintf("user.%s", m.Author.ID), "", 0).Err(); err != nil {
-			log.Println("Redis error:", err)
-			log.Println("Failed to set user information for user:", m.Author.ID)
-			return
-		}
-
-		// Unbook the server.
-		Serv.Unbook()
-
-		// Upload STV demos
-		STVMessage, err := Serv.UploadSTV()
-
-		// Send 'returned' message.
-		Session.ChannelMessageSend(m.ChannelID, fmt.Sprintf("%s: Server returned.", User.GetMention()))
-
-		// Send 'stv' message, if it uploaded successfully.
-		if err == nil {
-			Session.ChannelMessageSend(m.ChannelID, fmt.Sprintf("%s: %s", User.GetMention(), STVMessage))
-		}
-
-		UpdateGameString()
-
-		log.Println(fmt.Sprintf("Unbooked server \"%s\" from \"%s\"", Serv.Name, m.Author.ID))
-	} else {
-		Session.ChannelMessageSend(m.ChannelID, fmt.Sprintf("%s: You haven't booked a server. Type `book` to book a server.", User.GetMention()))
-
-		// We're in an invalid state, reset back to normal.
-		if err := globals.RedisClient.Set(fmt.Sprintf("user.%s", m.Author.ID), "", 0).Err(); err != nil {
-			log.Println("Redis error:", err)
-			log.Println("Failed to set user information for user:", m.Author.ID)
-			return
-		}
-
-		return
-	}
+	// Send 'returned' message.
+	ctx.Reply(messages.Render(locale, "unbook.returned", nil))
 }
 
 // ExtendServer command handler
-// Called when a user types the 'extend' command into the Discord channel.
-// This function checks whether the user has a server booked out, if so,
-// it will extend the booking by adding time onto the servers return time.
-func ExtendServer(m *discordgo.MessageCreate, command string, args []string) {
-	User := &util.PatchUser{m.Author}
-
-	bookingInfo, err := GetDefaultValue.Run(globals.RedisClient, []string{fmt.Sprintf("user.%s", m.Author.ID)}, nil).Result()
+// Called when a user types the 'extend' command into the Discord channel, or ingame.
+// This function extends the user's booking by adding time onto the servers return time.
+func ExtendServer(ctx *commands.Context) {
+	duration, err := Bookings.ExtendServer(ctx.Server, ctx.Args.Duration("duration"))
 	if err != nil {
-		// Send a message to let the user know an error occurred.
-		Session.ChannelMessageSend(m.ChannelID, fmt.Sprintf("%s: Oops, looked like an error has occurred. Please contact an admin for assistance.", User.GetMention()))
+		ctx.Reply(bookingErrorMessage(UserLocale(ctx.GetUserID()), err))
 		return
 	}
-	bookingInfoStr := bookingInfo.(string)
 
-	if len(bookingInfoStr) == 0 {
-		// Send a message to let the user know they do not have a server booked.
-		Session.ChannelMessageSend(m.ChannelID, fmt.Sprintf("%s: You haven't booked a server. Type `book` to book a server.", User.GetMention()))
-		return
+	message := Text(ctx.GetUserID(), "extend.extended", messages.Data{"Duration": duration})
+
+	// Notify server of successful operation, ingame commands are already replied to on the server.
+	if !ctx.Ingame() {
+		ctx.Server.Say(ctx.GetUsername(), message)
 	}
 
-	Serv, err := pool.GetServerByUUID(bookingInfoStr)
-
-	if err == nil && Serv != nil {
-		Serv.ExtendBooking()
-
-		// Notify server of successful operation.
-		Serv.SendCommand(
-			fmt.Sprintf(
-				"say @%s: Your booking has been extended by %s.",
-				m.Author.Username,
-				fmt.Sprintf("%d minutes", config.Conf.Booking.MaxIdleMinutes),
-			),
-		)
-
-		// Notify Discord channel of successful operation.
-		Session.ChannelMessageSend(
-			m.ChannelID,
-			fmt.Sprintf(
-				"%s: Your booking has been extended by %s.",
-				User.GetMention(),
-				fmt.Sprintf("%d minutes", config.Conf.Booking.MaxIdleMinutes),
-			),
-		)
-	} else {
-		// Notify Discord channel of failed operation.
-		Session.ChannelMessageSend(m.ChannelID, fmt.Sprintf("%s: You haven't booked a server. Type `book` to book a server.", User.GetMention()))
-
-		// If the program execution reaches here, the state of the users & user-servers map
-		// is invalid and should be reset to the 'unbooked' state.
-		if err := globals.RedisClient.Set(fmt.Sprintf("user.%s", m.Author.ID), "", 0).Err(); err != nil {
-			log.Println("Redis error:", err)
-			log.Println("Failed to set user information for user:", m.Author.ID)
-			return
-		}
+	// Notify the caller of successful operation.
+	ctx.Reply(message)
+}
 
+// TimeLeft command handler
+// Called when a user types the 'time' command into the Discord channel, or ingame.
+// This function replies with the time remaining until the booking is returned.
+func TimeLeft(ctx *commands.Context) {
+	duration := ctx.Server.TimeLeft()
+	if ctx.Server.ReturnDate.IsZero() {
+		ctx.Reply(Text(ctx.GetUserID(), "time.no_return", nil))
 		return
 	}
+
+	if duration < 0 {
+		duration = 0
+	}
+
+	ctx.Reply(Text(ctx.GetUserID(), "time.left", messages.Data{"Duration": duration}))
 }
 
-func SendPassword(m *discordgo.MessageCreate, command string, args []string) {
-	User := &util.PatchUser{m.Author}
+// SendPassword command handler
+// Called when a user types the 'send password' command into the Discord channel.
+// This function sends the current details of the user's server via private message.
+func SendPassword(ctx *commands.Context) {
+	locale := UserLocale(ctx.GetUserID())
 
-	bookingInfo, err := GetDefaultValue.Run(globals.RedisClient, []string{fmt.Sprintf("user.%s", m.Author.ID)}, nil).Result()
+	serverPassword, err := ctx.Server.GetCurrentPassword()
 	if err != nil {
-		// Send a message to let the user know an error occurred.
-		Session.ChannelMessageSend(m.ChannelID, fmt.Sprintf("%s: Oops, looked like an error has occurred. Please contact an admin for assistance.", User.GetMention()))
+		ctx.Reply(messages.Render(locale, "password.failed", nil))
 		return
 	}
-	bookingInfoStr := bookingInfo.(string)
 
-	if len(bookingInfoStr) == 0 {
-		// Send a message to let the user know they do not have a server booked.
-		Session.ChannelMessageSend(m.ChannelID, fmt.Sprintf("%s: You haven't booked a server. Type `book` to book a server.", User.GetMention()))
-		return
-	}
+	// Send message to public channel, without server details.
+	ctx.Reply(messages.Render(locale, "password.sent", nil))
 
-	Serv, err := pool.GetServerByUUID(bookingInfoStr)
-
-	if err == nil && Serv != nil {
-		serverPassword, err := Serv.GetCurrentPassword()
-		if err != nil {
-			Session.ChannelMessageSend(
-				m.ChannelID,
-				fmt.Sprintf(
-					"%s: We failed to retrieve your server password.",
-					User.GetMention(),
-				),
-			)
-
-			return
-		}
-
-		// Send message to public channel, without server details.
-		Session.ChannelMessageSend(m.ChannelID, fmt.Sprintf("%s: Server password have been sent via private message.", User.GetMention()))
-
-		// Send message to private DM, with server details.
-		UserChannel, _ := Session.UserChannelCreate(m.Author.ID)
-		Session.ChannelMessageSend(
-			UserChannel.ID,
-			fmt.Sprintf(
-				"Here is your server details:\n\tServer address: %s\n\tPassword: %s\n\tConnect string: `connect %s; password %s`",
-				Serv.Address,
-				serverPassword,
-				Serv.Address,
-				serverPassword,
-			),
-		)
-	} else {
-		// Notify Discord channel of failed operation.
-		Session.ChannelMessageSend(m.ChannelID, fmt.Sprintf("%s: You haven't booked a server. Type `book` to book a server.", User.GetMention()))
-
-		// If the program execution reaches here, the state of the users & user-servers map
-		// is invalid and should be reset to the 'unbooked' state.
-		if err := globals.RedisClient.Set(fmt.Sprintf("user.%s", m.Author.ID), "", 0).Err(); err != nil {
-			log.Println("Redis error:", err)
-			log.Println("Failed to set user information for user:", m.Author.ID)
-			return
-		}
+	// Send message to private DM, with server details.
+	ctx.DM(passwordMessage(locale, ctx.Server, serverPassword))
+}
 
-		return
-	}
+// passwordMessage returns the message containing the server details sent by 'send password'.
+func passwordMessage(locale string, serv *servers.Server, serverPassword string) string {
+	return messages.Render(locale, "details.password_message", messages.Data{
+		"Address":  serv.Address,
+		"Password": serverPassword,
+	})
 }
 
 func getServerStatusString(server *servers.Server) string {
@@ -425,16 +316,14 @@ func getServerStatusString(server *servers.Server) string {
 	return "Stopped"
 }
 
-func PrintStats(m *discordgo.MessageCreate, command string, args []string) {
-	User := &util.PatchUser{m.Author}
-
+func PrintStats(ctx *commands.Context) {
 	servs := pool.GetServers()
 	// Sync the servers from redis.
 	for i, server := range servs {
 		// Synchronise the server from Redis, to get information for existing servers.
 		err := server.Synchronise(globals.RedisClient)
 		if err != nil {
-			log.Println("Server", server.Name, "failed to Redis sync")
+			server.Log().WithError(err).Error("Failed to Redis sync")
 			continue
 		}
 
@@ -442,13 +331,13 @@ func PrintStats(m *discordgo.MessageCreate, command string, args []string) {
 		servs[i] = server
 	}
 
-	message := "Server stats:"
+	message := Text(ctx.GetUserID(), "stats.header", nil)
 
 	data := make([][]string, 0, len(servs))
 	for _, serv := range servs {
 		// Retrieve the name of the Discord user who booked the server. Uses empty string if no one has booked the server.
 		bookerID := serv.Booker
-		bookerUser, err := Session.User(bookerID)
+		bookerUser, err := ctx.Session.User(bookerID)
 
 		var username string
 		if err != nil {
@@ -468,58 +357,154 @@ func PrintStats(m *discordgo.MessageCreate, command string, args []string) {
 	table.AppendBulk(data)
 	table.Render()
 
-	message = fmt.Sprintf("%s\n```%s```", message, buf.String())
-	Session.ChannelMessageSend(m.ChannelID, fmt.Sprintf("%s: %s", User.GetMention(), message))
+	ctx.Replyf("%s\n```%s```", message, buf.String())
 }
 
-func Update(m *discordgo.MessageCreate, command string, args []string) {
-	User := &util.PatchUser{m.Author}
+// ForceUnbookServer command handler.
+// Called when an admin types the 'force unbook <server>' command into the Discord channel.
+// This function unbooks & stops the server regardless of who booked it.
+func ForceUnbookServer(ctx *commands.Context) {
+	locale := UserLocale(ctx.GetUserID())
+
+	Serv, err := pool.GetServerByName(ctx.Args.String("server"))
+	if err != nil {
+		ctx.Reply(messages.Render(locale, "force_unbook.unknown", nil))
+		return
+	}
 
-	if len(args) <= 0 {
-		// Send usage.
-		Session.ChannelMessageSend(m.ChannelID, fmt.Sprintf("%s: Usage: `update <release tag>`", User.GetMention()))
+	if !Serv.Booked {
+		ctx.Reply(messages.Render(locale, "force_unbook.not_booked", nil))
 		return
 	}
 
+	BookerMention := Serv.BookerMention
+
+	// The booker is notified by the subscribers that their server was taken from them.
+	if err := Bookings.Release(Serv, events.Forced); err != nil {
+		ctx.Reply(messages.Render(locale, "force_unbook.failed", nil))
+		return
+	}
+
+	ctx.Reply(messages.Render(locale, "force_unbook.done", messages.Data{"Server": Serv.Name}))
+
+	log.WithFields(log.Fields{logging.ServerField: Serv.Name, logging.ServerUUIDField: Serv.UUID, "admin_id": ctx.GetUserID()}).Info("Force unbooked server")
+
+	Audit.Log(audit.Event{
+		Type:     audit.AdminAction,
+		UserID:   ctx.GetUserID(),
+		Username: ctx.GetUsername(),
+		Server:   Serv.Name,
+		Reason:   fmt.Sprintf("Force unbooked the server booked by %s", BookerMention),
+	})
+}
+
+// ExplainPermissions command handler.
+// Called when a user types the 'permissions' command into the Discord channel.
+// This function lists the permission groups the user is in, and the commands they may run.
+func ExplainPermissions(ctx *commands.Context) {
+	var allowed []string
+	for name, handler := range Command.Handlers {
+		if Command.Allowed(handler, ctx.Permissions, ctx.Message.Author.ID, ctx.Roles) {
+			allowed = append(allowed, fmt.Sprintf("`%s`", name))
+		}
+	}
+	sort.Strings(allowed)
+
+	locale := UserLocale(ctx.GetUserID())
+
+	groups := commands.PermissionGroups(config.Get().Permissions).Matching(ctx.Message.Author.ID, ctx.Roles)
+	groupsMessage := messages.Render(locale, "permissions.none", nil)
+	if len(groups) > 0 {
+		groupsMessage = messages.Render(locale, "permissions.groups", messages.Data{"Groups": strings.Join(groups, ", ")})
+	}
+
+	ctx.Reply(messages.Render(locale, "permissions.list", messages.Data{
+		"Groups":   groupsMessage,
+		"Commands": strings.Join(allowed, ", "),
+	}))
+}
+
+// Language command handler.
+// Called when a user types the 'language' command into the Discord channel, or ingame.
+// This function sets the language the user is sent messages in, or lists the available languages.
+func Language(ctx *commands.Context) {
+	userID := ctx.GetUserID()
+	locales := strings.Join(messages.Default.Locales(), ", ")
+
+	if !ctx.Args.Has("locale") {
+		ctx.Reply(Text(userID, "language.current", messages.Data{"Locale": UserLocale(userID), "Locales": locales}))
+		return
+	}
+
+	locale := ctx.Args.String("locale")
+	if !messages.Default.Has(locale) {
+		ctx.Reply(Text(userID, "language.unknown", messages.Data{"Locale": locale, "Locales": locales}))
+		return
+	}
+
+	if err := SetUserLocale(userID, locale); err != nil {
+		logging.User(userID).WithError(err).Error("Failed to set locale for user")
+		ctx.Reply(Text(userID, "error", nil))
+		return
+	}
+
+	ctx.Reply(messages.Render(locale, "language.set", messages.Data{"Locale": locale}))
+}
+
+func Update(ctx *commands.Context) {
+	locale := UserLocale(ctx.GetUserID())
+
 	// Create a GitHub API client.
 	client := github.NewClient(nil)
 	// Tag name
-	tagName := args[0]
+	tagName := ctx.Args.String("tag")
 
 	// Get release by tag.
 	release, _, err := client.Repositories.GetReleaseByTag("alex-j-butler", "tf2-booking", tagName)
 	if err != nil {
 		// Send error message.
-		Session.ChannelMessageSend(m.ChannelID, fmt.Sprintf("%s: Failed to retrieve release.", User.GetMention()))
+		ctx.Reply(messages.Render(locale, "update.release_failed", nil))
 		return
 	}
 
 	asset, err := util.GetReleaseAsset(release.Assets, "tf2-booking-amd64")
 	if err != nil {
 		// Send error message.
-		Session.ChannelMessageSend(m.ChannelID, fmt.Sprintf("%s: Failed to retrieve release asset.", User.GetMention()))
+		ctx.Reply(messages.Render(locale, "update.asset_failed", nil))
 		return
 	}
 
 	//
-	Session.ChannelMessageSend(m.ChannelID, fmt.Sprintf("%s: Starting update to release %s", User.GetMention(), *release.TagName))
+	ctx.Reply(messages.Render(locale, "update.starting", messages.Data{"Release": *release.TagName}))
+
+	Audit.Log(audit.Event{
+		Type:     audit.AdminAction,
+		UserID:   ctx.GetUserID(),
+		Username: ctx.GetUsername(),
+		Reason:   fmt.Sprintf("Updated to release `%s`", *release.TagName),
+	})
 
 	go func(asset github.ReleaseAsset) {
 		// Update the executable.
 		UpdateExecutable(*asset.BrowserDownloadURL)
 
 		// Send the success notification.
-		Session.ChannelMessageSend(m.ChannelID, fmt.Sprintf("%s: Updated `tf2-booking` & restarting now.", User.GetMention()))
+		ctx.Reply(messages.Render(locale, "update.restarting", nil))
 
 		// Annnnnd, exit.
 		wait.Exit()
 	}(asset)
 }
 
-func Exit(m *discordgo.MessageCreate, command string, args []string) {
-	User := &util.PatchUser{m.Author}
+func Exit(ctx *commands.Context) {
+	ctx.Reply(Text(ctx.GetUserID(), "exit", nil))
 
-	Session.ChannelMessageSend(m.ChannelID, fmt.Sprintf("%s: Shutting down `tf2-booking`.", User.GetMention()))
+	Audit.Log(audit.Event{
+		Type:     audit.AdminAction,
+		UserID:   ctx.GetUserID(),
+		Username: ctx.GetUsername(),
+		Reason:   "Shut down the bot",
+	})
 
 	wait.Exit()
 }
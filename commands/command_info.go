@@ -2,30 +2,57 @@ package commands
 
 import (
 	"fmt"
-	"log"
+
+	"alex-j-butler.com/tf2-booking/booking"
+	"alex-j-butler.com/tf2-booking/globals"
+	"alex-j-butler.com/tf2-booking/servers"
+	"alex-j-butler.com/tf2-booking/util"
 
 	"github.com/bwmarrin/discordgo"
 )
 
+// CommandInformation is where a command was sent from, allowing a command handler
+// to be used for both Discord & ingame commands.
 type CommandInformation interface {
+	// ReplyChannel sends a message to where the command was sent, addressed to the caller.
 	ReplyChannel(formatStr string, args ...interface{})
+	// ReplyUser sends a message privately to the caller.
 	ReplyUser(formatStr string, args ...interface{})
 	GetChannelID() string
 	GetUserID() string
+	GetUsername() string
+	// GetBooking returns the booked server linked to the caller.
+	GetBooking() (*servers.Server, error)
 }
 
+// DiscordCommandInformation is a command sent as a Discord message.
+// The caller is linked to the server they have booked.
 type DiscordCommandInformation struct {
 	*discordgo.MessageCreate
 
-	session *discordgo.Session
+	session  *discordgo.Session
+	bookings *booking.Manager
+}
+
+// NewDiscordCommandInformation creates the information for a command sent as a Discord message.
+func NewDiscordCommandInformation(session *discordgo.Session, m *discordgo.MessageCreate, bookings *booking.Manager) *DiscordCommandInformation {
+	return &DiscordCommandInformation{
+		MessageCreate: m,
+		session:       session,
+		bookings:      bookings,
+	}
 }
 
 func (c *DiscordCommandInformation) ReplyChannel(formatStr string, args ...interface{}) {
-	c.session.ChannelMessageSend(c.ChannelID, fmt.Sprintf(formatStr, args...))
+	User := &util.PatchUser{c.Author}
+	c.session.ChannelMessageSend(c.ChannelID, fmt.Sprintf("%s: %s", User.GetMention(), fmt.Sprintf(formatStr, args...)))
 }
 
 func (c *DiscordCommandInformation) ReplyUser(formatStr string, args ...interface{}) {
-	UserChannel, _ := c.session.UserChannelCreate(c.Author.ID)
+	UserChannel, err := c.session.UserChannelCreate(c.Author.ID)
+	if err != nil {
+		return
+	}
 	c.session.ChannelMessageSend(UserChannel.ID, fmt.Sprintf(formatStr, args...))
 }
 
@@ -37,23 +64,122 @@ func (c *DiscordCommandInformation) GetUserID() string {
 	return c.Author.ID
 }
 
+func (c *DiscordCommandInformation) GetUsername() string {
+	return c.Author.Username
+}
+
+func (c *DiscordCommandInformation) GetBooking() (*servers.Server, error) {
+	if c.bookings == nil {
+		return nil, booking.ErrNotBooked
+	}
+
+	return c.bookings.BookedServer(c.Author.ID)
+}
+
+// InteractionCommandInformation is a slash command or message component used in Discord.
+// Its replies are only shown to the caller, and it isn't linked to a booking, as its handlers look the booking up.
+type InteractionCommandInformation struct {
+	*discordgo.InteractionCreate
+
+	session *discordgo.Session
+}
+
+// NewInteractionCommandInformation creates the information for a slash command or message component.
+func NewInteractionCommandInformation(session *discordgo.Session, i *discordgo.InteractionCreate) *InteractionCommandInformation {
+	return &InteractionCommandInformation{
+		InteractionCreate: i,
+		session:           session,
+	}
+}
+
+func (c *InteractionCommandInformation) ReplyChannel(formatStr string, args ...interface{}) {
+	content := fmt.Sprintf(formatStr, args...)
+
+	// The interaction may already have been responded to, in which case the reply follows the response.
+	if err := Respond(c.session, c.InteractionCreate, content, true); err != nil {
+		c.session.FollowupMessageCreate(c.Interaction, false, &discordgo.WebhookParams{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		})
+	}
+}
+
+func (c *InteractionCommandInformation) ReplyUser(formatStr string, args ...interface{}) {
+	UserChannel, err := c.session.UserChannelCreate(c.GetUserID())
+	if err != nil {
+		return
+	}
+	c.session.ChannelMessageSend(UserChannel.ID, fmt.Sprintf(formatStr, args...))
+}
+
+func (c *InteractionCommandInformation) GetChannelID() string {
+	return c.ChannelID
+}
+
+func (c *InteractionCommandInformation) GetUserID() string {
+	return InteractionUser(c.InteractionCreate).ID
+}
+
+func (c *InteractionCommandInformation) GetUsername() string {
+	return InteractionUser(c.InteractionCreate).Username
+}
+
+func (c *InteractionCommandInformation) GetBooking() (*servers.Server, error) {
+	return nil, booking.ErrNotBooked
+}
+
+// TF2CommandInformation is a command sent in the ingame chat of a server.
+// The caller is linked to the booking of the server they sent the command on.
 type TF2CommandInformation struct {
-	steamID    string
-	serverName string
+	steamID  string
+	username string
+	server   *servers.Server
+}
+
+// NewTF2CommandInformation creates the information for a command sent by a player in the ingame chat.
+func NewTF2CommandInformation(server *servers.Server, steamID, username string) *TF2CommandInformation {
+	return &TF2CommandInformation{
+		steamID:  steamID,
+		username: username,
+		server:   server,
+	}
 }
 
 func (c *TF2CommandInformation) ReplyChannel(formatStr string, args ...interface{}) {
-	log.Println("TF2Command, ReplyChannel:", fmt.Sprintf(formatStr, args...))
+	c.server.Say(c.username, fmt.Sprintf(formatStr, args...))
 }
 
+// ReplyUser replies in the server's chat, addressed to the player, as a stock server can't message players privately.
 func (c *TF2CommandInformation) ReplyUser(formatStr string, args ...interface{}) {
-	log.Println("TF2Command, ReplyUser:", fmt.Sprintf(formatStr, args...))
+	c.server.Say(c.username, fmt.Sprintf(formatStr, args...))
 }
 
 func (c *TF2CommandInformation) GetChannelID() string {
-	return c.serverName
+	return c.server.Name
 }
 
 func (c *TF2CommandInformation) GetUserID() string {
 	return c.steamID
 }
+
+func (c *TF2CommandInformation) GetUsername() string {
+	return c.username
+}
+
+func (c *TF2CommandInformation) GetBooking() (*servers.Server, error) {
+	// Synchronise the server from Redis, to get the current booking.
+	if err := c.server.Synchronise(globals.RedisClient); err != nil {
+		return nil, err
+	}
+
+	if !c.server.Booked {
+		return nil, booking.ErrNotBooked
+	}
+
+	return c.server, nil
+}
+
+// GetServer returns the server the command was sent on.
+func (c *TF2CommandInformation) GetServer() *servers.Server {
+	return c.server
+}
@@ -1,27 +1,57 @@
 package commands
 
 import (
-	"fmt"
-	"log"
-	"reflect"
 	"strings"
 
+	"alex-j-butler.com/tf2-booking/booking"
+	"alex-j-butler.com/tf2-booking/commands/parser"
+	"alex-j-butler.com/tf2-booking/config"
+	"alex-j-butler.com/tf2-booking/messages"
 	"alex-j-butler.com/tf2-booking/util"
 
+	log "github.com/Sirupsen/logrus"
 	"github.com/bwmarrin/discordgo"
 )
 
-type CommandFunction func(*discordgo.MessageCreate, string, []string)
+type CommandFunction func(*Context)
+
+// UserLocale returns the locale of the user that the commands reply in,
+// the default locale unless the bot looks up the users' language preferences.
+var UserLocale = func(userID string) string {
+	return messages.Default.DefaultLocale
+}
+
+// Text renders the message in the locale of the user.
+func Text(userID string, key string, data messages.Data) string {
+	return messages.Render(UserLocale(userID), key, data)
+}
 
 type CommandHandler struct {
+	name        string
+	aliases     []string
 	function    CommandFunction
-	permissions int
+	params      []parser.Param
+	description string
+	usage       string
+	category    string
+	middleware  []Middleware
+	permissions int64
+	group       string
 	respondToDM bool
 }
 
 type Command struct {
 	Prefix   string
 	Handlers map[string]*CommandHandler
+
+	// Bookings links Discord users to their booked servers.
+	Bookings *booking.Manager
+
+	router     parser.Router
+	middleware []Middleware
+
+	// Handlers in the order they were registered, for listing in the help.
+	handlers []*CommandHandler
 }
 
 func NewCommand(function CommandFunction) *CommandHandler {
@@ -32,11 +62,50 @@ func NewCommand(function CommandFunction) *CommandHandler {
 	}
 }
 
-func (ch *CommandHandler) Permissions(permissions int) *CommandHandler {
+// Params declares the parameters of the command, which the arguments are parsed into.
+// A usage error is replied with if the arguments don't match.
+func (ch *CommandHandler) Params(params ...parser.Param) *CommandHandler {
+	ch.params = params
+	return ch
+}
+
+// Description sets the short description of the command shown in the help.
+func (ch *CommandHandler) Description(description string) *CommandHandler {
+	ch.description = description
+	return ch
+}
+
+// Usage overrides the usage of the command, which is otherwise generated from its parameters.
+// The usage shouldn't include the command itself, eg. "[duration]".
+func (ch *CommandHandler) Usage(usage string) *CommandHandler {
+	ch.usage = usage
+	return ch
+}
+
+// Category sets the category the command is listed under in the help.
+func (ch *CommandHandler) Category(category string) *CommandHandler {
+	ch.category = category
+	return ch
+}
+
+// Use adds middleware to run for this command only, after the middleware of the Command.
+func (ch *CommandHandler) Use(middleware ...Middleware) *CommandHandler {
+	ch.middleware = append(ch.middleware, middleware...)
+	return ch
+}
+
+func (ch *CommandHandler) Permissions(permissions int64) *CommandHandler {
 	ch.permissions = permissions
 	return ch
 }
 
+// Group sets the command group of the command, allowing the permission groups in the
+// configuration to grant the command without the Discord permissions.
+func (ch *CommandHandler) Group(group string) *CommandHandler {
+	ch.group = group
+	return ch
+}
+
 func (ch *CommandHandler) RespondToDM(respondToDM bool) *CommandHandler {
 	ch.respondToDM = respondToDM
 	return ch
@@ -51,59 +120,171 @@ func New(prefix string) *Command {
 	}
 }
 
+// Use adds middleware to run for every command, in the order it was added.
+func (c *Command) Use(middleware ...Middleware) {
+	c.middleware = append(c.middleware, middleware...)
+}
+
 // Add creates a new entry in the command handlers map.
 // First argument accepts a command handler implementing the type `CommandHandler`,
 // Second argument accepts a variable amount of strings specifying the commands to register.
+// The first command is used as the name of the handler, for configuring its rate limit,
+// and the rest are listed as its aliases in the help.
 func (c *Command) Add(handler *CommandHandler, commands ...string) {
+	if handler.name == "" && len(commands) > 0 {
+		handler.name = commands[0]
+	}
+
+	listed := false
+	for _, h := range c.handlers {
+		if h == handler {
+			listed = true
+			break
+		}
+	}
+	if !listed {
+		c.handlers = append(c.handlers, handler)
+	}
+
 	for _, command := range commands {
 		c.Handlers[command] = handler
+		c.router.Add(command, handler)
+
+		if command != handler.name {
+			handler.aliases = append(handler.aliases, command)
+		}
 	}
 }
 
 // Remove deletes entries from the command handlers map.
 func (c *Command) Remove(commands ...string) {
 	for _, command := range commands {
-		delete(c.Handlers, command)
-	}
-}
-
-// Handle the incoming commands and dispatches them to the appropriate
-// command handler, after parsing them.
-func (c *Command) Handle(session *discordgo.Session, m *discordgo.MessageCreate, command string, permissions int) {
-	for str, handler := range c.Handlers {
-		if !strings.HasPrefix(command, c.Prefix) && c.Prefix != "" {
+		handler, ok := c.Handlers[command]
+		if !ok {
 			continue
 		}
 
-		handlerSplit := strings.Split(str, " ")
-		commandSplit := strings.Split(command[len(c.Prefix):], " ")
+		delete(c.Handlers, command)
+		c.router.Remove(command)
 
-		if len(commandSplit) < len(handlerSplit) {
-			continue
+		for i, alias := range handler.aliases {
+			if alias == command {
+				handler.aliases = append(handler.aliases[:i], handler.aliases[i+1:]...)
+				break
+			}
 		}
+	}
 
-		if !handler.respondToDM {
-			channel, err := session.State.Channel(m.ChannelID)
-			if err != nil {
-				log.Println("Failed to lookup channel.", err)
+	// Stop listing handlers that no longer have any commands.
+	handlers := c.handlers[:0]
+	for _, handler := range c.handlers {
+		for _, registered := range c.Handlers {
+			if registered == handler {
+				handlers = append(handlers, handler)
+				break
 			}
+		}
+	}
+	c.handlers = handlers
+}
 
-			if channel.Type == discordgo.ChannelTypeDM {
-				continue
-			}
+// Allowed returns whether the caller with the specified Discord permissions & roles may run the command,
+// either through their Discord permissions or a permission group granting the command's group.
+func (c *Command) Allowed(handler *CommandHandler, permissions int64, userID string, roles []string) bool {
+	return handlerAllowed(handler, permissions, userID, roles)
+}
+
+func handlerAllowed(handler *CommandHandler, permissions int64, userID string, roles []string) bool {
+	return allowed(PermissionGroups(config.Get().Permissions), handler.permissions, handler.group, permissions, userID, roles)
+}
+
+// CallerRoles returns the role IDs of the message author, from the guild the message was sent in,
+// or the guild of the default channel for direct messages.
+func CallerRoles(session *discordgo.Session, m *discordgo.MessageCreate) []string {
+	guildID := m.GuildID
+	if guildID == "" {
+		channel, err := session.State.Channel(config.Get().Discord.DefaultChannel)
+		if err != nil {
+			return nil
 		}
+		guildID = channel.GuildID
+	}
 
-		if reflect.DeepEqual(handlerSplit, commandSplit[:len(handlerSplit)]) {
-			log.Println(fmt.Sprintf("Permissions test: %d & %d = %d", permissions, handler.permissions, permissions&handler.permissions))
+	return MemberRoles(session, guildID, m.Author.ID)
+}
 
-			if permissions&handler.permissions != 0 || handler.permissions == -1 {
-				handler.function(m, strings.Join(handlerSplit, " "), commandSplit[len(handlerSplit):])
-			} else {
-				User := &util.PatchUser{m.Author}
-				session.ChannelMessageSend(m.ChannelID, fmt.Sprintf("%s: You don't have permission for that command.", User.GetMention()))
-			}
+// Handle the incoming commands and dispatches them to the appropriate
+// command handler, through the middleware.
+func (c *Command) Handle(session *discordgo.Session, m *discordgo.MessageCreate, command string, permissions int64) {
+	if !strings.HasPrefix(command, c.Prefix) {
+		return
+	}
 
-			break
+	// An unterminated quote is only reported if the message is a command.
+	tokens, tokenizeErr := parser.Tokenize(command[len(c.Prefix):])
+	if tokenizeErr != nil {
+		tokens = strings.Fields(command[len(c.Prefix):])
+	}
+
+	value, name, rest, ok := c.router.Match(tokens)
+	if !ok {
+		return
+	}
+	handler := value.(*CommandHandler)
+
+	if !handler.respondToDM {
+		channel, err := session.State.Channel(m.ChannelID)
+		if err != nil {
+			log.WithError(err).WithField("channel_id", m.ChannelID).Error("Failed to lookup channel")
+		}
+
+		if channel != nil && channel.Type == discordgo.ChannelTypeDM {
+			return
 		}
 	}
+
+	ctx := &Context{
+		CommandInformation: NewDiscordCommandInformation(session, m, c.Bookings),
+
+		Session:     session,
+		Message:     m,
+		User:        &util.PatchUser{m.Author},
+		Command:     name,
+		Name:        handler.name,
+		Handler:     handler,
+		Permissions: permissions,
+		Roles:       CallerRoles(session, m),
+		rest:        rest,
+		tokenizeErr: tokenizeErr,
+	}
+
+	function := Chain(c.dispatch, handler.middleware)
+	function = Chain(function, c.middleware)
+	function(ctx)
+}
+
+// dispatch parses the arguments of the command and calls the command handler,
+// replying with the usage if the arguments don't match.
+func (c *Command) dispatch(ctx *Context) {
+	args, err := parser.Parse(ctx.Handler.params, ctx.rest)
+	if ctx.tokenizeErr != nil {
+		err = ctx.tokenizeErr
+	}
+	if err != nil {
+		ctx.Replyf("%s. Usage: `%s`", usageReason(err), c.usage(ctx.Command, ctx.Handler))
+		return
+	}
+
+	ctx.Args = args
+	ctx.Handler.function(ctx)
+}
+
+// usageReason returns the reason a command's arguments failed to parse, to show alongside its usage.
+func usageReason(err error) string {
+	if err == parser.ErrUnterminatedQuote {
+		return "Missing a closing quote"
+	}
+
+	reason := err.Error()
+	return strings.ToUpper(reason[:1]) + reason[1:]
 }
@@ -0,0 +1,145 @@
+package commands
+
+import (
+	"strings"
+
+	"alex-j-butler.com/tf2-booking/config"
+	"alex-j-butler.com/tf2-booking/messages"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/bwmarrin/discordgo"
+)
+
+// customIDSeparator separates the handler name from its arguments in a component's custom ID.
+const customIDSeparator = ":"
+
+// ComponentFunction is the function called when a message component (such as a button) is used.
+// The arguments are the values that were encoded into the component's custom ID.
+type ComponentFunction func(*discordgo.InteractionCreate, []string)
+
+type ComponentHandler struct {
+	function    ComponentFunction
+	permissions int64
+	group       string
+}
+
+// ComponentCommand is the registry of message component handlers.
+type ComponentCommand struct {
+	Handlers map[string]*ComponentHandler
+
+	middleware []Middleware
+}
+
+// NewComponentCommand creates a new instance of the message component system.
+func NewComponentCommand() *ComponentCommand {
+	return &ComponentCommand{
+		Handlers: make(map[string]*ComponentHandler),
+	}
+}
+
+// Use adds middleware to run for every component, in the order it was added.
+// The middleware is given the component's name, and the InteractionCommandInformation of the interaction.
+func (c *ComponentCommand) Use(middleware ...Middleware) {
+	c.middleware = append(c.middleware, middleware...)
+}
+
+// Add creates a new entry in the component handlers map.
+// Components are rate limited by their name, so a component sharing the name of a command shares its rate limit.
+func (c *ComponentCommand) Add(name string, function ComponentFunction) *ComponentHandler {
+	handler := &ComponentHandler{
+		function:    function,
+		permissions: -1,
+	}
+	c.Handlers[name] = handler
+
+	return handler
+}
+
+func (ch *ComponentHandler) Permissions(permissions int64) *ComponentHandler {
+	ch.permissions = permissions
+	return ch
+}
+
+// Group sets the command group of the component, allowing the permission groups in the
+// configuration to grant the component without the Discord permissions.
+func (ch *ComponentHandler) Group(group string) *ComponentHandler {
+	ch.group = group
+	return ch
+}
+
+// Handle dispatches a message component interaction to the appropriate handler,
+// using the name encoded in the component's custom ID.
+func (c *ComponentCommand) Handle(session *discordgo.Session, i *discordgo.InteractionCreate) {
+	split := strings.Split(i.MessageComponentData().CustomID, customIDSeparator)
+
+	handler, ok := c.Handlers[split[0]]
+	if !ok {
+		log.WithField("component", split[0]).Warn("Received unknown message component")
+		return
+	}
+
+	var roles []string
+	var permissions int64
+	if i.Member != nil {
+		roles = i.Member.Roles
+		permissions = i.Member.Permissions
+	}
+
+	dispatch := func(ctx *Context) {
+		if !allowed(PermissionGroups(config.Get().Permissions), handler.permissions, handler.group, permissions, InteractionUser(i).ID, roles) {
+			Respond(session, i, Text(InteractionUser(i).ID, "command.forbidden", nil), true)
+			return
+		}
+
+		// Interactions must always be responded to, so the notice is sent every time,
+		// but only the user can see it.
+		if result := rateLimit(InteractionUser(i).ID, roles, split[0]); !result.Allowed {
+			Respond(session, i, Text(InteractionUser(i).ID, "command.rate_limited", messages.Data{"RetryAfter": result.RetryAfter}), true)
+			return
+		}
+
+		handler.function(i, split[1:])
+	}
+
+	Chain(dispatch, c.middleware)(&Context{
+		CommandInformation: NewInteractionCommandInformation(session, i),
+		Command:            split[0],
+		Name:               split[0],
+		Session:            session,
+		Permissions:        permissions,
+		Roles:              roles,
+	})
+}
+
+// CustomID encodes a handler name & its arguments into a component custom ID.
+func CustomID(name string, args ...string) string {
+	return strings.Join(append([]string{name}, args...), customIDSeparator)
+}
+
+// UpdateMessage responds to a component interaction by editing the message the component is attached to.
+func UpdateMessage(session *discordgo.Session, i *discordgo.InteractionCreate, content string, embeds []*discordgo.MessageEmbed, components []discordgo.MessageComponent) error {
+	return session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    content,
+			Embeds:     embeds,
+			Components: components,
+		},
+	})
+}
+
+// DeferUpdate acknowledges a component interaction, for handlers that may take longer than
+// Discord allows before responding. Follow up messages are sent with Followup.
+func DeferUpdate(session *discordgo.Session, i *discordgo.InteractionCreate) error {
+	return session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	})
+}
+
+// Followup sends an additional message in response to an interaction.
+func Followup(session *discordgo.Session, i *discordgo.InteractionCreate, content string) error {
+	_, err := session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content: content,
+	})
+	return err
+}
@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"alex-j-butler.com/tf2-booking/config"
+	"alex-j-butler.com/tf2-booking/metrics"
+	"alex-j-butler.com/tf2-booking/util"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// responses records the bodies of the requests sent to Discord, ie. the interaction responses.
+type responses []string
+
+func (r *responses) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, _ := ioutil.ReadAll(req.Body)
+	*r = append(*r, string(body))
+
+	return &http.Response{StatusCode: http.StatusNoContent, Body: ioutil.NopCloser(strings.NewReader("")), Header: make(http.Header), Request: req}, nil
+}
+
+func testSession(t *testing.T) (*discordgo.Session, *responses) {
+	session, err := discordgo.New("Bot token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &responses{}
+	session.Client = &http.Client{Transport: r}
+
+	return session, r
+}
+
+func componentInteraction(userID string, customID string) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		ID:    "interaction",
+		Type:  discordgo.InteractionMessageComponent,
+		Token: "token",
+		User:  &discordgo.User{ID: userID},
+		Data:  discordgo.MessageComponentInteractionData{CustomID: customID},
+	}}
+}
+
+func TestComponentRateLimit(t *testing.T) {
+	conf := &config.Config{}
+	conf.RateLimits.Default = config.RateLimit{Requests: 1, Per: util.DurationUtil{Duration: time.Minute}}
+	config.Set(conf)
+
+	var args []string
+	c := NewComponentCommand()
+	c.Add("extend", func(i *discordgo.InteractionCreate, a []string) {
+		args = append(args, a...)
+	})
+
+	session, r := testSession(t)
+	c.Handle(session, componentInteraction("component-user", CustomID("extend", "server-1")))
+	c.Handle(session, componentInteraction("component-user", CustomID("extend", "server-1")))
+
+	if len(args) != 1 || args[0] != "server-1" {
+		t.Fatalf("Expected the second press to be rate limited, got %q", args)
+	}
+	if len(*r) != 1 || !strings.Contains((*r)[0], "Slow down!") {
+		t.Errorf("Expected the user to be told to slow down, got %q", *r)
+	}
+}
+
+func TestComponentPermissions(t *testing.T) {
+	config.Set(&config.Config{})
+
+	called := false
+	c := NewComponentCommand()
+	c.Add("restart", func(i *discordgo.InteractionCreate, a []string) {
+		called = true
+	}).Permissions(discordgo.PermissionAdministrator)
+
+	session, r := testSession(t)
+	c.Handle(session, componentInteraction("component-user", CustomID("restart")))
+
+	if called {
+		t.Fatal("Expected the component to require the permissions")
+	}
+	if len(*r) != 1 || !strings.Contains((*r)[0], "permission") {
+		t.Errorf("Expected the user to be refused, got %q", *r)
+	}
+}
+
+func TestComponentRecover(t *testing.T) {
+	config.Set(&config.Config{})
+
+	c := NewComponentCommand()
+	c.Use(Recover(nil))
+	c.Add("unbook", func(i *discordgo.InteractionCreate, a []string) {
+		panic("unbook failed")
+	})
+
+	session, r := testSession(t)
+	c.Handle(session, componentInteraction("component-user", CustomID("unbook")))
+
+	if len(*r) != 1 || !strings.Contains((*r)[0], `"flags":64`) {
+		t.Errorf("Expected the panic to be recovered with a private reply, got %q", *r)
+	}
+}
+
+func TestComponentMetrics(t *testing.T) {
+	config.Set(&config.Config{})
+
+	c := NewComponentCommand()
+	c.Use(Metrics)
+	c.Add("password", func(i *discordgo.InteractionCreate, a []string) {})
+
+	before := metrics.CommandInvocations.Value("password", "discord")
+
+	session, _ := testSession(t)
+	c.Handle(session, componentInteraction("component-user", CustomID("password")))
+
+	if invocations := metrics.CommandInvocations.Value("password", "discord") - before; invocations != 1 {
+		t.Errorf("Expected the button to be counted with its command, got %v invocations", invocations)
+	}
+}
@@ -0,0 +1,83 @@
+package commands
+
+import (
+	"alex-j-butler.com/tf2-booking/commands/parser"
+	"alex-j-butler.com/tf2-booking/logging"
+	"alex-j-butler.com/tf2-booking/servers"
+	"alex-j-butler.com/tf2-booking/util"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/bwmarrin/discordgo"
+)
+
+// Context holds a single invocation of a command, passed through the middleware to the command handler.
+// Command handlers that only use the CommandInformation can be registered for both Discord & ingame commands.
+type Context struct {
+	CommandInformation
+
+	// Command is the command that was matched, which may be an alias of the handler.
+	Command string
+
+	// Name is the name of the command's handler, the same for each of its aliases.
+	Name string
+
+	// Args are the parsed arguments, only available to the command handler itself.
+	Args *parser.Args
+
+	// Server is the caller's booked server, when loaded by middleware requiring a booking.
+	Server *servers.Server
+
+	// Discord commands only.
+	Session     *discordgo.Session
+	Message     *discordgo.MessageCreate
+	User        *util.PatchUser
+	Handler     *CommandHandler
+	Permissions int64
+	Roles       []string
+
+	rest        []string
+	tokenizeErr error
+}
+
+// Reply sends a message to where the command was sent, addressed to the caller.
+func (ctx *Context) Reply(message string) {
+	ctx.ReplyChannel("%s", message)
+}
+
+// Replyf formats the message and replies with it.
+func (ctx *Context) Replyf(format string, a ...interface{}) {
+	ctx.ReplyChannel(format, a...)
+}
+
+// DM sends a message privately to the caller.
+func (ctx *Context) DM(message string) {
+	ctx.ReplyUser("%s", message)
+}
+
+// Ingame returns whether the command was sent in the ingame chat of a server.
+func (ctx *Context) Ingame() bool {
+	_, ok := ctx.CommandInformation.(*TF2CommandInformation)
+	return ok
+}
+
+// Log returns a log entry with the command & caller, and the fields of the server the command is about.
+func (ctx *Context) Log() *log.Entry {
+	entry := log.NewEntry(log.StandardLogger())
+	if ctx.Server != nil {
+		entry = ctx.Server.Log()
+	} else if info, ok := ctx.CommandInformation.(*TF2CommandInformation); ok {
+		entry = info.GetServer().Log()
+	}
+
+	return entry.WithFields(log.Fields{"command": ctx.Command, logging.UserIDField: ctx.GetUserID(), "username": ctx.GetUsername()})
+}
+
+// DMChannel returns the ID of the caller's private message channel, for Discord commands.
+func (ctx *Context) DMChannel() (string, error) {
+	channel, err := ctx.Session.UserChannelCreate(ctx.Message.Author.ID)
+	if err != nil {
+		return "", err
+	}
+
+	return channel.ID, nil
+}
@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"fmt"
+	"testing"
+
+	"alex-j-butler.com/tf2-booking/servers"
+)
+
+type fakeInformation struct {
+	channel []string
+	user    []string
+}
+
+func (f *fakeInformation) ReplyChannel(formatStr string, args ...interface{}) {
+	f.channel = append(f.channel, fmt.Sprintf(formatStr, args...))
+}
+
+func (f *fakeInformation) ReplyUser(formatStr string, args ...interface{}) {
+	f.user = append(f.user, fmt.Sprintf(formatStr, args...))
+}
+
+func (f *fakeInformation) GetChannelID() string                 { return "channel" }
+func (f *fakeInformation) GetUserID() string                    { return "user" }
+func (f *fakeInformation) GetUsername() string                  { return "username" }
+func (f *fakeInformation) GetBooking() (*servers.Server, error) { return nil, nil }
+
+func TestContextReplies(t *testing.T) {
+	info := &fakeInformation{}
+	ctx := &Context{CommandInformation: info}
+
+	ctx.Reply("100% done")
+	ctx.Replyf("%d left", 5)
+	ctx.DM("secret")
+
+	if len(info.channel) != 2 || info.channel[0] != "100% done" || info.channel[1] != "5 left" {
+		t.Fatalf("Expected the channel replies to be sent as is, got %q", info.channel)
+	}
+
+	if len(info.user) != 1 || info.user[0] != "secret" {
+		t.Fatalf("Expected the private reply to be sent to the user, got %q", info.user)
+	}
+
+	if ctx.Ingame() {
+		t.Fatalf("Expected a non-TF2 command not to be ingame")
+	}
+
+	ctx.CommandInformation = NewTF2CommandInformation(&servers.Server{Name: "Server 1"}, "[U:1:1]", "player")
+	if !ctx.Ingame() {
+		t.Fatalf("Expected a TF2 command to be ingame")
+	}
+}
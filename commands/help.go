@@ -0,0 +1,127 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"alex-j-butler.com/tf2-booking/commands/parser"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// DefaultCategory is the category commands without one are listed under in the help.
+const DefaultCategory = "General"
+
+// usage returns the usage of the handler when called with the specified command,
+// including the command prefix.
+func (c *Command) usage(command string, handler *CommandHandler) string {
+	if handler.usage != "" {
+		return fmt.Sprintf("%s%s %s", c.Prefix, command, handler.usage)
+	}
+
+	return c.Prefix + parser.Usage(command, handler.params)
+}
+
+// available returns whether the caller can run the command in the channel the message was sent in.
+func (c *Command) available(session *discordgo.Session, m *discordgo.MessageCreate, handler *CommandHandler, permissions int64, roles []string) bool {
+	if !handler.respondToDM {
+		channel, err := session.State.Channel(m.ChannelID)
+		if err == nil && channel.Type == discordgo.ChannelTypeDM {
+			return false
+		}
+	}
+
+	return c.Allowed(handler, permissions, m.Author.ID, roles)
+}
+
+// Available returns the handlers the caller can run in the channel the message was sent in,
+// in the order they were registered.
+func (c *Command) Available(session *discordgo.Session, m *discordgo.MessageCreate, permissions int64) []*CommandHandler {
+	roles := CallerRoles(session, m)
+
+	var handlers []*CommandHandler
+	for _, handler := range c.handlers {
+		if c.available(session, m, handler, permissions, roles) {
+			handlers = append(handlers, handler)
+		}
+	}
+
+	return handlers
+}
+
+// Lookup finds the handler for the command, ignoring any arguments after it.
+func (c *Command) Lookup(command string) (*CommandHandler, bool) {
+	command = strings.TrimPrefix(strings.TrimSpace(command), c.Prefix)
+
+	value, _, _, ok := c.router.Match(strings.Fields(command))
+	if !ok {
+		return nil, false
+	}
+
+	return value.(*CommandHandler), true
+}
+
+// HelpList returns a listing of the handlers, grouped by category.
+// Categories are listed in the order their first command was registered.
+func (c *Command) HelpList(handlers []*CommandHandler) string {
+	var categories []string
+	byCategory := make(map[string][]*CommandHandler)
+
+	for _, handler := range handlers {
+		category := handler.category
+		if category == "" {
+			category = DefaultCategory
+		}
+
+		if _, ok := byCategory[category]; !ok {
+			categories = append(categories, category)
+		}
+		byCategory[category] = append(byCategory[category], handler)
+	}
+
+	var buf bytes.Buffer
+	for i, category := range categories {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		fmt.Fprintf(&buf, "%s:\n", category)
+
+		w := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+		for _, handler := range byCategory[category] {
+			names := c.Prefix + handler.name
+			if len(handler.aliases) > 0 {
+				names = fmt.Sprintf("%s (%s)", names, c.Prefix+strings.Join(handler.aliases, ", "+c.Prefix))
+			}
+			fmt.Fprintf(w, "  %s\t- %s\n", names, handler.description)
+		}
+		w.Flush()
+	}
+
+	return buf.String()
+}
+
+// HelpCommand returns the usage, description & aliases of the handler.
+func (c *Command) HelpCommand(handler *CommandHandler) string {
+	help := fmt.Sprintf("`%s`", c.usage(handler.name, handler))
+
+	if handler.description != "" {
+		help = fmt.Sprintf("%s\n%s", help, handler.description)
+	}
+
+	if len(handler.aliases) > 0 {
+		aliases := make([]string, len(handler.aliases))
+		for i, alias := range handler.aliases {
+			aliases[i] = fmt.Sprintf("`%s`", c.Prefix+alias)
+		}
+		help = fmt.Sprintf("%s\nAliases: %s", help, strings.Join(aliases, ", "))
+	}
+
+	return help
+}
+
+// CanRun returns whether the caller can run the handler in the channel the message was sent in.
+func (c *Command) CanRun(session *discordgo.Session, m *discordgo.MessageCreate, handler *CommandHandler, permissions int64) bool {
+	return c.available(session, m, handler, permissions, CallerRoles(session, m))
+}
@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"alex-j-butler.com/tf2-booking/commands/parser"
+)
+
+func noop(*Context) {}
+
+func testCommand() *Command {
+	c := New("")
+	c.Add(NewCommand(noop).Description("Book a new server").Category("Booking"), "book", "/book")
+	c.Add(NewCommand(noop).Description("Display the help"), "help")
+	c.Add(
+		NewCommand(noop).
+			Params(parser.Param{Name: "duration", Type: parser.Duration, Optional: true}).
+			Description("Extend your booking").
+			Category("Booking"),
+		"extend",
+	)
+	return c
+}
+
+func TestHelpListGroupsByCategory(t *testing.T) {
+	c := testCommand()
+
+	expected := "Booking:\n" +
+		"  book (/book)  - Book a new server\n" +
+		"  extend        - Extend your booking\n" +
+		"\n" +
+		"General:\n" +
+		"  help  - Display the help\n"
+
+	if help := c.HelpList(c.handlers); help != expected {
+		t.Fatalf("Expected help list:\n%s\ngot:\n%s", expected, help)
+	}
+}
+
+func TestHelpCommand(t *testing.T) {
+	c := testCommand()
+
+	handler, ok := c.Lookup("/BOOK")
+	if !ok {
+		t.Fatalf("Expected /BOOK to find the book command")
+	}
+
+	expected := "`book`\nBook a new server\nAliases: `/book`"
+	if help := c.HelpCommand(handler); help != expected {
+		t.Fatalf("Expected %q, got %q", expected, help)
+	}
+
+	handler, _ = c.Lookup("extend 30m")
+	if help := c.HelpCommand(handler); !strings.HasPrefix(help, "`extend [duration]`") {
+		t.Fatalf("Expected the generated usage, got %q", help)
+	}
+}
+
+func TestRemoveUnlistsHandler(t *testing.T) {
+	c := testCommand()
+
+	c.Remove("/book")
+	if len(c.handlers) != 3 || len(c.Handlers["book"].aliases) != 0 {
+		t.Fatalf("Expected removing an alias to keep the handler listed without it")
+	}
+
+	c.Remove("book")
+	if len(c.handlers) != 2 {
+		t.Fatalf("Expected removing every command to unlist the handler, got %d handlers", len(c.handlers))
+	}
+}
@@ -1,35 +1,69 @@
 package ingame
 
 import (
-	"reflect"
+	"fmt"
 	"strings"
 
-	"alex-j-butler.com/tf2-booking/commands/ingame/loghandler"
-	"alex-j-butler.com/tf2-booking/servers"
+	"alex-j-butler.com/tf2-booking/commands"
+	"alex-j-butler.com/tf2-booking/commands/parser"
+	"alex-j-butler.com/tf2-booking/config"
+	"alex-j-butler.com/tf2-booking/messages"
 )
 
-type CommandInfo struct {
-	loghandler.SayEvent
-	Server *servers.Server
-}
-
-type CommandFunction func(CommandInfo, string, []string)
-
+// CommandHandler is an ingame command, using the same command functions as the Discord commands.
 type CommandHandler struct {
-	function CommandFunction
+	name        string
+	aliases     []string
+	function    commands.CommandFunction
+	params      []parser.Param
+	description string
+	group       string
+	middleware  []commands.Middleware
 }
 
 type Command struct {
 	Prefix   string
 	Handlers map[string]*CommandHandler
+
+	router     parser.Router
+	middleware []commands.Middleware
+
+	// Handlers in the order they were registered, for listing in the help.
+	handlers []*CommandHandler
 }
 
-func NewCommand(function CommandFunction) *CommandHandler {
+func NewCommand(function commands.CommandFunction) *CommandHandler {
 	return &CommandHandler{
 		function: function,
 	}
 }
 
+// Params declares the parameters of the command, which the arguments are parsed into.
+// The usage is sent to the server if the arguments don't match.
+func (ch *CommandHandler) Params(params ...parser.Param) *CommandHandler {
+	ch.params = params
+	return ch
+}
+
+// Description sets the short description of the command shown in the help.
+func (ch *CommandHandler) Description(description string) *CommandHandler {
+	ch.description = description
+	return ch
+}
+
+// Group restricts the command to the callers granted the command group by a permission group,
+// listing their SteamID3, eg. "[U:1:22202]", in its users.
+func (ch *CommandHandler) Group(group string) *CommandHandler {
+	ch.group = group
+	return ch
+}
+
+// Use adds middleware to run for this command only, after the middleware of the Command.
+func (ch *CommandHandler) Use(middleware ...commands.Middleware) *CommandHandler {
+	ch.middleware = append(ch.middleware, middleware...)
+	return ch
+}
+
 // New creates a new instance of the Command system
 // with the specified prefix.
 func New(prefix string) *Command {
@@ -39,41 +73,114 @@ func New(prefix string) *Command {
 	}
 }
 
+// Use adds middleware to run for every command, in the order it was added.
+func (c *Command) Use(middleware ...commands.Middleware) {
+	c.middleware = append(c.middleware, middleware...)
+}
+
 // Add creates a new entry in the command handlers map.
 // First argument accepts a command handler implementing the type `CommandHandler`,
 // Second argument accepts a variable amount of strings specifying the commands to register.
-func (c *Command) Add(handler *CommandHandler, commands ...string) {
-	for _, command := range commands {
+// The first command is used as the name of the handler, and the rest are listed as its aliases in the help.
+func (c *Command) Add(handler *CommandHandler, names ...string) {
+	if handler.name == "" && len(names) > 0 {
+		handler.name = names[0]
+		c.handlers = append(c.handlers, handler)
+	}
+
+	for _, command := range names {
 		c.Handlers[command] = handler
+		c.router.Add(command, handler)
+
+		if command != handler.name {
+			handler.aliases = append(handler.aliases, command)
+		}
 	}
 }
 
 // Remove deletes entries from the command handlers map.
-func (c *Command) Remove(commands ...string) {
-	for _, command := range commands {
+func (c *Command) Remove(names ...string) {
+	for _, command := range names {
 		delete(c.Handlers, command)
+		c.router.Remove(command)
 	}
 }
 
 // Handle the incoming commands and dispatches them to the appropriate
 // command handler, after parsing them.
-func (c *Command) Handle(info CommandInfo, command string, permissions int) {
-	for str, handler := range c.Handlers {
-		if !strings.HasPrefix(command, c.Prefix) && c.Prefix != "" {
-			continue
-		}
+func (c *Command) Handle(info *commands.TF2CommandInformation, command string) {
+	if !strings.HasPrefix(command, c.Prefix) {
+		return
+	}
 
-		handlerSplit := strings.Split(str, " ")
-		commandSplit := strings.Split(command[len(c.Prefix):], " ")
+	// An unterminated quote is only reported if the message is a command.
+	tokens, tokenizeErr := parser.Tokenize(command[len(c.Prefix):])
+	if tokenizeErr != nil {
+		tokens = strings.Fields(command[len(c.Prefix):])
+	}
+
+	value, name, rest, ok := c.router.Match(tokens)
+	if !ok {
+		return
+	}
+	handler := value.(*CommandHandler)
 
-		if len(commandSplit) < len(handlerSplit) {
-			continue
+	dispatch := func(ctx *commands.Context) {
+		if handler.group != "" && !commands.PermissionGroups(config.Get().Permissions).Allows(ctx.GetUserID(), nil, handler.group) {
+			ctx.Reply(commands.Text(ctx.GetUserID(), "command.forbidden", nil))
+			return
 		}
 
-		if reflect.DeepEqual(handlerSplit, commandSplit[:len(handlerSplit)]) {
-			handler.function(info, strings.Join(handlerSplit, " "), commandSplit[len(handlerSplit):])
+		args, err := parser.Parse(handler.params, rest)
+		if tokenizeErr != nil {
+			err = tokenizeErr
+		}
+		if err != nil {
+			// Only the command's registered name is replied with, rather than what the player typed.
+			ctx.Reply(commands.Text(ctx.GetUserID(), "ingame.usage", messages.Data{"Usage": c.Prefix + parser.Usage(handler.name, handler.params)}))
+			return
+		}
+
+		ctx.Args = args
+		handler.function(ctx)
+	}
+
+	function := commands.Chain(dispatch, handler.middleware)
+	function = commands.Chain(function, c.middleware)
+	function(&commands.Context{
+		CommandInformation: info,
+		Command:            name,
+		Name:               handler.name,
+	})
+}
 
-			break
+// Help replies with the help, listing the commands, or the details of a single command if one is given.
+func (c *Command) Help(ctx *commands.Context, command string) {
+	if command == "" {
+		names := make([]string, len(c.handlers))
+		for i, handler := range c.handlers {
+			names[i] = c.Prefix + handler.name
 		}
+
+		ctx.Reply(commands.Text(ctx.GetUserID(), "ingame.help", messages.Data{"Commands": strings.Join(names, ", "), "Prefix": c.Prefix}))
+		return
+	}
+
+	value, name, _, ok := c.router.Match(strings.Fields(strings.TrimPrefix(command, c.Prefix)))
+	if !ok {
+		// The unknown command isn't echoed back, as replies are sent through the server's console.
+		ctx.Reply(commands.Text(ctx.GetUserID(), "ingame.unknown", messages.Data{"Prefix": c.Prefix}))
+		return
 	}
+	handler := value.(*CommandHandler)
+
+	help := c.Prefix + parser.Usage(name, handler.params)
+	if handler.description != "" {
+		help = fmt.Sprintf("%s - %s", help, handler.description)
+	}
+	if len(handler.aliases) > 0 {
+		help = fmt.Sprintf("%s (aliases: %s)", help, c.Prefix+strings.Join(handler.aliases, ", "+c.Prefix))
+	}
+
+	ctx.Reply(help)
 }
@@ -0,0 +1,66 @@
+package ingame
+
+import (
+	"reflect"
+	"testing"
+
+	"alex-j-butler.com/tf2-booking/commands"
+	"alex-j-butler.com/tf2-booking/commands/parser"
+	"alex-j-butler.com/tf2-booking/config"
+	"alex-j-butler.com/tf2-booking/servers"
+)
+
+// chatRunner records the commands sent to the server, ie. the replies.
+type chatRunner struct {
+	servers.ServerRunner
+
+	sent []string
+}
+
+func (r *chatRunner) SendCommand(server *servers.Server, command string) error {
+	r.sent = append(r.sent, command)
+	return nil
+}
+
+func TestCommandGroup(t *testing.T) {
+	conf := &config.Config{}
+	conf.Permissions = []config.PermissionGroup{{Name: "Players", Users: []string{"[U:1:1]"}, Commands: []string{"ingame"}}}
+	config.Set(conf)
+
+	var callers []string
+	c := New("!")
+	c.Add(NewCommand(func(ctx *commands.Context) {
+		callers = append(callers, ctx.GetUserID())
+	}).Group("ingame"), "unbook")
+
+	runner := &chatRunner{}
+	server := &servers.Server{Name: "Server 1", Runner: runner}
+
+	c.Handle(commands.NewTF2CommandInformation(server, "[U:1:1]", "booker"), "!unbook")
+	c.Handle(commands.NewTF2CommandInformation(server, "[U:1:3]", "player"), "!unbook")
+
+	if !reflect.DeepEqual(callers, []string{"[U:1:1]"}) {
+		t.Errorf("Expected only the granted player to run the command, got %q", callers)
+	}
+	if !reflect.DeepEqual(runner.sent, []string{`say "@player: You don't have permission for that command."`}) {
+		t.Errorf("Expected the other player to be refused, got %q", runner.sent)
+	}
+}
+
+func TestHelpUnknownCommand(t *testing.T) {
+	config.Set(&config.Config{})
+
+	c := New("!")
+	c.Add(NewCommand(func(ctx *commands.Context) {
+		c.Help(ctx, ctx.Args.String("command"))
+	}).Params(parser.Param{Name: "command", Type: parser.Text, Optional: true}), "help")
+
+	runner := &chatRunner{}
+	server := &servers.Server{Name: "Server 1", Runner: runner}
+
+	c.Handle(commands.NewTF2CommandInformation(server, "[U:1:1]", "player"), "!help x;quit")
+
+	if !reflect.DeepEqual(runner.sent, []string{`say "@player: Unknown command, type !help for a list of commands."`}) {
+		t.Errorf("Expected the unknown command not to be echoed, got %q", runner.sent)
+	}
+}
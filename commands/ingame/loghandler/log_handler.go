@@ -3,18 +3,21 @@ package loghandler
 import (
 	"errors"
 	"fmt"
-	"log"
 	"net"
 	"reflect"
 	"regexp"
 	"sync"
 
+	"alex-j-butler.com/tf2-booking/metrics"
 	"alex-j-butler.com/tf2-booking/servers"
+
+	log "github.com/Sirupsen/logrus"
 )
 
 type LogHandler struct {
 	Address    string
 	Port       int
+	Pool       servers.ServerPool
 	conn       *net.UDPConn
 	handlersMu sync.RWMutex
 	handlers   map[interface{}][]reflect.Value
@@ -32,10 +35,12 @@ type SayEvent struct {
 	Message string
 }
 
-func Dial(address string, port int) (*LogHandler, error) {
+// Dial listens for log lines on the address & port, from the servers in the pool.
+func Dial(address string, port int, pool servers.ServerPool) (*LogHandler, error) {
 	lh := &LogHandler{
 		Address: address,
 		Port:    port,
+		Pool:    pool,
 	}
 
 	serverAddr, err := net.ResolveUDPAddr("udp", fmt.Sprintf("%s:%d", lh.Address, lh.Port))
@@ -53,6 +58,15 @@ func Dial(address string, port int) (*LogHandler, error) {
 	return lh, nil
 }
 
+// Bound returns an error if the log handler isn't listening for log lines.
+func (lh *LogHandler) Bound() error {
+	if lh == nil || lh.conn == nil {
+		return errors.New("log handler socket isn't bound")
+	}
+
+	return nil
+}
+
 func (lh *LogHandler) handleConn() {
 	buf := make([]byte, 1024)
 
@@ -60,24 +74,30 @@ func (lh *LogHandler) handleConn() {
 		n, addr, err := lh.conn.ReadFromUDP(buf)
 
 		if err != nil {
-			log.Println("LogHandler error:", err)
+			log.WithError(err).Error("LogHandler read failed")
 		}
 
+		metrics.LogPacketsReceived.Inc()
+
 		data := string(buf[:n])
 
 		matches, err := lh.ParseLine(data)
 		if err != nil {
+			metrics.LogPacketsUnrecognised.Inc("unparsed")
 			continue
 		}
 
 		// Find a server with the same IP and Port.
-		server, err := servers.GetServerByAddress(servers.Servers, addr.String())
+		server, err := lh.Pool.GetServerByAddress(addr.String())
 		if err != nil {
 			// Ignore this log line, we don't recognise the server.
-			log.Println("Unrecognised server:", err)
+			log.WithError(err).WithField("address", addr.String()).Debug("Ignoring log line from unrecognised server")
+			metrics.LogPacketsUnrecognised.Inc("unknown_server")
 			continue
 		}
 
+		server.Log().WithField("line", data).Debug("Received log line")
+
 		// Notify the callback with the appropriate parameters.
 		// matches[0] = Username
 		// matches[1] = UserID
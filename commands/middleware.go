@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"alex-j-butler.com/tf2-booking/messages"
+	"alex-j-butler.com/tf2-booking/metrics"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Middleware wraps a command function, running before and/or after it.
+// Middleware stops the command by not calling the next function.
+type Middleware func(next CommandFunction) CommandFunction
+
+// Chain wraps the function in the middleware, so the first middleware runs first.
+func Chain(function CommandFunction, middleware []Middleware) CommandFunction {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		function = middleware[i](function)
+	}
+
+	return function
+}
+
+// Logging logs each command that is run and how long it took.
+func Logging(next CommandFunction) CommandFunction {
+	return func(ctx *Context) {
+		start := time.Now()
+
+		next(ctx)
+
+		ctx.Log().WithFields(log.Fields{"channel_id": ctx.GetChannelID(), "duration": time.Since(start).String()}).Info("Command handled")
+	}
+}
+
+// Metrics records the invocations of each command and how long they took,
+// by the name of the command's handler, so its aliases & slash command are counted together.
+func Metrics(next CommandFunction) CommandFunction {
+	return func(ctx *Context) {
+		start := time.Now()
+
+		source := "discord"
+		if ctx.Ingame() {
+			source = "ingame"
+		}
+
+		defer func() {
+			metrics.CommandInvocations.Inc(ctx.Name, source)
+			metrics.CommandLatency.Observe(time.Since(start).Seconds(), ctx.Name)
+		}()
+
+		next(ctx)
+	}
+}
+
+// Recover recovers from panics in the command, replying with an error and calling notify
+// so that the admins can be told about it.
+func Recover(notify func(ctx *Context, recovered interface{})) Middleware {
+	return func(next CommandFunction) CommandFunction {
+		return func(ctx *Context) {
+			defer func() {
+				if r := recover(); r != nil {
+					ctx.Log().WithFields(log.Fields{"panic": fmt.Sprint(r), "stack": string(debug.Stack())}).Error("Command panicked")
+
+					ctx.Reply(messages.Render(messages.Default.DefaultLocale, "error", nil))
+
+					if notify != nil {
+						notify(ctx, r)
+					}
+				}
+			}()
+
+			next(ctx)
+		}
+	}
+}
+
+// CheckPermissions is Discord command middleware that stops the command if the caller doesn't have the Discord permissions
+// or a permission group granting the command.
+func CheckPermissions(next CommandFunction) CommandFunction {
+	return func(ctx *Context) {
+		if !handlerAllowed(ctx.Handler, ctx.Permissions, ctx.GetUserID(), ctx.Roles) {
+			ctx.Reply(Text(ctx.GetUserID(), "command.forbidden", nil))
+			return
+		}
+
+		next(ctx)
+	}
+}
+
+// CheckRateLimit is command middleware that stops the command if the caller has exceeded its rate limit.
+// Ingame callers are limited by their SteamID.
+func CheckRateLimit(next CommandFunction) CommandFunction {
+	return func(ctx *Context) {
+		if result := rateLimit(ctx.GetUserID(), ctx.Roles, ctx.Name); !result.Allowed {
+			if result.Notify {
+				ctx.Reply(Text(ctx.GetUserID(), "command.rate_limited", messages.Data{"RetryAfter": result.RetryAfter}))
+			}
+			return
+		}
+
+		next(ctx)
+	}
+}
@@ -0,0 +1,84 @@
+package commands
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"alex-j-butler.com/tf2-booking/config"
+	"alex-j-butler.com/tf2-booking/metrics"
+	"alex-j-butler.com/tf2-booking/util"
+)
+
+func TestChainOrder(t *testing.T) {
+	var calls []string
+
+	record := func(name string) Middleware {
+		return func(next CommandFunction) CommandFunction {
+			return func(ctx *Context) {
+				calls = append(calls, name)
+				next(ctx)
+			}
+		}
+	}
+
+	function := Chain(func(ctx *Context) {
+		calls = append(calls, "handler")
+	}, []Middleware{record("first"), record("second")})
+	function(&Context{})
+
+	expected := []string{"first", "second", "handler"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Fatalf("Expected calls %v, got %v", expected, calls)
+	}
+}
+
+func TestChainStops(t *testing.T) {
+	stop := func(next CommandFunction) CommandFunction {
+		return func(ctx *Context) {}
+	}
+
+	called := false
+	Chain(func(ctx *Context) { called = true }, []Middleware{stop})(&Context{})
+
+	if called {
+		t.Fatalf("Expected the handler not to be called when middleware stops the command")
+	}
+}
+
+func TestMetricsByHandlerName(t *testing.T) {
+	before := metrics.CommandInvocations.Value("metrics-test", "discord")
+
+	function := Chain(func(ctx *Context) {}, []Middleware{Metrics})
+	function(&Context{CommandInformation: &fakeInformation{}, Command: "metrics-test", Name: "metrics-test"})
+	function(&Context{CommandInformation: &fakeInformation{}, Command: "metrics-alias", Name: "metrics-test"})
+
+	if invocations := metrics.CommandInvocations.Value("metrics-test", "discord") - before; invocations != 2 {
+		t.Errorf("Expected the alias to be counted with its command, got %v invocations", invocations)
+	}
+	if latency := metrics.CommandLatency.Count("metrics-test"); latency < 2 {
+		t.Errorf("Expected the latency of both invocations, got %d", latency)
+	}
+}
+
+func TestCheckRateLimitIngame(t *testing.T) {
+	conf := &config.Config{}
+	conf.RateLimits.Default = config.RateLimit{Requests: 1, Per: util.DurationUtil{Duration: time.Minute}}
+	config.Set(conf)
+
+	calls := 0
+	function := Chain(func(ctx *Context) { calls++ }, []Middleware{CheckRateLimit})
+
+	// Ingame commands have no Discord handler, they're limited by the handler's name for each alias.
+	info := &fakeInformation{}
+	function(&Context{CommandInformation: info, Command: "unbook", Name: "unbook"})
+	function(&Context{CommandInformation: info, Command: "return", Name: "unbook"})
+
+	if calls != 1 {
+		t.Fatalf("Expected the second use to be rate limited, got %d calls", calls)
+	}
+	if len(info.channel) != 1 || !strings.HasPrefix(info.channel[0], "Slow down!") {
+		t.Errorf("Expected the caller to be told to slow down, got %q", info.channel)
+	}
+}
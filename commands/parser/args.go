@@ -0,0 +1,256 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParamType is the type of value a command parameter accepts.
+type ParamType int
+
+const (
+	// String accepts a single token.
+	String ParamType = iota
+	// Integer accepts a whole number.
+	Integer
+	// Duration accepts a Go duration such as "1h30m", or a whole number of minutes.
+	Duration
+	// User accepts a Discord user mention or ID.
+	User
+	// Channel accepts a Discord channel mention or ID.
+	Channel
+	// Text accepts the rest of the tokens joined by spaces. It must be the last parameter.
+	Text
+)
+
+var (
+	userMention    = regexp.MustCompile(`^<@!?(\d+)>$`)
+	channelMention = regexp.MustCompile(`^<#(\d+)>$`)
+	snowflake      = regexp.MustCompile(`^\d+$`)
+)
+
+// Param declares a parameter of a command.
+type Param struct {
+	Name     string
+	Type     ParamType
+	Optional bool
+}
+
+// UsageError is returned when the arguments don't match the command's parameters.
+type UsageError struct {
+	Param  string
+	Reason string
+}
+
+func (e *UsageError) Error() string {
+	if e.Param == "" {
+		return e.Reason
+	}
+	return fmt.Sprintf("%s: %s", e.Param, e.Reason)
+}
+
+// Args holds the arguments of a parsed command.
+type Args struct {
+	// Raw is every token that followed the command.
+	Raw []string
+
+	values map[string]interface{}
+}
+
+// Has returns whether a value was given for the parameter.
+func (a *Args) Has(name string) bool {
+	_, ok := a.values[name]
+	return ok
+}
+
+// String returns the value of a String, Text, User or Channel parameter.
+func (a *Args) String(name string) string {
+	value, _ := a.values[name].(string)
+	return value
+}
+
+// Int returns the value of an Integer parameter.
+func (a *Args) Int(name string) int {
+	value, _ := a.values[name].(int)
+	return value
+}
+
+// Duration returns the value of a Duration parameter.
+func (a *Args) Duration(name string) time.Duration {
+	value, _ := a.values[name].(time.Duration)
+	return value
+}
+
+// User returns the user ID of a User parameter.
+func (a *Args) User(name string) string {
+	return a.String(name)
+}
+
+// Channel returns the channel ID of a Channel parameter.
+func (a *Args) Channel(name string) string {
+	return a.String(name)
+}
+
+// convert converts a token into the value for the parameter type.
+func convert(param Param, token string) (interface{}, error) {
+	switch param.Type {
+	case Integer:
+		value, err := strconv.Atoi(token)
+		if err != nil {
+			return nil, &UsageError{Param: param.Name, Reason: "must be a whole number"}
+		}
+		return value, nil
+	case Duration:
+		if minutes, err := strconv.Atoi(token); err == nil {
+			return time.Duration(minutes) * time.Minute, nil
+		}
+		value, err := time.ParseDuration(token)
+		if err != nil {
+			return nil, &UsageError{Param: param.Name, Reason: "must be a duration, such as 30m or 1h30m"}
+		}
+		return value, nil
+	case User:
+		if matches := userMention.FindStringSubmatch(token); matches != nil {
+			return matches[1], nil
+		}
+		if snowflake.MatchString(token) {
+			return token, nil
+		}
+		return nil, &UsageError{Param: param.Name, Reason: "must be a user mention"}
+	case Channel:
+		if matches := channelMention.FindStringSubmatch(token); matches != nil {
+			return matches[1], nil
+		}
+		if snowflake.MatchString(token) {
+			return token, nil
+		}
+		return nil, &UsageError{Param: param.Name, Reason: "must be a channel mention"}
+	default:
+		return token, nil
+	}
+}
+
+// Parse matches the tokens against the parameters.
+// Tokens in the form 'name:value' set the named parameter, the remaining tokens fill the
+// other parameters in order. Once a Text parameter is reached it takes the rest of the tokens
+// as they were typed, and a named Text parameter takes its value & the rest of the tokens.
+// Commands without parameters ignore any tokens.
+func Parse(params []Param, tokens []string) (*Args, error) {
+	args := &Args{
+		Raw:    tokens,
+		values: make(map[string]interface{}),
+	}
+
+	if len(params) == 0 {
+		return args, nil
+	}
+
+	byName := make(map[string]Param, len(params))
+	for _, param := range params {
+		byName[strings.ToLower(param.Name)] = param
+	}
+
+	// Extract the named options.
+	positional := make([]string, 0, len(tokens))
+	for i, token := range tokens {
+		split := strings.SplitN(token, ":", 2)
+		param, named := byName[strings.ToLower(split[0])]
+		named = named && len(split) == 2
+
+		// A named Text parameter takes the rest of the tokens.
+		if named && param.Type == Text {
+			args.values[param.Name] = strings.Join(append([]string{split[1]}, tokens[i+1:]...), " ")
+			break
+		}
+
+		if textReached(params, args, len(positional)) {
+			positional = append(positional, tokens[i:]...)
+			break
+		}
+
+		if named {
+			value, err := convert(param, split[1])
+			if err != nil {
+				return nil, err
+			}
+			args.values[param.Name] = value
+			continue
+		}
+
+		positional = append(positional, token)
+	}
+
+	// Fill the remaining parameters in order.
+	for _, param := range params {
+		if args.Has(param.Name) {
+			continue
+		}
+
+		if len(positional) == 0 {
+			if !param.Optional {
+				return nil, &UsageError{Param: param.Name, Reason: "is required"}
+			}
+			continue
+		}
+
+		if param.Type == Text {
+			args.values[param.Name] = strings.Join(positional, " ")
+			positional = nil
+			continue
+		}
+
+		value, err := convert(param, positional[0])
+		if err != nil {
+			return nil, err
+		}
+		args.values[param.Name] = value
+		positional = positional[1:]
+	}
+
+	if len(positional) > 0 {
+		return nil, &UsageError{Reason: "too many arguments"}
+	}
+
+	return args, nil
+}
+
+// textReached returns whether the positional tokens so far have filled the parameters before
+// the Text parameter, so the next token is part of the text.
+func textReached(params []Param, args *Args, positional int) bool {
+	before := 0
+	for _, param := range params {
+		if args.Has(param.Name) {
+			continue
+		}
+
+		if param.Type == Text {
+			return positional >= before
+		}
+		before++
+	}
+
+	return false
+}
+
+// Usage generates the usage string of a command from its parameters,
+// eg. 'extend [duration]' or 'force unbook <server>'.
+func Usage(command string, params []Param) string {
+	usage := command
+	for _, param := range params {
+		name := param.Name
+		if param.Type == Text {
+			name = fmt.Sprintf("%s...", name)
+		}
+
+		if param.Optional {
+			usage = fmt.Sprintf("%s [%s]", usage, name)
+		} else {
+			usage = fmt.Sprintf("%s <%s>", usage, name)
+		}
+	}
+
+	return usage
+}
@@ -0,0 +1,235 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTokenizeQuotes(t *testing.T) {
+	tokens, err := Tokenize(`force unbook "Server #1" "it""s" a\ b \"`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expected := []string{"force", "unbook", "Server #1", "its", "a b", `"`}
+	if !reflect.DeepEqual(tokens, expected) {
+		t.Fatalf("Expected %q but got %q", expected, tokens)
+	}
+}
+
+func TestTokenizeApostrophes(t *testing.T) {
+	tokens, err := Tokenize(`report he's cheating 'again'`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expected := []string{"report", "he's", "cheating", "'again'"}
+	if !reflect.DeepEqual(tokens, expected) {
+		t.Fatalf("Expected %q but got %q", expected, tokens)
+	}
+}
+
+func TestTokenizeWhitespace(t *testing.T) {
+	tokens, err := Tokenize("  send   password  ")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expected := []string{"send", "password"}
+	if !reflect.DeepEqual(tokens, expected) {
+		t.Fatalf("Expected %q but got %q", expected, tokens)
+	}
+}
+
+func TestTokenizeEmptyQuotes(t *testing.T) {
+	tokens, err := Tokenize(`demos ""`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expected := []string{"demos", ""}
+	if !reflect.DeepEqual(tokens, expected) {
+		t.Fatalf("Expected %q but got %q", expected, tokens)
+	}
+}
+
+func TestTokenizeUnterminated(t *testing.T) {
+	if _, err := Tokenize(`book "server`); err != ErrUnterminatedQuote {
+		t.Fatalf("Expected ErrUnterminatedQuote but got %v", err)
+	}
+}
+
+func TestRouterLongestMatch(t *testing.T) {
+	var r Router
+	r.Add("send", "send")
+	r.Add("send password", "send password")
+
+	for i := 0; i < 10; i++ {
+		value, command, rest, ok := r.Match([]string{"Send", "PASSWORD", "now"})
+		if !ok || value != "send password" || command != "send password" || !reflect.DeepEqual(rest, []string{"now"}) {
+			t.Fatalf("Expected 'send password' to match, got %v %q %q", value, command, rest)
+		}
+	}
+
+	value, _, rest, ok := r.Match([]string{"send", "demos"})
+	if !ok || value != "send" || !reflect.DeepEqual(rest, []string{"demos"}) {
+		t.Fatalf("Expected 'send' to match, got %v %q", value, rest)
+	}
+}
+
+func TestRouterNoMatch(t *testing.T) {
+	var r Router
+	r.Add("book", "book")
+
+	if _, _, _, ok := r.Match([]string{"booking"}); ok {
+		t.Fatalf("Expected no match")
+	}
+	if _, _, _, ok := r.Match(nil); ok {
+		t.Fatalf("Expected no match")
+	}
+}
+
+func TestRouterRemove(t *testing.T) {
+	var r Router
+	r.Add("book", "book")
+	r.Remove("book")
+
+	if _, _, _, ok := r.Match([]string{"book"}); ok {
+		t.Fatalf("Expected removed command not to match")
+	}
+}
+
+var testParams = []Param{
+	{Name: "user", Type: User},
+	{Name: "duration", Type: Duration, Optional: true},
+	{Name: "reason", Type: Text, Optional: true},
+}
+
+func TestParsePositional(t *testing.T) {
+	args, err := Parse(testParams, []string{"<@!1234>", "1h30m", "too", "many", "players"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if args.User("user") != "1234" {
+		t.Fatalf("Expected user 1234 but got %q", args.User("user"))
+	}
+	if args.Duration("duration") != 90*time.Minute {
+		t.Fatalf("Expected 1h30m but got %s", args.Duration("duration"))
+	}
+	if args.String("reason") != "too many players" {
+		t.Fatalf("Expected 'too many players' but got %q", args.String("reason"))
+	}
+}
+
+func TestParseNamed(t *testing.T) {
+	args, err := Parse(testParams, []string{"duration:45", "<@1234>", "server:1.2.3.4:27015"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if args.Duration("duration") != 45*time.Minute {
+		t.Fatalf("Expected 45m but got %s", args.Duration("duration"))
+	}
+	if args.String("reason") != "server:1.2.3.4:27015" {
+		t.Fatalf("Expected unknown options to be positional, got %q", args.String("reason"))
+	}
+}
+
+func TestParseNamedInText(t *testing.T) {
+	args, err := Parse(testParams, []string{"1234", "duration:45", "lag", "on", "mid", "user:5678"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if args.User("user") != "1234" || args.Duration("duration") != 45*time.Minute {
+		t.Fatalf("Expected the options before the text to be set, got %q %s", args.User("user"), args.Duration("duration"))
+	}
+	if args.String("reason") != "lag on mid user:5678" {
+		t.Fatalf("Expected options inside the text to be kept, got %q", args.String("reason"))
+	}
+
+	args, err = Parse([]Param{{Name: "reason", Type: Text}}, []string{"reason:lag", "on", "mid"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if args.String("reason") != "lag on mid" {
+		t.Fatalf("Expected a named text to take the rest of the tokens, got %q", args.String("reason"))
+	}
+}
+
+func TestParseWithoutParams(t *testing.T) {
+	args, err := Parse(nil, []string{"please"})
+	if err != nil {
+		t.Fatalf("Expected extra tokens to be ignored, got %s", err)
+	}
+	if len(args.Raw) != 1 {
+		t.Fatalf("Expected the raw tokens to be kept, got %v", args.Raw)
+	}
+}
+
+func TestParseOptional(t *testing.T) {
+	args, err := Parse(testParams, []string{"1234"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if args.Has("duration") || args.Has("reason") {
+		t.Fatalf("Expected optional parameters to be missing")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	if _, err := Parse(testParams, nil); err == nil {
+		t.Fatalf("Expected an error for a missing required parameter")
+	}
+
+	if _, err := Parse(testParams, []string{"someone"}); err == nil {
+		t.Fatalf("Expected an error for an invalid user")
+	}
+
+	if _, err := Parse(testParams, []string{"1234", "soon"}); err == nil {
+		t.Fatalf("Expected an error for an invalid duration")
+	}
+
+	if _, err := Parse([]Param{{Name: "count", Type: Integer}}, []string{"1", "2"}); err == nil {
+		t.Fatalf("Expected an error for too many arguments")
+	}
+}
+
+func TestParseText(t *testing.T) {
+	tokens, err := Tokenize(`he's "really" cheating`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	args, err := Parse([]Param{{Name: "reason", Type: Text}}, tokens)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if args.String("reason") != "he's really cheating" {
+		t.Fatalf("Expected 'he's really cheating' but got %q", args.String("reason"))
+	}
+}
+
+func TestParseChannel(t *testing.T) {
+	args, err := Parse([]Param{{Name: "channel", Type: Channel}}, []string{"<#5678>"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if args.Channel("channel") != "5678" {
+		t.Fatalf("Expected channel 5678 but got %q", args.Channel("channel"))
+	}
+}
+
+func TestUsage(t *testing.T) {
+	usage := Usage("report", testParams)
+	expected := "report <user> [duration] [reason...]"
+
+	if usage != expected {
+		t.Fatalf("Expected %q but got %q", expected, usage)
+	}
+}
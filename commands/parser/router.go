@@ -0,0 +1,71 @@
+package parser
+
+import (
+	"sort"
+	"strings"
+)
+
+type route struct {
+	command string
+	tokens  []string
+	value   interface{}
+}
+
+// Router matches tokens to the longest registered command, so that overlapping
+// commands such as "send" and "send password" always resolve the same way.
+type Router struct {
+	routes []route
+}
+
+// Add registers the value for the command, replacing any value already registered for it.
+func (r *Router) Add(command string, value interface{}) {
+	r.Remove(command)
+
+	r.routes = append(r.routes, route{
+		command: command,
+		tokens:  strings.Fields(command),
+		value:   value,
+	})
+
+	// Longest commands first, then alphabetical so the order is deterministic.
+	sort.SliceStable(r.routes, func(i, j int) bool {
+		if len(r.routes[i].tokens) != len(r.routes[j].tokens) {
+			return len(r.routes[i].tokens) > len(r.routes[j].tokens)
+		}
+		return r.routes[i].command < r.routes[j].command
+	})
+}
+
+// Remove unregisters the command.
+func (r *Router) Remove(command string) {
+	for i, rt := range r.routes {
+		if rt.command == command {
+			r.routes = append(r.routes[:i], r.routes[i+1:]...)
+			return
+		}
+	}
+}
+
+// Match finds the longest registered command that the tokens start with, ignoring case.
+// Returns the value & command that matched, and the remaining tokens.
+func (r *Router) Match(tokens []string) (value interface{}, command string, rest []string, ok bool) {
+	for _, rt := range r.routes {
+		if len(rt.tokens) == 0 || len(tokens) < len(rt.tokens) {
+			continue
+		}
+
+		matched := true
+		for i, token := range rt.tokens {
+			if !strings.EqualFold(token, tokens[i]) {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			return rt.value, rt.command, tokens[len(rt.tokens):], true
+		}
+	}
+
+	return nil, "", nil, false
+}
@@ -0,0 +1,62 @@
+package parser
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// ErrUnterminatedQuote is returned when a quoted argument is missing its closing quote.
+var ErrUnterminatedQuote = errors.New("parser: unterminated quote")
+
+// Tokenize splits the input on whitespace into tokens.
+// Double quotes group text (including whitespace) into a single token,
+// and a backslash escapes the next character.
+// Single quotes aren't quotes, as they're used as apostrophes in free text, eg. "he's cheating".
+func Tokenize(input string) ([]string, error) {
+	var tokens []string
+	var current strings.Builder
+
+	inToken := false
+	quoted := false
+	escaped := false
+
+	for _, r := range input {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+			inToken = true
+		case quoted:
+			if r == '"' {
+				quoted = false
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"':
+			quoted = true
+			inToken = true
+		case unicode.IsSpace(r):
+			if inToken {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				inToken = false
+			}
+		default:
+			current.WriteRune(r)
+			inToken = true
+		}
+	}
+
+	if quoted {
+		return nil, ErrUnterminatedQuote
+	}
+
+	if inToken {
+		tokens = append(tokens, current.String())
+	}
+
+	return tokens, nil
+}
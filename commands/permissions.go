@@ -0,0 +1,83 @@
+package commands
+
+import (
+	"alex-j-butler.com/tf2-booking/config"
+	"alex-j-butler.com/tf2-booking/util"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// PermissionGroups grants named command groups to Discord roles & users.
+type PermissionGroups []config.PermissionGroup
+
+// Matching returns the names of the permission groups the user is a member of,
+// either directly or through one of their roles.
+func (pg PermissionGroups) Matching(userID string, roles []string) []string {
+	var names []string
+	for _, group := range pg {
+		if util.Contains(group.Users, userID) || containsAny(group.Roles, roles) {
+			names = append(names, group.Name)
+		}
+	}
+
+	return names
+}
+
+// Allows returns whether the user is granted the command group,
+// either directly or through one of their roles.
+func (pg PermissionGroups) Allows(userID string, roles []string, commandGroup string) bool {
+	if commandGroup == "" {
+		return false
+	}
+
+	for _, group := range pg {
+		if !util.Contains(group.Commands, commandGroup) {
+			continue
+		}
+
+		if util.Contains(group.Users, userID) || containsAny(group.Roles, roles) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// containsAny returns whether any of the values are in the slice.
+func containsAny(s []string, values []string) bool {
+	for _, value := range values {
+		if util.Contains(s, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MemberRoles returns the role IDs of the user in the guild, using the session state.
+// Returns nil if the user isn't a member of the guild.
+func MemberRoles(session *discordgo.Session, guildID string, userID string) []string {
+	if guildID == "" {
+		return nil
+	}
+
+	member, err := session.State.Member(guildID, userID)
+	if err != nil {
+		member, err = session.GuildMember(guildID, userID)
+		if err != nil {
+			return nil
+		}
+	}
+
+	return member.Roles
+}
+
+// allowed returns whether the caller with the specified Discord permissions & roles may run a command
+// requiring the permissions and command group.
+func allowed(groups PermissionGroups, required int64, commandGroup string, permissions int64, userID string, roles []string) bool {
+	if required == -1 || permissions&required != 0 {
+		return true
+	}
+
+	return groups.Allows(userID, roles, commandGroup)
+}
@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"testing"
+
+	"alex-j-butler.com/tf2-booking/config"
+)
+
+var testGroups = PermissionGroups{
+	{
+		Name:     "Server Helper",
+		Roles:    []string{"100"},
+		Commands: []string{"moderation"},
+	},
+	{
+		Name:     "Owner",
+		Users:    []string{"1"},
+		Commands: []string{"admin", "moderation"},
+	},
+}
+
+func TestPermissionGroupsAllowsRole(t *testing.T) {
+	if !testGroups.Allows("2", []string{"100"}, "moderation") {
+		t.Fatalf("Expected role 100 to be allowed the moderation group")
+	}
+
+	if testGroups.Allows("2", []string{"100"}, "admin") {
+		t.Fatalf("Expected role 100 not to be allowed the admin group")
+	}
+}
+
+func TestPermissionGroupsAllowsUser(t *testing.T) {
+	if !testGroups.Allows("1", nil, "admin") {
+		t.Fatalf("Expected user 1 to be allowed the admin group")
+	}
+
+	if testGroups.Allows("3", nil, "admin") {
+		t.Fatalf("Expected user 3 not to be allowed the admin group")
+	}
+}
+
+func TestPermissionGroupsEmptyGroup(t *testing.T) {
+	if testGroups.Allows("1", []string{"100"}, "") {
+		t.Fatalf("Expected commands without a group not to be granted by permission groups")
+	}
+}
+
+func TestPermissionGroupsMatching(t *testing.T) {
+	names := testGroups.Matching("1", []string{"100"})
+
+	if len(names) != 2 || names[0] != "Server Helper" || names[1] != "Owner" {
+		t.Fatalf("Expected [Server Helper Owner] but got %v", names)
+	}
+}
+
+func TestAllowedBitmask(t *testing.T) {
+	groups := PermissionGroups([]config.PermissionGroup{})
+
+	if !allowed(groups, -1, "", 0, "1", nil) {
+		t.Fatalf("Expected commands without permissions to be allowed")
+	}
+
+	if !allowed(groups, 0x20, "admin", 0x20, "1", nil) {
+		t.Fatalf("Expected matching permissions to be allowed")
+	}
+
+	if allowed(groups, 0x20, "admin", 0x08, "1", nil) {
+		t.Fatalf("Expected missing permissions not to be allowed")
+	}
+}
@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"fmt"
+
+	"alex-j-butler.com/tf2-booking/config"
+	"alex-j-butler.com/tf2-booking/ratelimit"
+)
+
+// Limiter rate limits the commands of each user, shared by the text & slash commands.
+var Limiter = ratelimit.New()
+
+// CommandLimit returns the configured rate limit for the command.
+func CommandLimit(name string) ratelimit.Limit {
+	limit, ok := config.Get().RateLimits.Commands[name]
+	if !ok {
+		limit = config.Get().RateLimits.Default
+	}
+
+	return ratelimit.Limit{
+		Requests: limit.Requests,
+		Per:      limit.Per.Duration,
+	}
+}
+
+// rateLimitExempt returns whether the user is in a permission group that isn't rate limited.
+func rateLimitExempt(userID string, roles []string) bool {
+	for _, group := range PermissionGroups(config.Get().Permissions).Matching(userID, roles) {
+		for _, exempt := range config.Get().RateLimits.ExemptGroups {
+			if group == exempt {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// rateLimit takes a use of the command from the user's rate limit.
+func rateLimit(userID string, roles []string, name string) ratelimit.Result {
+	if rateLimitExempt(userID, roles) {
+		return ratelimit.Result{Allowed: true}
+	}
+
+	return Limiter.Allow(fmt.Sprintf("%s:%s", userID, name), CommandLimit(name))
+}
@@ -0,0 +1,215 @@
+package commands
+
+import (
+	"alex-j-butler.com/tf2-booking/config"
+	"alex-j-butler.com/tf2-booking/messages"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/bwmarrin/discordgo"
+)
+
+// SlashFunction is the function called when a slash command is used.
+// The options are mapped by their name.
+type SlashFunction func(*discordgo.InteractionCreate, map[string]*discordgo.ApplicationCommandInteractionDataOption)
+
+type SlashHandler struct {
+	command     *discordgo.ApplicationCommand
+	function    SlashFunction
+	permissions int64
+	group       string
+	respondToDM bool
+}
+
+// SlashCommand is the registry of Discord application (slash) commands.
+type SlashCommand struct {
+	Handlers map[string]*SlashHandler
+
+	middleware []Middleware
+}
+
+// NewSlash creates a slash command handler for the command with the specified name & description.
+func NewSlash(name string, description string, function SlashFunction) *SlashHandler {
+	return &SlashHandler{
+		command: &discordgo.ApplicationCommand{
+			Name:        name,
+			Description: description,
+		},
+		function:    function,
+		permissions: -1,
+		respondToDM: false,
+	}
+}
+
+// Options adds typed options to the slash command.
+func (sh *SlashHandler) Options(options ...*discordgo.ApplicationCommandOption) *SlashHandler {
+	sh.command.Options = append(sh.command.Options, options...)
+	return sh
+}
+
+func (sh *SlashHandler) Permissions(permissions int64) *SlashHandler {
+	sh.permissions = permissions
+	return sh
+}
+
+// Group sets the command group of the command, allowing the permission groups in the
+// configuration to grant the command without the Discord permissions.
+func (sh *SlashHandler) Group(group string) *SlashHandler {
+	sh.group = group
+	return sh
+}
+
+func (sh *SlashHandler) RespondToDM(respondToDM bool) *SlashHandler {
+	sh.respondToDM = respondToDM
+	return sh
+}
+
+// NewSlashCommand creates a new instance of the slash command system.
+func NewSlashCommand() *SlashCommand {
+	return &SlashCommand{
+		Handlers: make(map[string]*SlashHandler),
+	}
+}
+
+// Use adds middleware to run for every slash command, in the order it was added.
+// The middleware is given the command's name, and the InteractionCommandInformation of the interaction.
+func (c *SlashCommand) Use(middleware ...Middleware) {
+	c.middleware = append(c.middleware, middleware...)
+}
+
+// Add creates a new entry in the slash command handlers map.
+func (c *SlashCommand) Add(handler *SlashHandler) {
+	c.Handlers[handler.command.Name] = handler
+}
+
+// Register overwrites the slash commands registered with Discord for the application.
+// An empty guild ID registers the commands globally.
+func (c *SlashCommand) Register(session *discordgo.Session, appID string, guildID string) error {
+	applicationCommands := make([]*discordgo.ApplicationCommand, 0, len(c.Handlers))
+	for _, handler := range c.Handlers {
+		command := *handler.command
+
+		// Hide admin commands from users without the permissions,
+		// unless a permission group can also grant the command.
+		if handler.permissions != -1 && handler.group == "" {
+			permissions := handler.permissions
+			command.DefaultMemberPermissions = &permissions
+		}
+
+		dmPermission := handler.respondToDM
+		command.DMPermission = &dmPermission
+
+		applicationCommands = append(applicationCommands, &command)
+	}
+
+	_, err := session.ApplicationCommandBulkOverwrite(appID, guildID, applicationCommands)
+	return err
+}
+
+// Handle dispatches an application command interaction to the appropriate command handler.
+func (c *SlashCommand) Handle(session *discordgo.Session, i *discordgo.InteractionCreate, permissions int64) {
+	data := i.ApplicationCommandData()
+
+	handler, ok := c.Handlers[data.Name]
+	if !ok {
+		log.WithField("command", data.Name).Warn("Received unknown slash command")
+		return
+	}
+
+	var roles []string
+	if i.Member != nil {
+		roles = i.Member.Roles
+	}
+
+	dispatch := func(ctx *Context) {
+		if !handler.respondToDM && i.GuildID == "" {
+			Respond(session, i, Text(InteractionUser(i).ID, "command.no_dm", nil), true)
+			return
+		}
+
+		if !allowed(PermissionGroups(config.Get().Permissions), handler.permissions, handler.group, permissions, InteractionUser(i).ID, roles) {
+			Respond(session, i, Text(InteractionUser(i).ID, "command.forbidden", nil), true)
+			return
+		}
+
+		// Interactions must always be responded to, so the notice is sent every time,
+		// but only the user can see it.
+		if result := rateLimit(InteractionUser(i).ID, roles, data.Name); !result.Allowed {
+			Respond(session, i, Text(InteractionUser(i).ID, "command.rate_limited", messages.Data{"RetryAfter": result.RetryAfter}), true)
+			return
+		}
+
+		handler.function(i, OptionMap(data.Options))
+	}
+
+	Chain(dispatch, c.middleware)(&Context{
+		CommandInformation: NewInteractionCommandInformation(session, i),
+		Command:            data.Name,
+		Name:               data.Name,
+		Session:            session,
+		Permissions:        permissions,
+		Roles:              roles,
+	})
+}
+
+// OptionMap maps the options of a slash command by their name.
+func OptionMap(options []*discordgo.ApplicationCommandInteractionDataOption) map[string]*discordgo.ApplicationCommandInteractionDataOption {
+	optionMap := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(options))
+	for _, option := range options {
+		optionMap[option.Name] = option
+	}
+
+	return optionMap
+}
+
+// InteractionUser returns the user that created the interaction,
+// whether it was created in a guild or a direct message.
+func InteractionUser(i *discordgo.InteractionCreate) *discordgo.User {
+	if i.Member != nil {
+		return i.Member.User
+	}
+
+	return i.User
+}
+
+// Respond replies to the interaction with a message,
+// which is only visible to the user if ephemeral is set.
+func Respond(session *discordgo.Session, i *discordgo.InteractionCreate, content string, ephemeral bool) error {
+	data := &discordgo.InteractionResponseData{
+		Content: content,
+	}
+	if ephemeral {
+		data.Flags = discordgo.MessageFlagsEphemeral
+	}
+
+	return session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: data,
+	})
+}
+
+// Defer acknowledges the interaction, for handlers that may take longer than Discord
+// allows before responding. The response is later sent with EditResponse.
+func Defer(session *discordgo.Session, i *discordgo.InteractionCreate, ephemeral bool) error {
+	data := &discordgo.InteractionResponseData{}
+	if ephemeral {
+		data.Flags = discordgo.MessageFlagsEphemeral
+	}
+
+	return session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: data,
+	})
+}
+
+// EditResponse replaces the response of a deferred interaction.
+func EditResponse(session *discordgo.Session, i *discordgo.InteractionCreate, content string, embeds []*discordgo.MessageEmbed) error {
+	edit := &discordgo.WebhookEdit{
+		Content: &content,
+	}
+	if embeds != nil {
+		edit.Embeds = &embeds
+	}
+
+	_, err := session.InteractionResponseEdit(i.Interaction, edit)
+	return err
+}
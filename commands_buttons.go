@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+
+	"alex-j-butler.com/tf2-booking/commands"
+	"alex-j-butler.com/tf2-booking/messages"
+	"alex-j-butler.com/tf2-booking/servers"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Names of the booking message buttons, encoded into their custom IDs.
+const (
+	buttonExtend   = "extend"
+	buttonPassword = "password"
+	buttonUnbook   = "unbook"
+	buttonDemos    = "demos"
+)
+
+// RegisterButtons adds the handlers for the buttons attached to the booking message.
+func RegisterButtons(components *commands.ComponentCommand) {
+	components.Add(buttonExtend, ButtonExtendServer)
+	components.Add(buttonPassword, ButtonSendPassword)
+	components.Add(buttonUnbook, ButtonUnbookServer)
+	components.Add(buttonDemos, ButtonUploadDemos)
+}
+
+// bookingButtons returns the buttons attached to the booking message of the specified server.
+func bookingButtons(locale string, serv *servers.Server, disabled bool) []discordgo.MessageComponent {
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    messages.Render(locale, "button.extend", nil),
+					Style:    discordgo.PrimaryButton,
+					CustomID: commands.CustomID(buttonExtend, serv.UUID),
+					Disabled: disabled,
+				},
+				discordgo.Button{
+					Label:    messages.Render(locale, "button.password", nil),
+					Style:    discordgo.SecondaryButton,
+					CustomID: commands.CustomID(buttonPassword, serv.UUID),
+					Disabled: disabled,
+				},
+				discordgo.Button{
+					Label:    messages.Render(locale, "button.demos", nil),
+					Style:    discordgo.SecondaryButton,
+					CustomID: commands.CustomID(buttonDemos, serv.UUID),
+					Disabled: disabled,
+				},
+				discordgo.Button{
+					Label:    messages.Render(locale, "button.unbook", nil),
+					Style:    discordgo.DangerButton,
+					CustomID: commands.CustomID(buttonUnbook, serv.UUID),
+					Disabled: disabled,
+				},
+			},
+		},
+	}
+}
+
+// bookingStatus returns a line describing the current state of the booking.
+func bookingStatus(locale string, serv *servers.Server) string {
+	if serv.ReturnDate.IsZero() {
+		return messages.Render(locale, "booking.booked_at", messages.Data{"Time": serv.BookedDate.Format("15:04 MST")})
+	}
+
+	return messages.Render(locale, "time.left", messages.Data{"Duration": serv.TimeLeft()})
+}
+
+// buttonServer returns the server a booking button was pressed for, if it's still booked by the user pressing it.
+// If it isn't, the interaction is responded to and nil is returned.
+func buttonServer(i *discordgo.InteractionCreate, args []string) *servers.Server {
+	user := commands.InteractionUser(i)
+	locale := UserLocale(user.ID)
+
+	if len(args) < 1 {
+		return nil
+	}
+
+	serv, err := pool.GetServerByUUID(args[0])
+	if err != nil || !serv.Booked {
+		// The booking has ended, disable the buttons.
+		content := fmt.Sprintf("%s\n_%s_", serverDetailsContent(locale), messages.Render(locale, "button.ended", nil))
+		commands.UpdateMessage(Session, i, content, i.Message.Embeds, bookingButtons(locale, &servers.Server{UUID: args[0]}, true))
+		return nil
+	}
+
+	if serv.Booker != user.ID {
+		commands.Respond(Session, i, messages.Render(locale, "button.not_yours", nil), true)
+		return nil
+	}
+
+	return serv
+}
+
+// ButtonExtendServer button handler.
+// Called when the user presses the 'Extend' button on their booking message.
+func ButtonExtendServer(i *discordgo.InteractionCreate, args []string) {
+	serv := buttonServer(i, args)
+	if serv == nil {
+		return
+	}
+
+	user := commands.InteractionUser(i)
+	locale := UserLocale(user.ID)
+
+	serv, duration, err := Bookings.Extend(user.ID, 0)
+	if err != nil {
+		commands.Respond(Session, i, bookingErrorMessage(locale, err), true)
+		return
+	}
+
+	// Notify server of successful operation.
+	serv.Say(user.Username, messages.Render(locale, "extend.extended", messages.Data{"Duration": duration}))
+
+	content := fmt.Sprintf("%s\n_%s %s_", serverDetailsContent(locale), messages.Render(locale, "button.extended", messages.Data{"Duration": duration}), bookingStatus(locale, serv))
+	commands.UpdateMessage(Session, i, content, i.Message.Embeds, bookingButtons(locale, serv, false))
+}
+
+// ButtonSendPassword button handler.
+// Called when the user presses the 'Resend password' button on their booking message.
+// The message is updated with the current server password.
+func ButtonSendPassword(i *discordgo.InteractionCreate, args []string) {
+	serv := buttonServer(i, args)
+	if serv == nil {
+		return
+	}
+
+	user := commands.InteractionUser(i)
+
+	serv, serverPassword, err := Bookings.Password(user.ID)
+	if err != nil && serv == nil {
+		commands.Respond(Session, i, bookingErrorMessage(UserLocale(user.ID), err), true)
+		return
+	} else if err != nil {
+		commands.Respond(Session, i, Text(user.ID, "password.failed", nil), true)
+		return
+	}
+
+	locale := UserLocale(user.ID)
+	content := fmt.Sprintf("%s\n_%s_", serverDetailsContent(locale), bookingStatus(locale, serv))
+	commands.UpdateMessage(Session, i, content, serverDetailsEmbeds(locale, serv, serverPassword, serv.RCONPassword), bookingButtons(locale, serv, false))
+}
+
+// ButtonUnbookServer button handler.
+// Called when the user presses the 'Unbook' button on their booking message.
+func ButtonUnbookServer(i *discordgo.InteractionCreate, args []string) {
+	serv := buttonServer(i, args)
+	if serv == nil {
+		return
+	}
+
+	user := commands.InteractionUser(i)
+
+	// Stopping the server & uploading the demos can take longer than Discord waits for a response.
+	commands.DeferUpdate(Session, i)
+
+	serv, err := Bookings.Unbook(user.ID)
+	if err != nil {
+		commands.Followup(Session, i, bookingErrorMessage(UserLocale(user.ID), err))
+		return
+	}
+
+	locale := UserLocale(user.ID)
+	content := fmt.Sprintf("%s\n_%s_", serverDetailsContent(locale), messages.Render(locale, "unbook.returned", nil))
+	components := bookingButtons(locale, serv, true)
+	Session.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content:    &content,
+		Components: &components,
+	})
+}
+
+// ButtonUploadDemos button handler.
+// Called when the user presses the 'Upload demos' button on their booking message.
+// The demos recorded so far are uploaded, without ending the booking.
+func ButtonUploadDemos(i *discordgo.InteractionCreate, args []string) {
+	serv := buttonServer(i, args)
+	if serv == nil {
+		return
+	}
+
+	// Uploading the demos can take longer than Discord waits for a response.
+	commands.DeferUpdate(Session, i)
+
+	STVMessage, err := serv.UploadSTV()
+	if err != nil {
+		commands.Followup(Session, i, Text(commands.InteractionUser(i).ID, "demos.none", nil))
+		return
+	}
+
+	commands.Followup(Session, i, STVMessage)
+}
@@ -1,57 +1,37 @@
 package main
 
 import (
-	"fmt"
-	"strings"
-	"time"
-
-	"alex-j-butler.com/tf2-booking/commands/ingame"
+	"alex-j-butler.com/tf2-booking/commands"
 	"alex-j-butler.com/tf2-booking/config"
-	"alex-j-butler.com/tf2-booking/util"
+	"alex-j-butler.com/tf2-booking/events"
+	"alex-j-butler.com/tf2-booking/ratelimit"
 )
 
-func ReportServer(commandInfo ingame.CommandInfo, command string, args []string) {
-	if len(args) < 1 {
-		// No reason given, send error message to server.
-		commandInfo.Server.SendCommand(fmt.Sprintf("say Please give a reason: !report <reason>"))
-		return
-	}
-
-	if timeout, ok := UserReportTimeouts[commandInfo.SteamID]; ok && time.Since(timeout).Nanoseconds() < 0 {
+// ReportServer command handler.
+// Called when a player types the '!report' command into the ingame chat.
+// This function notifies the admins of the report.
+func ReportServer(ctx *commands.Context) {
+	reportLimit := ratelimit.Limit{Requests: 1, Per: config.Get().Commands.ReportDuration.Duration}
+	if !ReportLimiter.Allow(ctx.GetUserID(), reportLimit).Allowed {
 		// User can't report right now.
-		commandInfo.Server.SendCommand(fmt.Sprintf("say You can't report that quickly! Try again in a few minutes."))
+		ctx.Reply(Text(ctx.GetUserID(), "report.too_soon", nil))
 		return
 	}
 
-	reason := strings.Join(args, " ")
-
-	// Convert the steam id provided by the log handler.
-	steamID := util.FromSteamID3(commandInfo.SteamID)
-
-	// Construct the message.
-	message := fmt.Sprintf(
-		"Server '%s' (%s) has been reported by '%s' with reason: '%s'",
-		commandInfo.Server.Name,
-		commandInfo.Username,
-		steamID.GetCommunityURL(),
-		reason,
-	)
-
-	// Send the message to the notification users.
-	for _, notificationUser := range config.Conf.Discord.NotificationUsers {
-		UserChannel, _ := Session.UserChannelCreate(notificationUser)
-		Session.ChannelMessageSend(UserChannel.ID, message)
-	}
-
-	// Set the report timeout for this user.
-	UserReportTimeouts[commandInfo.SteamID] = time.Now().Add(config.Conf.Commands.ReportDuration.Duration)
+	// Let the subscribers notify the admins of the report.
+	Events.Publish(&events.PlayerReported{
+		Server:   ctx.GetChannelID(),
+		SteamID:  ctx.GetUserID(),
+		Username: ctx.GetUsername(),
+		Reason:   ctx.Args.String("reason"),
+	})
 
 	// Reply to the command.
-	commandInfo.Server.SendCommand(fmt.Sprintf("say Server reported! Thank you for your input."))
+	ctx.Reply(Text(ctx.GetUserID(), "report.sent", nil))
 }
 
-func TimeLeft(commandInfo ingame.CommandInfo, command string, args []string) {
-	// TODO: Implement this.
-	// duration := -time.Since(commandInfo.Server.ReturnDate)
-	// commandInfo.Server.SendCommand(fmt.Sprintf("say %s remaining in booking.", util.ToHuman(&duration)))
+// IngameHelp command handler.
+// Called when a player types the '!help' command into the ingame chat.
+func IngameHelp(ctx *commands.Context) {
+	IngameCommand.Help(ctx, ctx.Args.String("command"))
 }
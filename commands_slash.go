@@ -0,0 +1,218 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"alex-j-butler.com/tf2-booking/commands"
+	"alex-j-butler.com/tf2-booking/config"
+	"alex-j-butler.com/tf2-booking/messages"
+	"alex-j-butler.com/tf2-booking/util"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Discord only allows this many choices on a single slash command option.
+const maxOptionChoices = 25
+
+// RegisterSlashCommands adds the slash command versions of the booking commands.
+func RegisterSlashCommands(slash *commands.SlashCommand) {
+	minDuration := 1.0
+
+	serverOption := &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        "server",
+		Description: "Name of the server to book",
+		Required:    false,
+	}
+	for _, serv := range pool.GetServers() {
+		if len(serverOption.Choices) >= maxOptionChoices {
+			// Too many servers to list, accept any name instead.
+			serverOption.Choices = nil
+			break
+		}
+		serverOption.Choices = append(serverOption.Choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  serv.Name,
+			Value: serv.Name,
+		})
+	}
+	sort.Slice(serverOption.Choices, func(i, j int) bool {
+		return serverOption.Choices[i].Name < serverOption.Choices[j].Name
+	})
+
+	presetOption := &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        "preset",
+		Description: "Preset to load once the server starts",
+		Required:    false,
+	}
+	for name := range config.Get().Booking.Presets {
+		presetOption.Choices = append(presetOption.Choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  name,
+			Value: name,
+		})
+	}
+	sort.Slice(presetOption.Choices, func(i, j int) bool {
+		return presetOption.Choices[i].Name < presetOption.Choices[j].Name
+	})
+
+	bookOptions := []*discordgo.ApplicationCommandOption{
+		serverOption,
+		&discordgo.ApplicationCommandOption{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        "duration",
+			Description: "Length of the booking in minutes",
+			Required:    false,
+			MinValue:    &minDuration,
+		},
+	}
+	if len(presetOption.Choices) > 0 && len(presetOption.Choices) <= maxOptionChoices {
+		bookOptions = append(bookOptions, presetOption)
+	}
+
+	slash.Add(
+		commands.NewSlash("book", "Book a new server", SlashBookServer).
+			Options(bookOptions...),
+	)
+	slash.Add(
+		commands.NewSlash("unbook", "Unbook your current server", SlashUnbookServer),
+	)
+	slash.Add(
+		commands.NewSlash("extend", "Extend your current booking", SlashExtendServer).
+			Options(&discordgo.ApplicationCommandOption{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "duration",
+				Description: "Minutes to extend the booking by",
+				Required:    false,
+				MinValue:    &minDuration,
+			}),
+	)
+	slash.Add(
+		commands.NewSlash("password", "Send the updated server details", SlashSendPassword).
+			RespondToDM(true),
+	)
+	slash.Add(
+		commands.NewSlash("demos", "Send the link to the uploaded demos", SlashDemoLink).
+			Options(&discordgo.ApplicationCommandOption{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "user",
+				Description: "User to find the demos of",
+				Required:    false,
+			}).
+			RespondToDM(true),
+	)
+}
+
+// SlashBookServer slash command handler.
+// Called when a user uses the '/book' command.
+// The server details are sent as an ephemeral reply, so only the user can see them.
+func SlashBookServer(i *discordgo.InteractionCreate, options map[string]*discordgo.ApplicationCommandInteractionDataOption) {
+	user := commands.InteractionUser(i)
+
+	var serverName, preset string
+	var duration time.Duration
+	if option, ok := options["server"]; ok {
+		serverName = option.StringValue()
+	}
+	if option, ok := options["preset"]; ok {
+		preset = option.StringValue()
+	}
+	if option, ok := options["duration"]; ok {
+		duration = time.Duration(option.IntValue()) * time.Minute
+	}
+
+	// Setting up the server can take longer than Discord waits for a response.
+	commands.Defer(Session, i, true)
+
+	b, err := Bookings.Book(user, serverName, preset, duration)
+	if err != nil {
+		commands.EditResponse(Session, i, bookingErrorMessage(UserLocale(user.ID), err), nil)
+		return
+	}
+
+	commands.EditResponse(
+		Session,
+		i,
+		serverDetailsContent(UserLocale(user.ID)),
+		serverDetailsEmbeds(UserLocale(user.ID), b.Server, b.ServerPassword, b.RCONPassword),
+	)
+}
+
+// SlashUnbookServer slash command handler.
+// Called when a user uses the '/unbook' command.
+func SlashUnbookServer(i *discordgo.InteractionCreate, options map[string]*discordgo.ApplicationCommandInteractionDataOption) {
+	user := commands.InteractionUser(i)
+
+	commands.Defer(Session, i, false)
+
+	if _, err := Bookings.Unbook(user.ID); err != nil {
+		commands.EditResponse(Session, i, bookingErrorMessage(UserLocale(user.ID), err), nil)
+		return
+	}
+
+	commands.EditResponse(Session, i, Text(user.ID, "unbook.returned", nil), nil)
+}
+
+// SlashExtendServer slash command handler.
+// Called when a user uses the '/extend' command.
+func SlashExtendServer(i *discordgo.InteractionCreate, options map[string]*discordgo.ApplicationCommandInteractionDataOption) {
+	user := commands.InteractionUser(i)
+
+	var duration time.Duration
+	if option, ok := options["duration"]; ok {
+		duration = time.Duration(option.IntValue()) * time.Minute
+	}
+
+	Serv, duration, err := Bookings.Extend(user.ID, duration)
+	if err != nil {
+		commands.Respond(Session, i, bookingErrorMessage(UserLocale(user.ID), err), true)
+		return
+	}
+
+	message := Text(user.ID, "extend.extended", messages.Data{"Duration": duration})
+
+	// Notify server of successful operation.
+	Serv.Say(user.Username, message)
+
+	commands.Respond(Session, i, message, false)
+}
+
+// SlashSendPassword slash command handler.
+// Called when a user uses the '/password' command.
+// The server details are sent as an ephemeral reply, so only the user can see them.
+func SlashSendPassword(i *discordgo.InteractionCreate, options map[string]*discordgo.ApplicationCommandInteractionDataOption) {
+	user := commands.InteractionUser(i)
+
+	commands.Defer(Session, i, true)
+
+	Serv, serverPassword, err := Bookings.Password(user.ID)
+	if err != nil && Serv == nil {
+		commands.EditResponse(Session, i, bookingErrorMessage(UserLocale(user.ID), err), nil)
+		return
+	} else if err != nil {
+		commands.EditResponse(Session, i, Text(user.ID, "password.failed", nil), nil)
+		return
+	}
+
+	commands.EditResponse(Session, i, passwordMessage(UserLocale(user.ID), Serv, serverPassword), nil)
+}
+
+// SlashDemoLink slash command handler.
+// Called when a user uses the '/demos' command.
+func SlashDemoLink(i *discordgo.InteractionCreate, options map[string]*discordgo.ApplicationCommandInteractionDataOption) {
+	target := commands.InteractionUser(i)
+	if option, ok := options["user"]; ok {
+		if user := option.UserValue(Session); user != nil {
+			target = user
+		}
+	}
+
+	User := &util.PatchUser{target}
+
+	commands.Respond(
+		Session,
+		i,
+		Text(commands.InteractionUser(i).ID, "demos.link", messages.Data{"Target": User.GetFullname()}),
+		false,
+	)
+}
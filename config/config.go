@@ -1,50 +1,103 @@
 package config
 
-import (
-	"io/ioutil"
-	"log"
+import "alex-j-butler.com/tf2-booking/util"
+
+// PermissionGroup grants named command groups to Discord roles & users,
+// in addition to the commands allowed by their Discord permissions.
+type PermissionGroup struct {
+	Name     string   `yaml:"name"`
+	Roles    []string `yaml:"roles"`
+	Users    []string `yaml:"users"`
+	Commands []string `yaml:"commands"`
+}
+
+// RateLimit allows a number of command uses over a period of time.
+// A rate limit with no requests is unlimited.
+type RateLimit struct {
+	Requests int               `yaml:"requests"`
+	Per      util.DurationUtil `yaml:"per"`
+}
+
+// Webhook is an HTTP endpoint that receives signed JSON payloads of booking events.
+type Webhook struct {
+	URL    string `yaml:"url"`
+	Secret string `yaml:"secret" secret:"true"`
 
-	"alex-j-butler.com/tf2-booking/util"
+	// Event types sent to the endpoint, all events if empty.
+	Events []string `yaml:"events"`
+}
+
+// APIClient is a client of the REST API, authenticated by its key.
+// Clients book servers as their Discord user, admin clients may act as any user.
+type APIClient struct {
+	Name   string `yaml:"name"`
+	Key    string `yaml:"key" secret:"true"`
+	UserID string `yaml:"user_id"`
+	Admin  bool   `yaml:"admin"`
+}
 
-	yaml "gopkg.in/yaml.v2"
-)
+// DashboardAdmin is a login of the admin dashboard.
+type DashboardAdmin struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password" secret:"true"`
+}
 
+// Config is the bot's configuration.
+// Settings tagged `reload:"restart"` are only read when the bot starts, so changing them needs a restart.
+// Settings tagged `secret:"true"` can be read from a file, and are redacted whenever the configuration is shown.
 type Config struct {
 
 	// Settings for the Discord bot
 	Discord struct {
-		Token          string `yaml:"token"`
-		DefaultChannel string `yaml:"default_channel"`
-		Debug          bool   `yaml:"debug"`
+		Token          string `yaml:"token" reload:"restart" secret:"true"`
+		GuildID        string `yaml:"guild_id" reload:"restart"`
+		DefaultChannel string `yaml:"default_channel" reload:"restart"`
+		StatusChannel  string `yaml:"status_channel" reload:"restart"`
+		AuditChannel   string `yaml:"audit_channel" reload:"restart"`
 
 		AcceptableChannels []string `yaml:"acceptable_channels"`
 		NotificationUsers  []string `yaml:"notification_users"`
 	} `yaml:"discord"`
 
+	// Settings for the bot's log
+	Logging struct {
+		// Minimum level of the logged entries, "debug", "info", "warn" or "error".
+		Level string `yaml:"level"`
+
+		// Format of the logged entries, "text" or "json".
+		Format string `yaml:"format"`
+
+		// Whether to log the debug messages of the Discord client.
+		DiscordDebug bool `yaml:"discord_debug" reload:"restart"`
+	} `yaml:"logging"`
+
 	// Settings for the UDP log handling server
 	LogServer struct {
 		LogAddress       string `yaml:"log_address"`
 		LogAddressRemote string `yaml:"log_address_remote"`
 		LogPort          int    `yaml:"log_port"`
-	} `yaml:"log_server"`
+	} `yaml:"log_server" reload:"restart"`
 
 	// Settings for the bookings
 	Booking struct {
 		IdleWarningDuration int `yaml:"idle_warning_duration"`
 
+		// Overrides the 'server.kick' message, kept for existing configurations.
 		KickMessage string `yaml:"kick_message"`
 
-		// Settings used for Bash server runner.
-		SetupCommand     string `yaml:"setup_command"`
-		StartCommand     string `yaml:"start_command"`
-		StopCommand      string `yaml:"stop_command"`
-		UploadSTVCommand string `yaml:"upload_stv_command"`
+		// Default scripts of the servers run with the "local" runner of the file pool.
+		SetupCommand     string `yaml:"setup_command" reload:"restart"`
+		StartCommand     string `yaml:"start_command" reload:"restart"`
+		StopCommand      string `yaml:"stop_command" reload:"restart"`
+		UploadSTVCommand string `yaml:"upload_stv_command" reload:"restart"`
+
+		// Default & maximum length of a booking, zero for bookings that don't expire.
+		DefaultDuration util.DurationUtil `yaml:"default_duration"`
+		MaxDuration     util.DurationUtil `yaml:"max_duration"`
 
-		// Settings used for the server API server runner.
-		Tag        string `yaml:"tag"`
-		APIAddress string `yaml:"api_address"`
-		APIPort    int    `yaml:"api_port"`
-		APIKey     string `yaml:"api_key"`
+		// Named presets that can be chosen while booking, mapped to the command
+		// that is sent to the server once it starts.
+		Presets map[string]string `yaml:"presets"`
 
 		MaxIdleMinutes int `yaml:"max_idle_minutes"`
 		MinPlayers     int `yaml:"min_players"`
@@ -52,30 +105,103 @@ type Config struct {
 		ErrorThreshold int `yaml:"error_threshold"`
 	} `yaml:"booking"`
 
+	// Settings for the pool of bookable servers
+	Servers struct {
+		// Where the servers are loaded from, "api" for the booking API's servers with the tag,
+		// or "file" for the servers listed in the file.
+		Pool string `yaml:"pool"`
+
+		// YAML file listing the servers of the "file" pool.
+		File string `yaml:"file"`
+	} `yaml:"servers" reload:"restart"`
+
+	// Settings for the booking API, which runs the servers
+	BookingAPI struct {
+		// Only servers with this tag are booked.
+		Tag string `yaml:"tag"`
+
+		Address string `yaml:"api_address"`
+		Port    int    `yaml:"api_port"`
+		Key     string `yaml:"api_key" secret:"true"`
+	} `yaml:"booking_api" reload:"restart"`
+
+	// Settings for the state reconciler
+	Reconcile struct {
+		// Cron schedule to run the reconciler on, in addition to startup.
+		Schedule string `yaml:"schedule" reload:"restart"`
+
+		// Bookings younger than this are skipped, as their server may still be starting.
+		GracePeriod util.DurationUtil `yaml:"grace_period"`
+
+		// Policies ("fix", "report" or "ignore") for each kind of inconsistency.
+		OrphanedUsers   string `yaml:"orphaned_users"`
+		UnbookedServers string `yaml:"unbooked_servers"`
+		StoppedBookings string `yaml:"stopped_bookings"`
+	} `yaml:"reconcile"`
+
+	// Settings for the message catalogue
+	Messages struct {
+		// Locale used for users without a language preference, and for admin notifications.
+		Locale string `yaml:"locale"`
+
+		// Directory containing the locale files, eg. "de.yml".
+		Directory string `yaml:"directory"`
+	} `yaml:"messages" reload:"restart"`
+
 	Commands struct {
 		ReportDuration util.DurationUtil `yaml:"report_duration"`
 	}
 
+	Permissions []PermissionGroup `yaml:"permissions"`
+
+	// Settings for the per-user command rate limits
+	RateLimits struct {
+		// Limit applied to each command that doesn't have its own limit.
+		Default RateLimit `yaml:"default"`
+
+		// Limits for specific commands, by command name.
+		Commands map[string]RateLimit `yaml:"commands"`
+
+		// Names of the permission groups that aren't rate limited.
+		ExemptGroups []string `yaml:"exempt_groups"`
+	} `yaml:"rate_limits"`
+
+	// Settings for the HTTP server, serving the metrics, REST API & admin dashboard
+	HTTP struct {
+		// Address to listen on, eg. ":8080", the HTTP server is disabled if empty.
+		Address string `yaml:"address"`
+	} `yaml:"http" reload:"restart"`
+
+	// Settings for the REST API, served under '/api'
+	API struct {
+		Enabled bool `yaml:"enabled"`
+
+		Clients []APIClient `yaml:"clients"`
+	} `yaml:"api" reload:"restart"`
+
+	// Settings for the admin dashboard, served under '/dashboard'
+	Dashboard struct {
+		Enabled bool `yaml:"enabled"`
+
+		// Secret signing the session cookies, a random secret is used if empty,
+		// which logs out the admins whenever the bot restarts.
+		SessionSecret string `yaml:"session_secret" secret:"true"`
+
+		Admins []DashboardAdmin `yaml:"admins"`
+	} `yaml:"dashboard" reload:"restart"`
+
+	// Webhooks that receive the booking events
+	Webhooks []Webhook `yaml:"webhooks" reload:"restart"`
+
 	Database struct {
-		DSN string `yaml:"dsn"`
-	} `yaml:"database"`
+		DSN string `yaml:"dsn" secret:"true"`
+	} `yaml:"database" reload:"restart"`
 
 	Redis struct {
 		Address  string `yaml:"address"`
-		Password string `yaml:"password"`
+		Password string `yaml:"password" secret:"true"`
 		DB       int    `yaml:"db"`
-	}
+	} `reload:"restart"`
 
 	Tips []string `yaml:"tips"`
 }
-
-var Conf Config
-
-func InitialiseConfiguration() {
-	configuration, _ := ioutil.ReadFile("./config.yml")
-	err := yaml.Unmarshal(configuration, &Conf)
-
-	if err != nil {
-		log.Println("Failed to initialise configuration:", err)
-	}
-}
@@ -0,0 +1,259 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// DefaultPath is the configuration file read when no path is given.
+const DefaultPath = "./config.yml"
+
+// EnvPrefix prefixes the environment variables that override the configuration,
+// named after the path of the setting, eg. TF2_BOOKING_REDIS_PASSWORD for 'redis.password'.
+// Secrets can also be read from the file named by the variable suffixed with _FILE.
+const EnvPrefix = "TF2_BOOKING_"
+
+// Errors are the problems found in a configuration.
+type Errors []error
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+
+	return "invalid configuration:\n  " + strings.Join(messages, "\n  ")
+}
+
+// moved are the settings that have moved, for more helpful errors with older configurations.
+var moved = map[string]string{
+	"booking.tag":         "booking_api.tag",
+	"booking.api_address": "booking_api.api_address",
+	"booking.api_port":    "booking_api.api_port",
+	"booking.api_key":     "booking_api.api_key",
+	"discord.debug":       "logging.discord_debug",
+}
+
+// Load reads the configuration file, overriding its settings with any environment variables & applying the defaults.
+// It doesn't replace the current configuration, or check the settings are valid.
+// Configuration files that every user can read are refused, as they contain secrets.
+func Load(path string) (*Config, error) {
+	if err := checkPermissions(path); err != nil {
+		return nil, err
+	}
+
+	configuration, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read configuration: %s", err)
+	}
+
+	conf := defaultConfig()
+	if err := yaml.Unmarshal(configuration, conf); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %s", path, err)
+	}
+
+	// Unmarshal the file again without the Config type, to find the keys that Config doesn't have.
+	var document interface{}
+	if err := yaml.Unmarshal(configuration, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %s", path, err)
+	}
+
+	errs := unknownKeys(reflect.TypeOf(conf).Elem(), document, "")
+	errs = append(errs, readSecretFiles(reflect.ValueOf(conf).Elem(), document, "")...)
+	errs = append(errs, applyEnv(reflect.ValueOf(conf).Elem(), "", os.LookupEnv)...)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	conf.applyDefaults()
+
+	return conf, nil
+}
+
+// InitialiseConfiguration loads & validates the configuration file,
+// replacing the current configuration only if it's valid.
+func InitialiseConfiguration(path string) error {
+	conf, err := Load(path)
+	if err != nil {
+		return err
+	}
+
+	if errs := conf.Validate(); len(errs) > 0 {
+		return Errors(errs)
+	}
+
+	Set(conf)
+	return nil
+}
+
+// UnmarshalStrict unmarshals the YAML document into v, returning an error for each key that v doesn't have.
+func UnmarshalStrict(data []byte, v interface{}) error {
+	if err := yaml.Unmarshal(data, v); err != nil {
+		return err
+	}
+
+	var document interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return err
+	}
+
+	if errs := unknownKeys(reflect.TypeOf(v).Elem(), document, ""); len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+var unmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+
+// key returns the YAML key of the struct field, empty if the field isn't unmarshalled.
+func key(field reflect.StructField) string {
+	if field.PkgPath != "" {
+		return ""
+	}
+
+	tag := strings.Split(field.Tag.Get("yaml"), ",")[0]
+	switch tag {
+	case "-":
+		return ""
+	case "":
+		return strings.ToLower(field.Name)
+	}
+
+	return tag
+}
+
+// unknownKeys returns an error for each key of the document that the type doesn't have.
+func unknownKeys(t reflect.Type, document interface{}, path string) Errors {
+	if reflect.PtrTo(t).Implements(unmarshalerType) {
+		return nil
+	}
+
+	var errs Errors
+	switch t.Kind() {
+	case reflect.Struct:
+		values, ok := document.(map[interface{}]interface{})
+		if !ok {
+			return nil
+		}
+
+		fields := make(map[string]reflect.Type)
+		for i := 0; i < t.NumField(); i++ {
+			if k := key(t.Field(i)); k != "" {
+				fields[k] = t.Field(i).Type
+				if isSecret(t.Field(i)) {
+					fields[k+SecretFileSuffix] = t.Field(i).Type
+				}
+			}
+		}
+
+		keys := make([]string, 0, len(values))
+		for k := range values {
+			keys = append(keys, fmt.Sprint(k))
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			fieldType, ok := fields[k]
+			if !ok {
+				if to, ok := moved[join(path, k)]; ok {
+					errs = append(errs, fmt.Errorf("%s has moved to %s", join(path, k), to))
+				} else {
+					errs = append(errs, fmt.Errorf("unknown setting %s", join(path, k)))
+				}
+				continue
+			}
+
+			errs = append(errs, unknownKeys(fieldType, values[k], join(path, k))...)
+		}
+
+	case reflect.Slice:
+		items, _ := document.([]interface{})
+		for i, item := range items {
+			errs = append(errs, unknownKeys(t.Elem(), item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+
+	case reflect.Map:
+		values, _ := document.(map[interface{}]interface{})
+		for k, value := range values {
+			errs = append(errs, unknownKeys(t.Elem(), value, join(path, fmt.Sprint(k)))...)
+		}
+	}
+
+	return errs
+}
+
+func join(path string, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+// applyEnv overrides the settings of the struct with the environment variables named after their paths.
+// Settings that aren't strings are parsed as YAML, eg. TF2_BOOKING_TIPS='["tip one", "tip two"]'.
+func applyEnv(v reflect.Value, path string, lookup func(string) (string, bool)) Errors {
+	var errs Errors
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		k := key(field)
+		if k == "" {
+			continue
+		}
+
+		fieldPath := join(path, k)
+		value := v.Field(i)
+
+		if value.Kind() == reflect.Struct && !value.Addr().Type().Implements(unmarshalerType) {
+			errs = append(errs, applyEnv(value, fieldPath, lookup)...)
+			continue
+		}
+
+		name := EnvName(fieldPath)
+		env, ok := lookup(name)
+
+		if file, fileOk := lookup(name + strings.ToUpper(SecretFileSuffix)); fileOk && isSecret(field) {
+			if ok {
+				errs = append(errs, fmt.Errorf("only one of %s & %s can be set", name, name+strings.ToUpper(SecretFileSuffix)))
+				continue
+			}
+
+			secret, err := readSecret(fieldPath, file)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			env, ok = secret, true
+		}
+
+		if !ok {
+			continue
+		}
+
+		if value.Kind() == reflect.String {
+			value.SetString(env)
+			continue
+		}
+
+		// Replace the setting, rather than merging into maps from the file.
+		value.Set(reflect.Zero(value.Type()))
+		if err := yaml.Unmarshal([]byte(env), value.Addr().Interface()); err != nil {
+			errs = append(errs, fmt.Errorf("%s is invalid for %s: %s", name, fieldPath, err))
+		}
+	}
+
+	return errs
+}
+
+// EnvName returns the name of the environment variable overriding the setting.
+func EnvName(path string) string {
+	return EnvPrefix + strings.ToUpper(strings.Replace(path, ".", "_", -1))
+}
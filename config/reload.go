@@ -0,0 +1,102 @@
+package config
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+var current atomic.Value
+
+// reloadMu serialises reloads, so concurrent reloads can't undo each other's changes.
+var reloadMu sync.Mutex
+
+// Get returns the current configuration.
+// The configuration is replaced as a whole when it's reloaded, so it must not be modified,
+// and settings read from the same configuration are consistent with each other.
+func Get() *Config {
+	conf, _ := current.Load().(*Config)
+	if conf == nil {
+		return &Config{}
+	}
+
+	return conf
+}
+
+// Set replaces the current configuration.
+func Set(conf *Config) {
+	current.Store(conf)
+}
+
+// Change is a setting that differs in the reloaded configuration, with its secrets redacted.
+type Change struct {
+	Setting string
+	Old     string
+	New     string
+
+	// Restart is set for settings that are only read when the bot starts,
+	// which keep their current values until it's restarted.
+	Restart bool
+}
+
+// Reload loads & validates the configuration file, and replaces the current configuration.
+// Settings tagged `reload:"restart"` keep their current values, and are returned as changes that need a restart.
+// The current configuration is kept if the file is invalid.
+func Reload(path string) ([]Change, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	conf, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if errs := conf.Validate(); len(errs) > 0 {
+		return nil, Errors(errs)
+	}
+
+	changes := diff(reflect.ValueOf(Get()).Elem(), reflect.ValueOf(conf).Elem(), "", false)
+
+	Set(conf)
+
+	return changes, nil
+}
+
+// diff returns the settings that changed from the old to the updated struct,
+// restoring the old values of the settings that need a restart.
+func diff(old reflect.Value, updated reflect.Value, path string, restart bool) []Change {
+	var changes []Change
+
+	for i := 0; i < old.NumField(); i++ {
+		field := old.Type().Field(i)
+		k := key(field)
+		if k == "" {
+			continue
+		}
+
+		fieldPath := join(path, k)
+		fieldRestart := restart || field.Tag.Get("reload") == "restart"
+		oldValue, newValue := old.Field(i), updated.Field(i)
+
+		if oldValue.Kind() == reflect.Struct && !oldValue.Addr().Type().Implements(unmarshalerType) {
+			changes = append(changes, diff(oldValue, newValue, fieldPath, fieldRestart)...)
+			continue
+		}
+
+		if reflect.DeepEqual(oldValue.Interface(), newValue.Interface()) {
+			continue
+		}
+
+		changes = append(changes, Change{
+			Setting: fieldPath,
+			Old:     show(field, oldValue),
+			New:     show(field, newValue),
+			Restart: fieldRestart,
+		})
+
+		if fieldRestart {
+			newValue.Set(oldValue)
+		}
+	}
+
+	return changes
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReloadAppliesLiveSettings(t *testing.T) {
+	path := writeConfig(t, validConfig)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	if err := InitialiseConfiguration(path); err != nil {
+		t.Fatalf("Expected the configuration to load, got %s", err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(validConfig+"booking:\n  min_players: 4\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := Reload(path)
+	if err != nil {
+		t.Fatalf("Expected the configuration to reload, got %s", err)
+	}
+
+	expected := Change{Setting: "booking.min_players", Old: "2", New: "4"}
+	if len(changes) != 1 || changes[0] != expected {
+		t.Errorf("Expected %v, got %v", []Change{expected}, changes)
+	}
+	if Get().Booking.MinPlayers != 4 {
+		t.Errorf("Expected the min players to be applied, got %d", Get().Booking.MinPlayers)
+	}
+}
+
+func TestReloadKeepsRestartSettings(t *testing.T) {
+	path := writeConfig(t, validConfig)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	if err := InitialiseConfiguration(path); err != nil {
+		t.Fatalf("Expected the configuration to load, got %s", err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(validConfig+"redis:\n  address: \"redis:6379\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := Reload(path)
+	if err != nil {
+		t.Fatalf("Expected the configuration to reload, got %s", err)
+	}
+
+	expected := Change{Setting: "redis.address", Old: DefaultRedisAddress, New: "redis:6379", Restart: true}
+	if len(changes) != 1 || changes[0] != expected {
+		t.Errorf("Expected %v, got %v", []Change{expected}, changes)
+	}
+	if Get().Redis.Address != DefaultRedisAddress {
+		t.Errorf("Expected the Redis address to be kept until a restart, got %s", Get().Redis.Address)
+	}
+}
+
+func TestReloadInvalidKeepsConfiguration(t *testing.T) {
+	path := writeConfig(t, validConfig)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	if err := InitialiseConfiguration(path); err != nil {
+		t.Fatalf("Expected the configuration to load, got %s", err)
+	}
+	loaded := Get()
+
+	if err := ioutil.WriteFile(path, []byte(validConfig+"booking:\n  min_players: -1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Reload(path); err == nil {
+		t.Fatalf("Expected the invalid configuration to fail")
+	}
+	if Get() != loaded {
+		t.Errorf("Expected the current configuration to be kept")
+	}
+}
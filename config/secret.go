@@ -0,0 +1,151 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// SecretFileSuffix suffixes the settings that read a secret from a file, eg. 'redis.password_file',
+// and their environment variables, eg. TF2_BOOKING_REDIS_PASSWORD_FILE.
+const SecretFileSuffix = "_file"
+
+// Redacted replaces the secrets whenever the configuration is shown.
+const Redacted = "[redacted]"
+
+// isSecret returns whether the struct field is tagged as a secret.
+func isSecret(field reflect.StructField) bool {
+	return field.Tag.Get("secret") == "true" && field.Type.Kind() == reflect.String
+}
+
+// checkPermissions refuses configuration files that every user can read, as they contain secrets.
+func checkPermissions(path string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to read configuration: %s", err)
+	}
+
+	if info.Mode().Perm()&0004 != 0 {
+		return fmt.Errorf("%s is readable by every user (mode %#o), restrict it with 'chmod o-r %s'", path, info.Mode().Perm(), path)
+	}
+
+	return nil
+}
+
+// readSecret reads a secret from a file, without its trailing newline.
+func readSecret(setting string, path string) (string, error) {
+	secret, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %s", setting, err)
+	}
+
+	return strings.TrimRight(string(secret), "\r\n"), nil
+}
+
+// readSecretFiles sets the secrets of the struct that the document references as files, eg. 'token_file'.
+func readSecretFiles(v reflect.Value, document interface{}, path string) Errors {
+	values, ok := document.(map[interface{}]interface{})
+	if !ok {
+		return nil
+	}
+
+	var errs Errors
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		k := key(field)
+		if k == "" {
+			continue
+		}
+
+		fieldPath := join(path, k)
+		value := v.Field(i)
+
+		switch {
+		case isSecret(field):
+			file, ok := values[k+SecretFileSuffix]
+			if !ok {
+				continue
+			}
+			if _, ok := values[k]; ok {
+				errs = append(errs, fmt.Errorf("only one of %s & %s can be set", fieldPath, fieldPath+SecretFileSuffix))
+				continue
+			}
+
+			secret, err := readSecret(fieldPath, fmt.Sprint(file))
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			value.SetString(secret)
+
+		case value.Kind() == reflect.Struct && !value.Addr().Type().Implements(unmarshalerType):
+			errs = append(errs, readSecretFiles(value, values[k], fieldPath)...)
+
+		case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Struct:
+			items, _ := values[k].([]interface{})
+			for j := 0; j < value.Len() && j < len(items); j++ {
+				errs = append(errs, readSecretFiles(value.Index(j), items[j], fmt.Sprintf("%s[%d]", fieldPath, j))...)
+			}
+		}
+	}
+
+	return errs
+}
+
+// Redact returns a copy of the configuration with its secrets replaced, for showing the configuration.
+func (c *Config) Redact() *Config {
+	redacted := *c
+	redact(reflect.ValueOf(&redacted).Elem())
+
+	return &redacted
+}
+
+// redact replaces the secrets of the value, copying any slices rather than modifying the original items.
+func redact(v reflect.Value) {
+	switch {
+	case v.Kind() == reflect.Struct && !v.Addr().Type().Implements(unmarshalerType):
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if key(field) == "" {
+				continue
+			}
+
+			if isSecret(field) {
+				if v.Field(i).String() != "" {
+					v.Field(i).SetString(Redacted)
+				}
+				continue
+			}
+
+			redact(v.Field(i))
+		}
+
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Struct:
+		items := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(items, v)
+		for i := 0; i < items.Len(); i++ {
+			redact(items.Index(i))
+		}
+		v.Set(items)
+	}
+}
+
+// show formats the value of a setting, with its secrets redacted.
+func show(field reflect.StructField, value reflect.Value) string {
+	if isSecret(field) {
+		return Redacted
+	}
+
+	shown := reflect.New(value.Type()).Elem()
+	shown.Set(value)
+	redact(shown)
+
+	return fmt.Sprint(shown.Interface())
+}
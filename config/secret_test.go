@@ -0,0 +1,147 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeSecret(t *testing.T, dir string, name string, secret string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(secret), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadSecretFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	token := writeSecret(t, dir, "token", "token from file\n")
+	key := writeSecret(t, dir, "key", "client key")
+	password := writeSecret(t, dir, "password", "password from env file")
+
+	os.Setenv("TF2_BOOKING_REDIS_PASSWORD_FILE", password)
+	defer os.Unsetenv("TF2_BOOKING_REDIS_PASSWORD_FILE")
+
+	conf, err := load(t, `
+discord:
+  token_file: "`+token+`"
+  default_channel: "channel id"
+api:
+  clients:
+    - name: "website"
+      key_file: "`+key+`"
+`)
+	if err != nil {
+		t.Fatalf("Expected the configuration to load, got %s", err)
+	}
+
+	if conf.Discord.Token != "token from file" {
+		t.Errorf("Expected the token to be read from its file, got %q", conf.Discord.Token)
+	}
+	if conf.API.Clients[0].Key != "client key" {
+		t.Errorf("Expected the client key to be read from its file, got %q", conf.API.Clients[0].Key)
+	}
+	if conf.Redis.Password != "password from env file" {
+		t.Errorf("Expected the Redis password to be read from the environment variable's file, got %q", conf.Redis.Password)
+	}
+}
+
+func TestLoadSecretFileProblems(t *testing.T) {
+	_, err := load(t, validConfig+`
+redis:
+  password: "example"
+  password_file: "/run/secrets/redis"
+database:
+  dsn_file: "does-not-exist"
+booking:
+  tag_file: "tag"
+`)
+
+	errs, ok := err.(Errors)
+	if !ok {
+		t.Fatalf("Expected configuration errors, got %v", err)
+	}
+
+	problems := strings.Join(messages(errs), "\n")
+	for _, expected := range []string{
+		"unknown setting booking.tag_file",
+		"only one of redis.password & redis.password_file can be set",
+		"failed to read database.dsn",
+	} {
+		if !strings.Contains(problems, expected) {
+			t.Errorf("Expected %q in %s", expected, problems)
+		}
+	}
+}
+
+func TestLoadWorldReadable(t *testing.T) {
+	path := writeConfig(t, validConfig)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "readable by every user") {
+		t.Errorf("Expected the world-readable configuration to be refused, got %v", err)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	conf, err := load(t, validConfig+`
+redis:
+  password: "example"
+webhooks:
+  - url: "https://example.com/hooks"
+    secret: "webhook secret"
+`)
+	if err != nil {
+		t.Fatalf("Expected the configuration to load, got %s", err)
+	}
+
+	redacted := conf.Redact()
+
+	if redacted.Discord.Token != Redacted || redacted.Redis.Password != Redacted || redacted.Webhooks[0].Secret != Redacted {
+		t.Errorf("Expected the secrets to be redacted, got %q, %q & %q", redacted.Discord.Token, redacted.Redis.Password, redacted.Webhooks[0].Secret)
+	}
+	if redacted.BookingAPI.Key != "" {
+		t.Errorf("Expected the empty booking API key to stay empty, got %q", redacted.BookingAPI.Key)
+	}
+	if redacted.Webhooks[0].URL != "https://example.com/hooks" {
+		t.Errorf("Expected the webhook URL to be kept, got %q", redacted.Webhooks[0].URL)
+	}
+	if conf.Discord.Token != "token" || conf.Webhooks[0].Secret != "webhook secret" {
+		t.Errorf("Expected the configuration to be unchanged, got %q & %q", conf.Discord.Token, conf.Webhooks[0].Secret)
+	}
+}
+
+func TestReloadRedactsSecrets(t *testing.T) {
+	path := writeConfig(t, validConfig)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	if err := InitialiseConfiguration(path); err != nil {
+		t.Fatalf("Expected the configuration to load, got %s", err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(strings.Replace(validConfig, `token: "token"`, `token: "new token"`, 1)), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := Reload(path)
+	if err != nil {
+		t.Fatalf("Expected the configuration to reload, got %s", err)
+	}
+
+	expected := Change{Setting: "discord.token", Old: Redacted, New: Redacted, Restart: true}
+	if len(changes) != 1 || changes[0] != expected {
+		t.Errorf("Expected %v, got %v", []Change{expected}, changes)
+	}
+}
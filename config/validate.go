@@ -0,0 +1,146 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"alex-j-butler.com/tf2-booking/logging"
+	"github.com/Sirupsen/logrus"
+)
+
+// Defaults of the settings that are used when they're omitted.
+// Settings that can't be zero also use their defaults when they're zero.
+const (
+	DefaultLogLevel       = "info"
+	DefaultLogFormat      = logging.FormatText
+	DefaultLogPort        = 3001
+	DefaultServerPool     = ServerPoolAPI
+	DefaultServerFile     = "./servers.yml"
+	DefaultMaxIdleMinutes = 15
+	DefaultMinPlayers     = 2
+	DefaultErrorThreshold = 5
+	DefaultLocale         = "en"
+	DefaultReportDuration = 4 * time.Minute
+	DefaultRedisAddress   = "localhost:6379"
+)
+
+// Server pools that the servers can be loaded from.
+const (
+	ServerPoolAPI  = "api"
+	ServerPoolFile = "file"
+)
+
+// reconcilePolicies are the valid policies of the 'reconcile' section, empty for the default.
+var reconcilePolicies = map[string]bool{"": true, "fix": true, "report": true, "ignore": true}
+
+// defaultConfig returns the configuration that the file is loaded over, with the defaults of the settings
+// that can be zero, so only the omitted settings use their defaults.
+func defaultConfig() *Config {
+	conf := &Config{}
+	conf.Booking.MaxIdleMinutes = DefaultMaxIdleMinutes
+	conf.Booking.MinPlayers = DefaultMinPlayers
+	conf.Booking.ErrorThreshold = DefaultErrorThreshold
+
+	return conf
+}
+
+// applyDefaults sets the omitted or zero settings that the bot can't run without.
+func (c *Config) applyDefaults() {
+	if c.Logging.Level == "" {
+		c.Logging.Level = DefaultLogLevel
+	}
+	if c.Logging.Format == "" {
+		c.Logging.Format = DefaultLogFormat
+	}
+	if c.LogServer.LogPort == 0 {
+		c.LogServer.LogPort = DefaultLogPort
+	}
+	if c.Servers.Pool == "" {
+		c.Servers.Pool = DefaultServerPool
+	}
+	if c.Servers.File == "" {
+		c.Servers.File = DefaultServerFile
+	}
+	if c.Messages.Locale == "" {
+		c.Messages.Locale = DefaultLocale
+	}
+	if c.Commands.ReportDuration.Duration == 0 {
+		c.Commands.ReportDuration.Duration = DefaultReportDuration
+	}
+	if c.Redis.Address == "" {
+		c.Redis.Address = DefaultRedisAddress
+	}
+}
+
+// Validate checks the settings the bot needs to run, returning every problem found.
+func (c *Config) Validate() []error {
+	var errs []error
+	require := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	require(c.Discord.Token != "", "discord.token is required")
+	require(c.Discord.DefaultChannel != "", "discord.default_channel is required")
+
+	_, err := logrus.ParseLevel(c.Logging.Level)
+	require(err == nil, "logging.level must be \"debug\", \"info\", \"warn\" or \"error\", got \"%s\"", c.Logging.Level)
+	require(c.Logging.Format == logging.FormatText || c.Logging.Format == logging.FormatJSON,
+		"logging.format must be \"text\" or \"json\", got \"%s\"", c.Logging.Format)
+
+	require(c.LogServer.LogPort > 0 && c.LogServer.LogPort <= 65535, "log_server.log_port must be a port number, got %d", c.LogServer.LogPort)
+
+	require(c.Servers.Pool == ServerPoolAPI || c.Servers.Pool == ServerPoolFile, "servers.pool must be \"api\" or \"file\", got \"%s\"", c.Servers.Pool)
+
+	// The booking API is optional for the file pool, which only uses it for servers with the "api" runner.
+	if c.Servers.Pool == ServerPoolAPI || c.BookingAPI.Address != "" {
+		require(c.Servers.Pool != ServerPoolAPI || c.BookingAPI.Tag != "", "booking_api.tag is required")
+		require(c.BookingAPI.Address != "", "booking_api.api_address is required")
+		require(c.BookingAPI.Port > 0 && c.BookingAPI.Port <= 65535, "booking_api.api_port must be a port number, got %d", c.BookingAPI.Port)
+	}
+
+	require(c.Booking.MaxIdleMinutes > 0, "booking.max_idle_minutes must be greater than 0")
+	require(c.Booking.MinPlayers >= 0, "booking.min_players can't be negative")
+	require(c.Booking.ErrorThreshold > 0, "booking.error_threshold must be greater than 0")
+	require(c.Booking.DefaultDuration.Duration >= 0, "booking.default_duration can't be negative")
+	require(c.Booking.MaxDuration.Duration >= 0, "booking.max_duration can't be negative")
+	require(c.Booking.MaxDuration.Duration == 0 || c.Booking.DefaultDuration.Duration <= c.Booking.MaxDuration.Duration,
+		"booking.default_duration can't be longer than booking.max_duration")
+
+	require(reconcilePolicies[c.Reconcile.OrphanedUsers], "reconcile.orphaned_users must be \"fix\", \"report\" or \"ignore\"")
+	require(reconcilePolicies[c.Reconcile.UnbookedServers], "reconcile.unbooked_servers must be \"fix\", \"report\" or \"ignore\"")
+	require(reconcilePolicies[c.Reconcile.StoppedBookings], "reconcile.stopped_bookings must be \"fix\", \"report\" or \"ignore\"")
+	require(c.Reconcile.GracePeriod.Duration >= 0, "reconcile.grace_period can't be negative")
+
+	for i, group := range c.Permissions {
+		require(group.Name != "", "permissions[%d].name is required", i)
+	}
+
+	require(c.RateLimits.Default.Requests >= 0, "rate_limits.default.requests can't be negative")
+	for command, limit := range c.RateLimits.Commands {
+		require(limit.Requests >= 0, "rate_limits.commands.%s.requests can't be negative", command)
+	}
+
+	require(!c.API.Enabled || c.HTTP.Address != "", "http.address is required when the API is enabled")
+	for i, client := range c.API.Clients {
+		require(client.Key != "", "api.clients[%d].key is required", i)
+		require(client.Admin || client.UserID != "", "api.clients[%d].user_id is required unless the client is an admin", i)
+	}
+
+	require(!c.Dashboard.Enabled || c.HTTP.Address != "", "http.address is required when the dashboard is enabled")
+	require(!c.Dashboard.Enabled || len(c.Dashboard.Admins) > 0, "dashboard.admins is required when the dashboard is enabled")
+	for i, admin := range c.Dashboard.Admins {
+		require(admin.Username != "" && admin.Password != "", "dashboard.admins[%d] requires a username & password", i)
+	}
+
+	for i, webhook := range c.Webhooks {
+		u, err := url.Parse(webhook.URL)
+		require(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "webhooks[%d].url must be an HTTP URL", i)
+	}
+
+	require(c.Redis.DB >= 0, "redis.db can't be negative")
+
+	return errs
+}
@@ -0,0 +1,273 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const validConfig = `
+discord:
+  token: "token"
+  default_channel: "channel id"
+booking_api:
+  tag: "bookable"
+  api_address: "127.0.0.1"
+  api_port: 9902
+`
+
+func writeConfig(t *testing.T, contents string) string {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "config.yml")
+	if err := ioutil.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func load(t *testing.T, contents string) (*Config, error) {
+	path := writeConfig(t, contents)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	return Load(path)
+}
+
+func messages(errs []error) []string {
+	var messages []string
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	return messages
+}
+
+func TestLoadValid(t *testing.T) {
+	conf, err := load(t, validConfig)
+	if err != nil {
+		t.Fatalf("Expected the configuration to load, got %s", err)
+	}
+	if errs := conf.Validate(); len(errs) != 0 {
+		t.Errorf("Expected the configuration to be valid, got %v", errs)
+	}
+}
+
+func TestLoadAppliesDefaults(t *testing.T) {
+	conf, err := load(t, validConfig+`
+booking:
+  min_players: 4
+`)
+	if err != nil {
+		t.Fatalf("Expected the configuration to load, got %s", err)
+	}
+
+	if conf.Booking.MaxIdleMinutes != DefaultMaxIdleMinutes {
+		t.Errorf("Expected the default max idle minutes, got %d", conf.Booking.MaxIdleMinutes)
+	}
+	if conf.Booking.MinPlayers != 4 {
+		t.Errorf("Expected the configured min players, got %d", conf.Booking.MinPlayers)
+	}
+	if conf.Commands.ReportDuration.Duration != DefaultReportDuration {
+		t.Errorf("Expected the default report duration, got %s", conf.Commands.ReportDuration)
+	}
+	if conf.Redis.Address != DefaultRedisAddress {
+		t.Errorf("Expected the default Redis address, got %s", conf.Redis.Address)
+	}
+}
+
+func TestLoadKeepsExplicitZeros(t *testing.T) {
+	conf, err := load(t, validConfig+`
+booking:
+  min_players: 0
+  max_idle_minutes: 0
+  error_threshold: 0
+`)
+	if err != nil {
+		t.Fatalf("Expected the configuration to load, got %s", err)
+	}
+
+	if conf.Booking.MinPlayers != 0 || conf.Booking.MaxIdleMinutes != 0 || conf.Booking.ErrorThreshold != 0 {
+		t.Errorf("Expected the configured zeros, got %+v", conf.Booking)
+	}
+
+	// The zeros that aren't valid are reported, rather than replaced with the defaults.
+	expected := []string{
+		"booking.max_idle_minutes must be greater than 0",
+		"booking.error_threshold must be greater than 0",
+	}
+	if problems := messages(conf.Validate()); !reflect.DeepEqual(problems, expected) {
+		t.Errorf("Expected %v, got %v", expected, problems)
+	}
+}
+
+func TestLoadUnknownKeys(t *testing.T) {
+	_, err := load(t, validConfig+`
+booking:
+  tag: "bookable"
+  max_idle_minuets: 15
+redis:
+  adress: "localhost:6379"
+api:
+  clients:
+    - name: "website"
+      secret: "example"
+`)
+
+	errs, ok := err.(Errors)
+	if !ok {
+		t.Fatalf("Expected configuration errors, got %v", err)
+	}
+
+	expected := []string{
+		"unknown setting api.clients[0].secret",
+		"unknown setting booking.max_idle_minuets",
+		"booking.tag has moved to booking_api.tag",
+		"unknown setting redis.adress",
+	}
+	if !reflect.DeepEqual(messages(errs), expected) {
+		t.Errorf("Expected %v, got %v", expected, messages(errs))
+	}
+}
+
+func TestLoadEnvOverrides(t *testing.T) {
+	env := map[string]string{
+		"TF2_BOOKING_REDIS_PASSWORD":               "from env",
+		"TF2_BOOKING_BOOKING_MAX_IDLE_MINUTES":     "30",
+		"TF2_BOOKING_BOOKING_DEFAULT_DURATION":     "2h",
+		"TF2_BOOKING_DISCORD_NOTIFICATION_USERS":   `["1", "2"]`,
+		"TF2_BOOKING_BOOKING_PRESETS":              `{"ultiduo": "exec ultiduo"}`,
+		"TF2_BOOKING_RATE_LIMITS_DEFAULT_REQUESTS": "3",
+	}
+	for name, value := range env {
+		os.Setenv(name, value)
+		defer os.Unsetenv(name)
+	}
+
+	conf, err := load(t, validConfig+`
+booking:
+  presets:
+    6v6: "exec etf2l_6v6_5cp"
+`)
+	if err != nil {
+		t.Fatalf("Expected the configuration to load, got %s", err)
+	}
+
+	if conf.Redis.Password != "from env" {
+		t.Errorf("Expected the Redis password to be overridden, got %q", conf.Redis.Password)
+	}
+	if conf.Booking.MaxIdleMinutes != 30 {
+		t.Errorf("Expected the max idle minutes to be overridden, got %d", conf.Booking.MaxIdleMinutes)
+	}
+	if conf.Booking.DefaultDuration.Duration != 2*time.Hour {
+		t.Errorf("Expected the default duration to be overridden, got %s", conf.Booking.DefaultDuration)
+	}
+	if !reflect.DeepEqual(conf.Discord.NotificationUsers, []string{"1", "2"}) {
+		t.Errorf("Expected the notification users to be overridden, got %v", conf.Discord.NotificationUsers)
+	}
+	if !reflect.DeepEqual(conf.Booking.Presets, map[string]string{"ultiduo": "exec ultiduo"}) {
+		t.Errorf("Expected the presets to be replaced, got %v", conf.Booking.Presets)
+	}
+	if conf.RateLimits.Default.Requests != 3 {
+		t.Errorf("Expected the default rate limit to be overridden, got %d", conf.RateLimits.Default.Requests)
+	}
+}
+
+func TestLoadInvalidEnv(t *testing.T) {
+	os.Setenv("TF2_BOOKING_BOOKING_MIN_PLAYERS", "lots")
+	defer os.Unsetenv("TF2_BOOKING_BOOKING_MIN_PLAYERS")
+
+	_, err := load(t, validConfig)
+	if err == nil || !strings.Contains(err.Error(), "TF2_BOOKING_BOOKING_MIN_PLAYERS is invalid for booking.min_players") {
+		t.Errorf("Expected the invalid environment variable to be reported, got %v", err)
+	}
+}
+
+func TestValidateInvalidFields(t *testing.T) {
+	conf, err := load(t, `
+discord:
+  token: "token"
+logging:
+  level: "loud"
+booking_api:
+  tag: "bookable"
+  api_port: 99999
+booking:
+  default_duration: "6h"
+  max_duration: "3h"
+reconcile:
+  orphaned_users: "delete"
+api:
+  enabled: true
+  clients:
+    - name: "website"
+webhooks:
+  - url: "example.com/hooks"
+`)
+	if err != nil {
+		t.Fatalf("Expected the configuration to load, got %s", err)
+	}
+
+	expected := []string{
+		"discord.default_channel is required",
+		"logging.level must be \"debug\", \"info\", \"warn\" or \"error\", got \"loud\"",
+		"booking_api.api_address is required",
+		"booking_api.api_port must be a port number, got 99999",
+		"booking.default_duration can't be longer than booking.max_duration",
+		"reconcile.orphaned_users must be \"fix\", \"report\" or \"ignore\"",
+		"http.address is required when the API is enabled",
+		"api.clients[0].key is required",
+		"api.clients[0].user_id is required unless the client is an admin",
+		"webhooks[0].url must be an HTTP URL",
+	}
+	if problems := messages(conf.Validate()); !reflect.DeepEqual(problems, expected) {
+		t.Errorf("Expected %v, got %v", expected, problems)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	if _, err := Load("does-not-exist.yml"); err == nil {
+		t.Errorf("Expected an error loading a missing file")
+	}
+}
+
+func TestInitialiseConfigurationKeepsValidConfiguration(t *testing.T) {
+	path := writeConfig(t, "discord:\n  token: \"token\"\n")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	current := &Config{}
+	current.Discord.Token = "current"
+	Set(current)
+
+	if err := InitialiseConfiguration(path); err == nil {
+		t.Fatalf("Expected the invalid configuration to fail")
+	}
+	if Get().Discord.Token != "current" {
+		t.Errorf("Expected the current configuration to be kept, got %q", Get().Discord.Token)
+	}
+}
+
+func TestValidateFilePoolWithoutBookingAPI(t *testing.T) {
+	conf, err := load(t, `
+discord:
+  token: "token"
+  default_channel: "channel id"
+servers:
+  pool: "file"
+`)
+	if err != nil {
+		t.Fatalf("Expected the configuration to load, got %s", err)
+	}
+
+	if errs := conf.Validate(); len(errs) != 0 {
+		t.Errorf("Expected the booking API to be optional for the file pool, got %v", errs)
+	}
+	if conf.Servers.File != DefaultServerFile {
+		t.Errorf("Expected the default server file, got %s", conf.Servers.File)
+	}
+}
@@ -1,13 +1,11 @@
 package main
 
 import (
-	"fmt"
-	"log"
-
 	"alex-j-butler.com/tf2-booking/config"
-	"alex-j-butler.com/tf2-booking/globals"
+	"alex-j-butler.com/tf2-booking/events"
 	"alex-j-butler.com/tf2-booking/servers"
 
+	log "github.com/Sirupsen/logrus"
 	"github.com/kidoman/go-steam"
 )
 
@@ -15,9 +13,14 @@ func CheckIdleMinutes() {
 	// Iterate through servers.
 	for _, Serv := range pool.GetBookedServers() {
 		go func(s *servers.Server) {
+			if s.Expired() {
+				AutoUnbook(s, events.Expired)
+				return
+			}
+
 			server, err := steam.Connect(s.Address)
 			if err != nil {
-				log.Println(fmt.Sprintf("Failed to connect to server \"%s\":", s.Name), err)
+				s.Log().WithError(err).Warn("Failed to connect to server")
 
 				HandleQueryError(s, err)
 
@@ -28,14 +31,14 @@ func CheckIdleMinutes() {
 
 			info, err := server.Info()
 			if err != nil {
-				log.Println(fmt.Sprintf("Failed to query server \"%s\":", s.Name), err)
+				s.Log().WithError(err).Warn("Failed to query server")
 
 				HandleQueryError(s, err)
 
 				return
 			}
 
-			if info.Players < config.Conf.Booking.MinPlayers {
+			if info.Players < config.Get().Booking.MinPlayers {
 				s.AddIdleMinute()
 			} else {
 				// Reset the number of idle minutes, and allow the timeout warning message to be sent again.
@@ -43,46 +46,37 @@ func CheckIdleMinutes() {
 				s.ResetIdleMinutes()
 			}
 
-			if s.IdleMinutes >= config.Conf.Booking.MaxIdleMinutes {
-				UserID := s.Booker
-				UserMention := s.BookerMention
-
-				// Reset the idle minutes.
-				s.ResetIdleMinutes()
-
-				// Remove the user's booked state.
-				if err := globals.RedisClient.Set(fmt.Sprintf("user.%s", UserID), "", 0).Err(); err != nil {
-					log.Println("Redis error:", err)
-					log.Println("Failed to set user information for user:", UserID)
-					return
-				}
-
-				// Unbook the server.
-				s.Unbook()
-				s.Stop()
-
-				// Upload STV demos
-				STVMessage, err := s.UploadSTV()
-
-				// Send 'returned' message
-				Session.ChannelMessageSend(config.Conf.Discord.DefaultChannel, fmt.Sprintf("%s: Your server was automatically unbooked (not enough players).", UserMention))
-
-				// Send 'stv' message, if it uploaded successfully.
-				if err == nil {
-					Session.ChannelMessageSend(config.Conf.Discord.DefaultChannel, fmt.Sprintf("%s: %s", UserMention, STVMessage))
-				}
-
-				UpdateGameString()
-
-				log.Println(fmt.Sprintf("Automatically unbooked server \"%s\" from \"%s\", Reason: Idle timeout from too little players", s.Name, UserID))
+			if s.IdleMinutes >= config.Get().Booking.MaxIdleMinutes {
+				AutoUnbook(s, events.Idle)
 			}
 		}(Serv)
 	}
 }
 
 func Cron1Minute() {
+	// Check the dependencies before the game string is updated, so it shows any that are failing.
+	Health.Run()
+
 	err := UpdateGameString()
 	if err != nil {
-		log.Println("Failed to update game string:", err)
+		log.WithError(err).Error("Failed to update game string")
+	}
+
+	err = UpdateStatusBoard()
+	if err != nil {
+		log.WithError(err).Error("Failed to update status board")
 	}
 }
+
+// AutoUnbook unbooks a server without the booker's request.
+// The booker is notified by the BookingEnded subscribers.
+func AutoUnbook(s *servers.Server, reason events.EndReason) {
+	// Log with the booking's fields, which are cleared by releasing the server.
+	entry := s.Log()
+
+	if err := Bookings.Release(s, reason); err != nil {
+		return
+	}
+
+	entry.WithField("reason", reason).Info("Automatically unbooked server")
+}
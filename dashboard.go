@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"alex-j-butler.com/tf2-booking/audit"
+	"alex-j-butler.com/tf2-booking/config"
+	"alex-j-butler.com/tf2-booking/dashboard"
+	"alex-j-butler.com/tf2-booking/globals"
+	"alex-j-butler.com/tf2-booking/servers"
+)
+
+// RecentEvents keeps the recent booking events shown on the admin dashboard.
+var RecentEvents = audit.NewRecent(audit.DefaultRecent)
+
+// DashboardHandler returns the admin dashboard handler, going through the same server pool & booking manager as the Discord commands.
+func DashboardHandler() http.Handler {
+	admins := make([]dashboard.Admin, len(config.Get().Dashboard.Admins))
+	for i, admin := range config.Get().Dashboard.Admins {
+		admins[i] = dashboard.Admin{Username: admin.Username, Password: admin.Password}
+	}
+
+	d := dashboard.New(dashboardServers{pool}, Bookings, admins, config.Get().Dashboard.SessionSecret)
+	d.Recent = RecentEvents
+	d.Audit = &Audit
+
+	return d.Handler()
+}
+
+// dashboardServers performs the dashboard's admin actions on the servers of the pool.
+type dashboardServers struct {
+	servers.ServerPool
+}
+
+func (dashboardServers) Status(s *servers.Server) dashboard.Status {
+	status := queryServerStatus(s)
+
+	return dashboard.Status{
+		State:   AdminText(status.State, nil),
+		Class:   strings.TrimPrefix(status.State, "status_board."),
+		Map:     status.Map,
+		Players: status.Players,
+	}
+}
+
+func (dashboardServers) Console(s *servers.Server) ([]string, error) {
+	return s.Console()
+}
+
+// Restart restarts a booked server, unbooked servers are left stopped.
+func (dashboardServers) Restart(s *servers.Server) error {
+	if !s.Booked {
+		return errors.New("server isn't booked")
+	}
+
+	if err := s.Stop(); err != nil {
+		return err
+	}
+
+	return s.Start()
+}
+
+func (dashboardServers) SetMaintenance(s *servers.Server, maintenance bool) error {
+	s.SetMaintenance(maintenance)
+
+	return s.Update(globals.RedisClient)
+}
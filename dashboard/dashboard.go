@@ -0,0 +1,344 @@
+package dashboard
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"alex-j-butler.com/tf2-booking/audit"
+	"alex-j-butler.com/tf2-booking/events"
+	"alex-j-butler.com/tf2-booking/servers"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/mux"
+)
+
+// DefaultPrefix is the path the dashboard is served under.
+const DefaultPrefix = "/dashboard"
+
+// Admin is a login of the dashboard.
+type Admin struct {
+	Username string
+	Password string
+}
+
+// Status is the live state of a server.
+type Status struct {
+	// State is the state shown to the admin, and Class the unlocalised state used for styling it.
+	State   string
+	Class   string
+	Map     string
+	Players string
+}
+
+// Servers are the servers of the pool, and the admin actions taken on them.
+type Servers interface {
+	GetServers() []*servers.Server
+	GetServerByName(name string) (*servers.Server, error)
+
+	// Status queries the live state of the server.
+	Status(server *servers.Server) Status
+
+	// Console returns the latest console lines of the server.
+	Console(server *servers.Server) ([]string, error)
+
+	// Restart stops & starts the server.
+	Restart(server *servers.Server) error
+
+	// SetMaintenance takes the server out of, or returns it to, the pool of bookable servers.
+	SetMaintenance(server *servers.Server, maintenance bool) error
+}
+
+// Bookings force unbooks servers, the same as the 'unbook' command.
+type Bookings interface {
+	Release(server *servers.Server, reason events.EndReason) error
+}
+
+// Dashboard is a web dashboard for admins to manage the servers.
+type Dashboard struct {
+	Servers  Servers
+	Bookings Bookings
+	Admins   []Admin
+
+	// Recent is the audit trail shown on the dashboard, nil to hide it.
+	Recent *audit.Recent
+
+	// Audit records the actions taken on the dashboard, if not nil.
+	Audit audit.Recorder
+
+	// Secret signs the session cookies.
+	Secret []byte
+
+	// Prefix is the path the dashboard is served under.
+	Prefix string
+
+	SessionLength time.Duration
+
+	// revoked are the sessions that have logged out, by their signature, until they expire.
+	revoked   map[string]time.Time
+	revokedMu sync.Mutex
+}
+
+// New creates a dashboard, signing sessions with a random secret if the secret is empty.
+func New(servers Servers, bookings Bookings, admins []Admin, secret string) *Dashboard {
+	d := &Dashboard{
+		Servers:       servers,
+		Bookings:      bookings,
+		Admins:        admins,
+		Secret:        []byte(secret),
+		Prefix:        DefaultPrefix,
+		SessionLength: DefaultSessionLength,
+		revoked:       make(map[string]time.Time),
+	}
+
+	if secret == "" {
+		d.Secret = make([]byte, 32)
+		if _, err := rand.Read(d.Secret); err != nil {
+			log.WithError(err).Fatal("Failed to generate dashboard session secret")
+		}
+	}
+
+	return d
+}
+
+// Handler returns the HTTP handler of the dashboard pages, under the prefix.
+func (d *Dashboard) Handler() http.Handler {
+	r := mux.NewRouter().PathPrefix(d.Prefix).Subrouter()
+
+	r.HandleFunc("/login", d.loginPage).Methods(http.MethodGet)
+	r.HandleFunc("/login", d.loginSubmit).Methods(http.MethodPost)
+	r.HandleFunc("/logout", d.authenticated(d.logout)).Methods(http.MethodPost)
+
+	r.HandleFunc("/", d.authenticated(d.index)).Methods(http.MethodGet)
+	r.HandleFunc("/servers/{name}", d.authenticated(d.server(d.serverPage))).Methods(http.MethodGet)
+	r.HandleFunc("/servers/{name}/unbook", d.authenticated(d.server(d.unbook))).Methods(http.MethodPost)
+	r.HandleFunc("/servers/{name}/restart", d.authenticated(d.server(d.restart))).Methods(http.MethodPost)
+	r.HandleFunc("/servers/{name}/maintenance", d.authenticated(d.server(d.maintenance))).Methods(http.MethodPost)
+
+	return r
+}
+
+// sessionHandlerFunc is a handler of a logged in admin's request.
+type sessionHandlerFunc func(w http.ResponseWriter, r *http.Request, s *session)
+
+// authenticated redirects requests that aren't logged in to the login page,
+// and rejects forms that weren't submitted from the dashboard.
+func (d *Dashboard) authenticated(handler sessionHandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s := d.session(r)
+		if s == nil {
+			if r.Method != http.MethodGet {
+				http.Error(w, "Not logged in", http.StatusUnauthorized)
+				return
+			}
+
+			http.Redirect(w, r, d.Prefix+"/login", http.StatusSeeOther)
+			return
+		}
+
+		if r.Method == http.MethodPost && subtle.ConstantTimeCompare([]byte(r.PostFormValue("token")), []byte(s.Token)) != 1 {
+			http.Error(w, "Invalid form token", http.StatusForbidden)
+			return
+		}
+
+		handler(w, r, s)
+	}
+}
+
+// serverHandlerFunc is a handler of a logged in admin's request for a server.
+type serverHandlerFunc func(w http.ResponseWriter, r *http.Request, s *session, server *servers.Server)
+
+// server finds the server named in the path.
+func (d *Dashboard) server(handler serverHandlerFunc) sessionHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, s *session) {
+		server, err := d.Servers.GetServerByName(mux.Vars(r)["name"])
+		if err != nil || server == nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		handler(w, r, s, server)
+	}
+}
+
+func (d *Dashboard) loginPage(w http.ResponseWriter, r *http.Request) {
+	d.render(w, http.StatusOK, loginTemplate, page{Prefix: d.Prefix})
+}
+
+func (d *Dashboard) loginSubmit(w http.ResponseWriter, r *http.Request) {
+	admin := d.login(r.PostFormValue("username"), r.PostFormValue("password"))
+	if admin == nil {
+		log.WithFields(log.Fields{"username": r.PostFormValue("username"), "remote_addr": r.RemoteAddr}).Warn("Failed dashboard login")
+		d.render(w, http.StatusUnauthorized, loginTemplate, page{Prefix: d.Prefix, Error: "Invalid username or password."})
+		return
+	}
+
+	log.WithFields(log.Fields{"username": admin.Username, "remote_addr": r.RemoteAddr}).Info("Dashboard login")
+
+	d.setSession(w, admin)
+	http.Redirect(w, r, d.Prefix+"/", http.StatusSeeOther)
+}
+
+func (d *Dashboard) logout(w http.ResponseWriter, r *http.Request, s *session) {
+	d.revoke(s)
+	d.clearSession(w)
+	http.Redirect(w, r, d.Prefix+"/login", http.StatusSeeOther)
+}
+
+// page is the data of every page template.
+type page struct {
+	Prefix  string
+	Session *session
+	Notice  string
+	Error   string
+}
+
+// serverRow is a server in the server list.
+type serverRow struct {
+	Server *servers.Server
+	Status Status
+}
+
+// notices are the messages shown after an action, by the 'done' query parameter.
+var notices = map[string]string{
+	"unbooked":        "The server was force unbooked.",
+	"restarting":      "The server is restarting.",
+	"maintenance_on":  "The server is in maintenance, and can't be booked.",
+	"maintenance_off": "The server is out of maintenance, and can be booked.",
+}
+
+func (d *Dashboard) newPage(r *http.Request, s *session) page {
+	return page{
+		Prefix:  d.Prefix,
+		Session: s,
+		Notice:  notices[r.URL.Query().Get("done")],
+	}
+}
+
+func (d *Dashboard) index(w http.ResponseWriter, r *http.Request, s *session) {
+	pool := d.Servers.GetServers()
+
+	// Query every server at once, as each query can take a while for servers that are down.
+	rows := make([]serverRow, len(pool))
+	var wg sync.WaitGroup
+	for i, server := range pool {
+		wg.Add(1)
+		go func(i int, server *servers.Server) {
+			defer wg.Done()
+			rows[i] = serverRow{Server: server, Status: d.Servers.Status(server)}
+		}(i, server)
+	}
+	wg.Wait()
+
+	d.render(w, http.StatusOK, indexTemplate, struct {
+		page
+		Servers []serverRow
+		Events  []audit.Event
+	}{d.newPage(r, s), rows, d.Recent.Events()})
+}
+
+func (d *Dashboard) serverPage(w http.ResponseWriter, r *http.Request, s *session, server *servers.Server) {
+	data := struct {
+		page
+		Server       serverRow
+		Console      []string
+		ConsoleError string
+	}{page: d.newPage(r, s), Server: serverRow{Server: server, Status: d.Servers.Status(server)}}
+
+	console, err := d.Servers.Console(server)
+	if err != nil {
+		server.Log().WithError(err).Warn("Failed to retrieve server console")
+		data.ConsoleError = "Failed to retrieve the console output."
+	}
+	data.Console = console
+
+	d.render(w, http.StatusOK, serverTemplate, data)
+}
+
+func (d *Dashboard) unbook(w http.ResponseWriter, r *http.Request, s *session, server *servers.Server) {
+	if !server.Booked {
+		http.Error(w, "The server isn't booked", http.StatusConflict)
+		return
+	}
+
+	booker := server.BookerFullname
+	if err := d.Bookings.Release(server, events.Forced); err != nil {
+		server.Log().WithError(err).Error("Failed to force unbook server")
+		http.Error(w, "Failed to unbook the server", http.StatusInternalServerError)
+		return
+	}
+
+	d.record(s, server, fmt.Sprintf("Force unbooked the server booked by %s from the dashboard", booker))
+	d.redirect(w, r, server, "unbooked")
+}
+
+func (d *Dashboard) restart(w http.ResponseWriter, r *http.Request, s *session, server *servers.Server) {
+	// Unbooked servers are stopped, and only started when they're booked.
+	if !server.Booked {
+		http.Error(w, "The server isn't booked", http.StatusConflict)
+		return
+	}
+
+	// Restarting can take a while, so it's done in the background.
+	go func() {
+		if err := d.Servers.Restart(server); err != nil {
+			server.Log().WithError(err).Error("Failed to restart server")
+		}
+	}()
+
+	d.record(s, server, "Restarted the server from the dashboard")
+	d.redirect(w, r, server, "restarting")
+}
+
+func (d *Dashboard) maintenance(w http.ResponseWriter, r *http.Request, s *session, server *servers.Server) {
+	maintenance := r.PostFormValue("maintenance") == "on"
+
+	if err := d.Servers.SetMaintenance(server, maintenance); err != nil {
+		server.Log().WithError(err).Error("Failed to set server maintenance")
+		http.Error(w, "Failed to update the server", http.StatusInternalServerError)
+		return
+	}
+
+	if maintenance {
+		d.record(s, server, "Put the server into maintenance from the dashboard")
+		d.redirect(w, r, server, "maintenance_on")
+	} else {
+		d.record(s, server, "Took the server out of maintenance from the dashboard")
+		d.redirect(w, r, server, "maintenance_off")
+	}
+}
+
+// record records an admin action taken on the server.
+func (d *Dashboard) record(s *session, server *servers.Server, reason string) {
+	server.Log().WithFields(log.Fields{"username": s.Username, "action": reason}).Info("Dashboard admin action")
+
+	if d.Audit == nil {
+		return
+	}
+
+	d.Audit.Log(audit.Event{
+		Type:     audit.AdminAction,
+		Username: s.Username,
+		Server:   server.Name,
+		Reason:   reason,
+	})
+}
+
+// redirect redirects back to the server's page after an action, showing the notice.
+func (d *Dashboard) redirect(w http.ResponseWriter, r *http.Request, server *servers.Server, notice string) {
+	http.Redirect(w, r, fmt.Sprintf("%s/servers/%s?done=%s", d.Prefix, url.PathEscape(server.Name), notice), http.StatusSeeOther)
+}
+
+func (d *Dashboard) render(w http.ResponseWriter, status int, tmpl *template.Template, data interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+
+	if err := tmpl.Execute(w, data); err != nil {
+		log.WithError(err).Error("Failed to render dashboard page")
+	}
+}
@@ -0,0 +1,272 @@
+package dashboard
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"alex-j-butler.com/tf2-booking/audit"
+	"alex-j-butler.com/tf2-booking/events"
+	"alex-j-butler.com/tf2-booking/servers"
+)
+
+// pool is a pool of servers that records the admin actions taken on them.
+type pool struct {
+	servers   []*servers.Server
+	restarted chan string
+}
+
+func (p *pool) GetServers() []*servers.Server {
+	return p.servers
+}
+
+func (p *pool) GetServerByName(name string) (*servers.Server, error) {
+	for _, server := range p.servers {
+		if server.Name == name {
+			return server, nil
+		}
+	}
+	return nil, errors.New("server not found")
+}
+
+func (p *pool) Status(server *servers.Server) Status {
+	if server.Booked {
+		return Status{State: "Booked", Class: "booked", Map: "cp_process_final", Players: "12/24"}
+	}
+	return Status{State: "Available", Class: "available", Map: "-", Players: "-"}
+}
+
+func (p *pool) Console(server *servers.Server) ([]string, error) {
+	return []string{"L 01/01/2017 - 00:00:00: \"<script>\" say \"hello\""}, nil
+}
+
+func (p *pool) Restart(server *servers.Server) error {
+	p.restarted <- server.Name
+	return nil
+}
+
+func (p *pool) SetMaintenance(server *servers.Server, maintenance bool) error {
+	server.SetMaintenance(maintenance)
+	return nil
+}
+
+type bookings struct {
+	released []string
+}
+
+func (b *bookings) Release(server *servers.Server, reason events.EndReason) error {
+	b.released = append(b.released, server.Name)
+	server.Booked = false
+	return nil
+}
+
+func newDashboard() (*Dashboard, *pool, *bookings) {
+	p := &pool{
+		servers: []*servers.Server{
+			{Name: "Server 1", Address: "127.0.0.1:27015", Booked: true, Booker: "1", BookerFullname: "Booker#0001"},
+			{Name: "Server 2", Address: "127.0.0.1:27025"},
+		},
+		restarted: make(chan string, 1),
+	}
+	b := &bookings{}
+
+	d := New(p, b, []Admin{{Username: "admin", Password: "password"}}, "secret")
+	d.Recent = audit.NewRecent(10)
+	d.Audit = d.Recent
+
+	return d, p, b
+}
+
+// login logs in as the admin, returning the session cookie & form token.
+func login(t *testing.T, d *Dashboard, h http.Handler) (*http.Cookie, string) {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, form("/dashboard/login", nil, url.Values{"username": {"admin"}, "password": {"password"}}))
+
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("Expected login to redirect, got %d", rec.Code)
+	}
+
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != SessionCookie {
+		t.Fatalf("Expected a session cookie, got %v", cookies)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/dashboard/", nil)
+	req.AddCookie(cookies[0])
+
+	return cookies[0], d.session(req).Token
+}
+
+func form(path string, cookie *http.Cookie, values url.Values) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(values.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	return req
+}
+
+func get(h http.Handler, path string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRequiresLogin(t *testing.T) {
+	d, _, _ := newDashboard()
+	h := d.Handler()
+
+	if rec := get(h, "/dashboard/", nil); rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/dashboard/login" {
+		t.Errorf("Expected a redirect to the login page, got %d %s", rec.Code, rec.Header().Get("Location"))
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, form("/dashboard/login", nil, url.Values{"username": {"admin"}, "password": {"wrong"}}))
+	if rec.Code != http.StatusUnauthorized || len(rec.Result().Cookies()) != 0 {
+		t.Errorf("Expected the wrong password to be rejected, got %d", rec.Code)
+	}
+
+	forged := &http.Cookie{Name: SessionCookie, Value: "YWRtaW4.9999999999.0000"}
+	if rec := get(h, "/dashboard/", forged); rec.Code != http.StatusSeeOther {
+		t.Errorf("Expected a forged session to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestExpiredSession(t *testing.T) {
+	d, _, _ := newDashboard()
+	d.SessionLength = -time.Minute
+	h := d.Handler()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, form("/dashboard/login", nil, url.Values{"username": {"admin"}, "password": {"password"}}))
+
+	if rec := get(h, "/dashboard/", rec.Result().Cookies()[0]); rec.Code != http.StatusSeeOther {
+		t.Errorf("Expected an expired session to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestLogoutRevokesSession(t *testing.T) {
+	d, _, _ := newDashboard()
+	h := d.Handler()
+	cookie, token := login(t, d, h)
+
+	if !cookie.Secure {
+		t.Error("Expected the session cookie to only be sent over HTTPS")
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, form("/dashboard/logout", cookie, url.Values{"token": {token}}))
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("Expected logging out to redirect, got %d", rec.Code)
+	}
+
+	// A copy of the cookie is no longer valid after logging out.
+	if rec := get(h, "/dashboard/", cookie); rec.Code != http.StatusSeeOther {
+		t.Errorf("Expected the logged out session to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestIndex(t *testing.T) {
+	d, _, _ := newDashboard()
+	d.Recent.Log(audit.Event{Type: audit.Book, UserID: "1", Username: "Booker#0001", Server: "Server 1"})
+	h := d.Handler()
+	cookie, _ := login(t, d, h)
+
+	rec := get(h, "/dashboard/", cookie)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected the server list, got %d", rec.Code)
+	}
+
+	body := rec.Body.String()
+	for _, expected := range []string{"Server 1", "Server 2", "Booker#0001", "cp_process_final", "12/24", "/dashboard/servers/Server%201/unbook", "book"} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected the server list to contain %q", expected)
+		}
+	}
+	if strings.Contains(body, "/dashboard/servers/Server%202/unbook") {
+		t.Errorf("Expected no force unbook button for the unbooked server")
+	}
+}
+
+func TestServerPageEscapesConsole(t *testing.T) {
+	d, _, _ := newDashboard()
+	h := d.Handler()
+	cookie, _ := login(t, d, h)
+
+	rec := get(h, "/dashboard/servers/Server%201", cookie)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected the server page, got %d", rec.Code)
+	}
+	if body := rec.Body.String(); strings.Contains(body, "<script>") || !strings.Contains(body, "&lt;script&gt;") {
+		t.Errorf("Expected the console output to be escaped")
+	}
+
+	if rec := get(h, "/dashboard/servers/Server%203", cookie); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected an unknown server to be not found, got %d", rec.Code)
+	}
+}
+
+func TestActions(t *testing.T) {
+	d, p, b := newDashboard()
+	h := d.Handler()
+	cookie, token := login(t, d, h)
+
+	post := func(path string, values url.Values) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, form(path, cookie, values))
+		return rec
+	}
+
+	if rec := post("/dashboard/servers/Server%201/unbook", url.Values{}); rec.Code != http.StatusForbidden || len(b.released) != 0 {
+		t.Errorf("Expected an action without the form token to be rejected, got %d", rec.Code)
+	}
+
+	rec := post("/dashboard/servers/Server%201/unbook", url.Values{"token": {token}})
+	if rec.Code != http.StatusSeeOther || len(b.released) != 1 {
+		t.Errorf("Expected the server to be force unbooked, got %d", rec.Code)
+	}
+	if rec := post("/dashboard/servers/Server%201/unbook", url.Values{"token": {token}}); rec.Code != http.StatusConflict {
+		t.Errorf("Expected an unbooked server not to be unbooked, got %d", rec.Code)
+	}
+
+	post("/dashboard/servers/Server%202/maintenance", url.Values{"token": {token}, "maintenance": {"on"}})
+	if !p.servers[1].InMaintenance() {
+		t.Errorf("Expected the server to be in maintenance")
+	}
+	post("/dashboard/servers/Server%202/maintenance", url.Values{"token": {token}, "maintenance": {"off"}})
+	if p.servers[1].InMaintenance() {
+		t.Errorf("Expected the server to be out of maintenance")
+	}
+
+	if rec := post("/dashboard/servers/Server%202/restart", url.Values{"token": {token}}); rec.Code != http.StatusConflict {
+		t.Errorf("Expected an unbooked server not to be restarted, got %d", rec.Code)
+	}
+
+	p.servers[1].Booked = true
+	post("/dashboard/servers/Server%202/restart", url.Values{"token": {token}})
+	select {
+	case name := <-p.restarted:
+		if name != "Server 2" {
+			t.Errorf("Expected Server 2 to be restarted, got %s", name)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Expected the server to be restarted")
+	}
+
+	events := d.Recent.Events()
+	if len(events) != 4 {
+		t.Fatalf("Expected each action to be audited, got %d events", len(events))
+	}
+	if events[0].Type != audit.AdminAction || events[0].Username != "admin" || events[0].Server != "Server 2" {
+		t.Errorf("Expected the restart to be audited, got %+v", events[0])
+	}
+}
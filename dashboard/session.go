@@ -0,0 +1,163 @@
+package dashboard
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SessionCookie is the name of the cookie holding the admin's session.
+const SessionCookie = "dashboard_session"
+
+// DefaultSessionLength is how long admins stay logged in.
+const DefaultSessionLength = 12 * time.Hour
+
+// session is a logged in admin.
+type session struct {
+	Username string
+	Expires  time.Time
+
+	// Token is sent with every form, so actions can't be submitted from other sites.
+	Token string
+
+	// signature is the signature of the session cookie, identifying the session when it's revoked.
+	signature string
+}
+
+// login returns the admin with the username & password, nil if none.
+func (d *Dashboard) login(username string, password string) *Admin {
+	for i := range d.Admins {
+		admin := &d.Admins[i]
+
+		usernameMatch := subtle.ConstantTimeCompare([]byte(admin.Username), []byte(username))
+		passwordMatch := subtle.ConstantTimeCompare([]byte(admin.Password), []byte(password))
+		if usernameMatch&passwordMatch == 1 && admin.Password != "" {
+			return admin
+		}
+	}
+
+	return nil
+}
+
+// sign returns the hex HMAC-SHA256 of the value with the dashboard's secret.
+func (d *Dashboard) sign(value string) string {
+	mac := hmac.New(sha256.New, d.Secret)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// setSession sets the session cookie of the admin, signed so it can't be forged.
+func (d *Dashboard) setSession(w http.ResponseWriter, admin *Admin) {
+	expires := time.Now().Add(d.SessionLength)
+
+	value := base64.RawURLEncoding.EncodeToString([]byte(admin.Username)) + "." + strconv.FormatInt(expires.Unix(), 10)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    value + "." + d.sign(value),
+		Path:     d.Prefix + "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// clearSession removes the session cookie.
+func (d *Dashboard) clearSession(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    "",
+		Path:     d.Prefix + "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// session returns the request's session, nil if it isn't logged in or the session has expired.
+func (d *Dashboard) session(r *http.Request) *session {
+	cookie, err := r.Cookie(SessionCookie)
+	if err != nil {
+		return nil
+	}
+
+	i := strings.LastIndex(cookie.Value, ".")
+	if i < 0 {
+		return nil
+	}
+	value, signature := cookie.Value[:i], cookie.Value[i+1:]
+	if !hmac.Equal([]byte(signature), []byte(d.sign(value))) {
+		return nil
+	}
+
+	parts := strings.Split(value, ".")
+	if len(parts) != 2 {
+		return nil
+	}
+
+	username, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || time.Now().After(time.Unix(expires, 0)) {
+		return nil
+	}
+
+	// Sessions of admins removed from the configuration are no longer valid.
+	if !d.isAdmin(string(username)) {
+		return nil
+	}
+
+	if d.isRevoked(signature) {
+		return nil
+	}
+
+	return &session{
+		Username:  string(username),
+		Expires:   time.Unix(expires, 0),
+		Token:     d.sign("token:" + value),
+		signature: signature,
+	}
+}
+
+// revoke invalidates the session, so copies of its cookie can't be used after logging out.
+// Revoked sessions are forgotten once they've expired, and when the bot restarts.
+func (d *Dashboard) revoke(s *session) {
+	d.revokedMu.Lock()
+	defer d.revokedMu.Unlock()
+
+	for signature, expires := range d.revoked {
+		if time.Now().After(expires) {
+			delete(d.revoked, signature)
+		}
+	}
+
+	d.revoked[s.signature] = s.Expires
+}
+
+func (d *Dashboard) isRevoked(signature string) bool {
+	d.revokedMu.Lock()
+	defer d.revokedMu.Unlock()
+
+	_, ok := d.revoked[signature]
+	return ok
+}
+
+func (d *Dashboard) isAdmin(username string) bool {
+	for _, admin := range d.Admins {
+		if admin.Username == username {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,168 @@
+package dashboard
+
+import (
+	"html/template"
+	"time"
+)
+
+var funcs = template.FuncMap{
+	"time": func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.Format("2006-01-02 15:04:05")
+	},
+	"row": func(prefix string, token string, row serverRow) actionsData {
+		return actionsData{Prefix: prefix, Token: token, Row: row}
+	},
+}
+
+// layout is the page around every template, which defines the 'title' & 'content' templates.
+const layout = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{template "title" .}} - TF2 Booking</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2em; }
+th, td { text-align: left; padding: 0.4em 0.8em; border-bottom: 1px solid #ddd; }
+form.inline { display: inline; }
+pre { background: #222; color: #eee; padding: 1em; overflow-x: auto; }
+.notice { background: #dff0d8; padding: 0.6em; }
+.error { background: #f2dede; padding: 0.6em; }
+.state-available { color: #2e7d32; }
+.state-booked { color: #1565c0; }
+.state-maintenance, .state-unavailable { color: #c62828; }
+</style>
+</head>
+<body>
+{{if .Session}}
+<p>
+<a href="{{.Prefix}}/">Servers</a> |
+Logged in as {{.Session.Username}}
+<form class="inline" method="post" action="{{.Prefix}}/logout">
+<input type="hidden" name="token" value="{{.Session.Token}}">
+<button type="submit">Log out</button>
+</form>
+</p>
+{{end}}
+{{if .Notice}}<p class="notice">{{.Notice}}</p>{{end}}
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+{{template "content" .}}
+</body>
+</html>`
+
+// actions are the buttons of the admin actions on a server, executed with an actionsData.
+const actions = `{{define "actions"}}
+{{if .Row.Server.Booked}}
+<form class="inline" method="post" action="{{.Prefix}}/servers/{{.Row.Server.Name}}/unbook" onsubmit="return confirm('Force unbook {{.Row.Server.Name}}?')">
+<input type="hidden" name="token" value="{{.Token}}">
+<button type="submit">Force unbook</button>
+</form>
+<form class="inline" method="post" action="{{.Prefix}}/servers/{{.Row.Server.Name}}/restart" onsubmit="return confirm('Restart {{.Row.Server.Name}}?')">
+<input type="hidden" name="token" value="{{.Token}}">
+<button type="submit">Restart</button>
+</form>
+{{end}}
+<form class="inline" method="post" action="{{.Prefix}}/servers/{{.Row.Server.Name}}/maintenance">
+<input type="hidden" name="token" value="{{.Token}}">
+{{if .Row.Server.InMaintenance}}
+<input type="hidden" name="maintenance" value="off">
+<button type="submit">End maintenance</button>
+{{else}}
+<input type="hidden" name="maintenance" value="on">
+<button type="submit">Start maintenance</button>
+{{end}}
+</form>
+{{end}}`
+
+const loginContent = `{{define "title"}}Log in{{end}}
+{{define "content"}}
+<h1>Log in</h1>
+<form method="post" action="{{.Prefix}}/login">
+<p><label>Username <input type="text" name="username" autofocus></label></p>
+<p><label>Password <input type="password" name="password"></label></p>
+<p><button type="submit">Log in</button></p>
+</form>
+{{end}}`
+
+const indexContent = `{{define "title"}}Servers{{end}}
+{{define "content"}}
+<h1>Servers</h1>
+<table>
+<tr><th>Server</th><th>State</th><th>Booker</th><th>Booked at</th><th>Returns at</th><th>Players</th><th>Map</th><th></th></tr>
+{{range .Servers}}
+<tr>
+<td><a href="{{$.Prefix}}/servers/{{.Server.Name}}">{{.Server.Name}}</a></td>
+<td class="state-{{.Status.Class}}">{{.Status.State}}</td>
+<td>{{if .Server.Booked}}{{.Server.BookerFullname}}{{else}}-{{end}}</td>
+<td>{{if .Server.Booked}}{{time .Server.BookedDate}}{{else}}-{{end}}</td>
+<td>{{if .Server.Booked}}{{time .Server.ReturnDate}}{{else}}-{{end}}</td>
+<td>{{.Status.Players}}</td>
+<td>{{.Status.Map}}</td>
+<td>{{template "actions" (row $.Prefix $.Session.Token .)}}</td>
+</tr>
+{{else}}
+<tr><td colspan="8">There are no servers in the pool.</td></tr>
+{{end}}
+</table>
+
+<h2>Recent events</h2>
+<table>
+<tr><th>Time</th><th>Event</th><th>User</th><th>Server</th><th>Reason</th></tr>
+{{range .Events}}
+<tr>
+<td>{{time .Time}}</td>
+<td>{{.Type}}</td>
+<td>{{.Username}}{{if .UserID}} ({{.UserID}}){{end}}</td>
+<td>{{.Server}}</td>
+<td>{{.Reason}}</td>
+</tr>
+{{else}}
+<tr><td colspan="5">There haven't been any events since the bot started.</td></tr>
+{{end}}
+</table>
+{{end}}`
+
+const serverContent = `{{define "title"}}{{.Server.Server.Name}}{{end}}
+{{define "content"}}
+<h1>{{.Server.Server.Name}}</h1>
+<table>
+<tr><th>Address</th><td>{{.Server.Server.Address}}</td></tr>
+<tr><th>State</th><td class="state-{{.Server.Status.Class}}">{{.Server.Status.State}}</td></tr>
+{{if .Server.Server.Booked}}
+<tr><th>Booker</th><td>{{.Server.Server.BookerFullname}} ({{.Server.Server.Booker}})</td></tr>
+<tr><th>Booked at</th><td>{{time .Server.Server.BookedDate}}</td></tr>
+<tr><th>Returns at</th><td>{{time .Server.Server.ReturnDate}}</td></tr>
+{{end}}
+<tr><th>Players</th><td>{{.Server.Status.Players}}</td></tr>
+<tr><th>Map</th><td>{{.Server.Status.Map}}</td></tr>
+</table>
+<p>{{template "actions" (row .Prefix .Session.Token .Server)}}</p>
+
+<h2>Console</h2>
+{{if .ConsoleError}}<p class="error">{{.ConsoleError}}</p>{{end}}
+<pre>{{range .Console}}{{.}}
+{{end}}</pre>
+{{end}}`
+
+// actionsData is the data of the 'actions' template.
+type actionsData struct {
+	Prefix string
+	Token  string
+	Row    serverRow
+}
+
+var (
+	loginTemplate  = parse(loginContent)
+	indexTemplate  = parse(indexContent)
+	serverTemplate = parse(serverContent)
+)
+
+// parse parses the page's content into the layout.
+func parse(content string) *template.Template {
+	tmpl := template.New("layout").Funcs(funcs)
+
+	return template.Must(template.Must(template.Must(tmpl.Parse(layout)).Parse(actions)).Parse(content))
+}
@@ -1,10 +1,11 @@
 package main
 
 import (
-	"fmt"
-
 	"alex-j-butler.com/tf2-booking/config"
+	"alex-j-butler.com/tf2-booking/events"
 	"alex-j-butler.com/tf2-booking/globals"
+	"alex-j-butler.com/tf2-booking/logging"
+	"alex-j-butler.com/tf2-booking/metrics"
 	"alex-j-butler.com/tf2-booking/servers"
 )
 
@@ -12,39 +13,41 @@ import (
 // notifying an admin via Discord if too many errors occur in a short space of time.
 func HandleQueryError(s *servers.Server, err error) {
 	s.ErrorMinutes++
+	metrics.QueryErrors.Inc(s.Name)
 
-	// Too many notifications. Send a message.
-	if s.ErrorMinutes >= config.Conf.Booking.ErrorThreshold {
-		var message string
-		bookerName := "Unknown"
+	// Too many errors. Let the subscribers know.
+	if s.ErrorMinutes >= config.Get().Booking.ErrorThreshold {
+		bookerName := ""
 		if !s.IsBooked() && s.Available() {
+			bookerName = "Unknown"
 			u, err := Session.User(s.Booker)
 			if err == nil {
 				bookerName = u.Username
 			}
-
-			message = fmt.Sprintf(
-				"The server `%s` failed to be contacted after %d retries after being booked by `%s`. Check to ensure the server is correctly working.",
-				s.Name,
-				s.ErrorMinutes,
-				bookerName,
-			)
-		} else {
-			message = fmt.Sprintf(
-				"The server `%s` failed to be contacted after %d retries while unbooked. Check to ensure the server is correctly working.",
-				s.Name,
-				s.ErrorMinutes,
-			)
 		}
 
+		Events.Publish(&events.ServerErrored{
+			Server:  s,
+			Retries: s.ErrorMinutes,
+			Err:     err,
+			Booker:  bookerName,
+		})
+
 		// Reset the error minutes.
 		s.ErrorMinutes = 0
-
-		for _, notificationUser := range config.Conf.Discord.NotificationUsers {
-			UserChannel, _ := Session.UserChannelCreate(notificationUser)
-			Session.ChannelMessageSend(UserChannel.ID, message)
-		}
 	}
 
 	s.Update(globals.RedisClient)
 }
+
+// NotifyAdmins sends the message to the notification users via private message.
+func NotifyAdmins(message string) {
+	for _, notificationUser := range config.Get().Discord.NotificationUsers {
+		UserChannel, err := Session.UserChannelCreate(notificationUser)
+		if err != nil {
+			logging.User(notificationUser).WithError(err).Error("Failed to create user channel")
+			continue
+		}
+		Session.ChannelMessageSend(UserChannel.ID, message)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"alex-j-butler.com/tf2-booking/config"
+	"alex-j-butler.com/tf2-booking/events"
+	"alex-j-butler.com/tf2-booking/subscribers"
+)
+
+// Events is the bus the booking events are published to.
+var Events = events.New()
+
+// SetupSubscribers subscribes the reactions to the booking events.
+// Subscribers are called in the order they're subscribed, so the booker is notified before the demos are sent.
+func SetupSubscribers() {
+	notifier := &subscribers.Notifier{
+		Send:         SendMessage,
+		DM:           SendDM,
+		NotifyAdmins: NotifyAdmins,
+		Text:         Text,
+		AdminText:    AdminText,
+		Channel:      config.Get().Discord.DefaultChannel,
+	}
+	notifier.Subscribe(Events)
+
+	subscribers.NewDemos(SendMessage, config.Get().Discord.DefaultChannel).Subscribe(Events)
+
+	presence := &subscribers.Presence{Update: UpdateGameString}
+	presence.Subscribe(Events)
+
+	// Audit is read when the events are published, as the audit channel is only known once connected to Discord.
+	auditor := &subscribers.Auditor{Recorder: &Audit}
+	auditor.Subscribe(Events)
+
+	subscribers.Metrics{}.Subscribe(Events)
+}
+
+// SendMessage sends a message to the Discord channel.
+func SendMessage(channelID string, message string) error {
+	_, err := Session.ChannelMessageSend(channelID, message)
+	return err
+}
+
+// SendDM sends a private message to the Discord user.
+func SendDM(userID string, message string) error {
+	channel, err := Session.UserChannelCreate(userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = Session.ChannelMessageSend(channel.ID, message)
+	return err
+}
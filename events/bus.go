@@ -0,0 +1,83 @@
+package events
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Bus delivers the booking events to the subscribers of their type.
+// A nil Bus discards events.
+type Bus struct {
+	handlersMu sync.RWMutex
+	handlers   map[reflect.Type][]reflect.Value
+}
+
+// New creates a bus without any subscribers.
+func New() *Bus {
+	return &Bus{
+		handlers: make(map[reflect.Type][]reflect.Value),
+	}
+}
+
+// Subscribe adds a handler of type func(*events.EventType), called with each event of that type.
+// A handler of type func(interface{}) is called with every event.
+// Returns a function that removes the handler.
+func (b *Bus) Subscribe(handler interface{}) func() {
+	eventType := validateHandler(handler)
+
+	b.handlersMu.Lock()
+	defer b.handlersMu.Unlock()
+
+	h := reflect.ValueOf(handler)
+
+	b.handlers[eventType] = append(b.handlers[eventType], h)
+
+	return func() {
+		b.handlersMu.Lock()
+		defer b.handlersMu.Unlock()
+
+		handlers := b.handlers[eventType]
+		for i, v := range handlers {
+			if h == v {
+				b.handlers[eventType] = append(handlers[:i], handlers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+func validateHandler(handler interface{}) reflect.Type {
+	handlerType := reflect.TypeOf(handler)
+
+	if handlerType == nil || handlerType.Kind() != reflect.Func || handlerType.NumIn() != 1 || handlerType.NumOut() != 0 {
+		panic("Unable to subscribe to events, handler must be of type func(*events.EventType)")
+	}
+
+	eventType := handlerType.In(0)
+
+	if eventType.Kind() == reflect.Interface {
+		eventType = nil
+	} else if eventType.Kind() != reflect.Ptr {
+		panic("Unable to subscribe to events, event must be a pointer to an event type")
+	}
+
+	return eventType
+}
+
+// Publish calls the subscribers of the event in the order they subscribed, followed by the
+// subscribers of every event, before returning.
+// Subscribers that are slow should do their work in the background.
+func (b *Bus) Publish(event interface{}) {
+	if b == nil {
+		return
+	}
+
+	b.handlersMu.RLock()
+	handlers := append(append([]reflect.Value{}, b.handlers[reflect.TypeOf(event)]...), b.handlers[nil]...)
+	b.handlersMu.RUnlock()
+
+	handlerParameters := []reflect.Value{reflect.ValueOf(event)}
+	for _, handler := range handlers {
+		handler.Call(handlerParameters)
+	}
+}
@@ -0,0 +1,83 @@
+package events
+
+import (
+	"testing"
+)
+
+func TestBusPublishesToSubscribersOfType(t *testing.T) {
+	bus := New()
+
+	var started, ended int
+	bus.Subscribe(func(e *BookingStarted) { started++ })
+	bus.Subscribe(func(e *BookingEnded) { ended++ })
+
+	bus.Publish(&BookingStarted{UserID: "1"})
+	bus.Publish(&BookingStarted{UserID: "2"})
+	bus.Publish(&BookingEnded{UserID: "1"})
+
+	if started != 2 || ended != 1 {
+		t.Errorf("Expected 2 started & 1 ended events, got %d started & %d ended", started, ended)
+	}
+}
+
+func TestBusPublishesInOrder(t *testing.T) {
+	bus := New()
+
+	var order []string
+	bus.Subscribe(func(e interface{}) { order = append(order, "all") })
+	bus.Subscribe(func(e *BookingEnded) { order = append(order, "first") })
+	bus.Subscribe(func(e *BookingEnded) { order = append(order, "second") })
+
+	bus.Publish(&BookingEnded{})
+
+	expected := []string{"first", "second", "all"}
+	if len(order) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, order)
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Fatalf("Expected %v, got %v", expected, order)
+		}
+	}
+}
+
+func TestBusUnsubscribe(t *testing.T) {
+	bus := New()
+
+	var calls int
+	unsubscribe := bus.Subscribe(func(e *BookingExtended) { calls++ })
+
+	bus.Publish(&BookingExtended{})
+	unsubscribe()
+	bus.Publish(&BookingExtended{})
+
+	if calls != 1 {
+		t.Errorf("Expected 1 call before unsubscribing, got %d", calls)
+	}
+}
+
+func TestBusInvalidHandler(t *testing.T) {
+	handlers := []interface{}{
+		"not a function",
+		func() {},
+		func(e BookingStarted) {},
+		func(e *BookingStarted) error { return nil },
+	}
+
+	for _, handler := range handlers {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected subscribing %T to panic", handler)
+				}
+			}()
+
+			New().Subscribe(handler)
+		}()
+	}
+}
+
+func TestNilBus(t *testing.T) {
+	var bus *Bus
+	bus.Publish(&BookingStarted{})
+}
@@ -0,0 +1,105 @@
+package events
+
+import (
+	"time"
+
+	"alex-j-butler.com/tf2-booking/servers"
+)
+
+// EndReason is why a booking ended.
+type EndReason string
+
+// Reasons a booking ended.
+const (
+	// Returned is when the booker returned the server.
+	Returned EndReason = "returned"
+
+	// ReturnedIngame is when a player returned the server from ingame.
+	ReturnedIngame EndReason = "returned_ingame"
+
+	// Expired is when the booking time ran out.
+	Expired EndReason = "expired"
+
+	// Idle is when the server didn't have enough players for too long.
+	Idle EndReason = "idle"
+
+	// Forced is when an admin unbooked the server.
+	Forced EndReason = "forced"
+
+	// Stopped is when the server stopped while booked, and the reconciler unbooked it.
+	Stopped EndReason = "stopped"
+)
+
+// Automatic returns whether the booking was ended by the bot, rather than a person.
+func (r EndReason) Automatic() bool {
+	return r == Expired || r == Idle || r == Stopped
+}
+
+// BookingStarted is published when a server is booked.
+type BookingStarted struct {
+	Server   *servers.Server
+	UserID   string
+	Username string
+
+	// Preset is the preset the server was booked with, empty if none.
+	Preset string
+
+	// Duration is how long the server was booked for, zero if the booking doesn't expire.
+	Duration time.Duration
+}
+
+// BookingStartFailed is published when a booked server fails to start, after the booking has been reset.
+type BookingStartFailed struct {
+	Server   *servers.Server
+	UserID   string
+	Username string
+	Err      error
+}
+
+// BookingEnded is published when a server is unbooked.
+type BookingEnded struct {
+	Server      *servers.Server
+	UserID      string
+	UserMention string
+	Reason      EndReason
+
+	// By is the name of the player that returned the server, for bookings returned ingame.
+	By string
+
+	// BookedFor is how long the server was booked for.
+	BookedFor time.Duration
+}
+
+// BookingExtended is published when a booking is extended.
+type BookingExtended struct {
+	Server   *servers.Server
+	UserID   string
+	Duration time.Duration
+}
+
+// ServerStopFailed is published when an unbooked server fails to stop.
+type ServerStopFailed struct {
+	Server *servers.Server
+	UserID string
+	Err    error
+}
+
+// ServerErrored is published when a server has failed to be queried too many times.
+type ServerErrored struct {
+	Server  *servers.Server
+	Retries int
+	Err     error
+
+	// Booker is the name of the user that booked the server, empty if it isn't booked.
+	Booker string
+}
+
+// PlayerReported is published when a player reports a server from ingame.
+type PlayerReported struct {
+	Server string
+
+	// SteamID is the SteamID3 of the player that reported the server.
+	SteamID  string
+	Username string
+	Reason   string
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"fmt"
-	"log"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
 )
 
 func GetGameString(num int) string {
@@ -15,14 +17,24 @@ func GetGameString(num int) string {
 	}
 }
 
+// GetDegradedString returns the game string shown while dependencies are failing.
+func GetDegradedString(degraded []string) string {
+	return fmt.Sprintf("Degraded: %s unavailable", strings.Join(degraded, ", "))
+}
+
 func UpdateGameString() error {
-	log.Println("Updating game string")
+	log.Debug("Updating game string")
+
+	// Let users know the bot isn't working properly, rather than them finding out from errors.
+	if degraded := Health.Degraded(); len(degraded) > 0 {
+		return Session.UpdateGameStatus(1, GetDegradedString(degraded))
+	}
 
 	availableServers := len(pool.GetAvailableServers())
 
 	if availableServers == 0 {
-		return Session.UpdateStatus(1, GetGameString(availableServers))
+		return Session.UpdateGameStatus(1, GetGameString(availableServers))
 	}
 
-	return Session.UpdateStatus(0, GetGameString(availableServers))
+	return Session.UpdateGameStatus(0, GetGameString(availableServers))
 }
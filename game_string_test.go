@@ -37,3 +37,12 @@ func TestGameStringFifteen(t *testing.T) {
 		t.Errorf("TestGameStringFifteen: Expected \"%s\", got \"%s\"", expected, gameString)
 	}
 }
+
+func TestDegradedString(t *testing.T) {
+	gameString := GetDegradedString([]string{"booking_api", "redis"})
+	expected := "Degraded: booking_api, redis unavailable"
+
+	if gameString != expected {
+		t.Errorf("TestDegradedString: Expected \"%s\", got \"%s\"", expected, gameString)
+	}
+}
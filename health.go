@@ -0,0 +1,39 @@
+package main
+
+import (
+	"errors"
+
+	"alex-j-butler.com/tf2-booking/commands/ingame/loghandler"
+	"alex-j-butler.com/tf2-booking/config"
+	"alex-j-butler.com/tf2-booking/globals"
+	"alex-j-butler.com/tf2-booking/health"
+
+	"github.com/Qixalite/booking-api/client"
+)
+
+// Health checks the bot's dependencies, nil until they've been setup.
+var Health *health.Checker
+
+// NewHealthChecker creates the checks of Redis, the booking API if it's configured, the Discord gateway & the log handler.
+func NewHealthChecker(bookingClient *client.Client, logs *loghandler.LogHandler) *health.Checker {
+	checker := health.New()
+
+	checker.Add("redis", func() error {
+		return globals.RedisClient.Ping().Err()
+	})
+	if bookingClient != nil {
+		checker.Add("booking_api", func() error {
+			_, err := bookingClient.GetServersByTag(config.Get().BookingAPI.Tag)
+			return err
+		})
+	}
+	checker.Add("discord", func() error {
+		if Session == nil || !Session.DataReady {
+			return errors.New("not connected to the Discord gateway")
+		}
+		return nil
+	})
+	checker.Add("log_handler", logs.Bound)
+
+	return checker
+}
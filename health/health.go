@@ -0,0 +1,209 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Statuses of the dependencies & the overall report.
+const (
+	StatusOK       = "ok"
+	StatusError    = "error"
+	StatusDegraded = "degraded"
+
+	// StatusUnchecked is the status of the report before the checks have first run.
+	StatusUnchecked = "unchecked"
+)
+
+// DefaultTimeout is how long a check can take before it's failed.
+const DefaultTimeout = 5 * time.Second
+
+// ErrTimeout is returned for checks that take longer than the timeout.
+var ErrTimeout = errors.New("health: check timed out")
+
+// Check checks a dependency, returning an error if it's unhealthy.
+type Check func() error
+
+// Result is the status of a single dependency.
+type Result struct {
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+	Latency string `json:"latency"`
+}
+
+// Report is the status of every dependency.
+type Report struct {
+	Status    string            `json:"status"`
+	CheckedAt time.Time         `json:"checked_at"`
+	Checks    map[string]Result `json:"checks"`
+}
+
+// Degraded returns the names of the failing dependencies, sorted.
+func (r Report) Degraded() []string {
+	var degraded []string
+	for name, result := range r.Checks {
+		if result.Status != StatusOK {
+			degraded = append(degraded, name)
+		}
+	}
+	sort.Strings(degraded)
+
+	return degraded
+}
+
+type check struct {
+	name  string
+	check Check
+}
+
+// Checker checks the bot's dependencies, keeping the last report.
+// A nil Checker has no dependencies.
+type Checker struct {
+	Timeout time.Duration
+
+	mu     sync.Mutex
+	checks []check
+	last   Report
+}
+
+// New creates a Checker without any checks.
+func New() *Checker {
+	return &Checker{Timeout: DefaultTimeout}
+}
+
+// Add adds a dependency check.
+func (c *Checker) Add(name string, fn Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checks = append(c.checks, check{name: name, check: fn})
+}
+
+// Run runs every check concurrently, returning & keeping the report.
+func (c *Checker) Run() Report {
+	if c == nil {
+		return Report{Status: StatusOK, CheckedAt: time.Now(), Checks: map[string]Result{}}
+	}
+
+	c.mu.Lock()
+	checks := append([]check{}, c.checks...)
+	c.mu.Unlock()
+
+	report := Report{
+		Status:    StatusOK,
+		CheckedAt: time.Now(),
+		Checks:    make(map[string]Result, len(checks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, chk := range checks {
+		wg.Add(1)
+		go func(chk check) {
+			defer wg.Done()
+
+			result := c.run(chk)
+
+			mu.Lock()
+			report.Checks[chk.name] = result
+			if result.Status != StatusOK {
+				report.Status = StatusDegraded
+			}
+			mu.Unlock()
+		}(chk)
+	}
+	wg.Wait()
+
+	if degraded := report.Degraded(); len(degraded) > 0 {
+		log.WithField("checks", degraded).Warn("Health check failed")
+	}
+
+	c.mu.Lock()
+	c.last = report
+	c.mu.Unlock()
+
+	return report
+}
+
+// run runs the check, failing it if it takes longer than the timeout.
+func (c *Checker) run(chk check) Result {
+	start := time.Now()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- chk.check()
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(c.Timeout):
+		err = ErrTimeout
+	}
+
+	result := Result{Status: StatusOK, Latency: time.Since(start).String()}
+	if err != nil {
+		result.Status = StatusError
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// Last returns the last report, without running the checks.
+func (c *Checker) Last() Report {
+	if c == nil {
+		return Report{Status: StatusOK}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.last.CheckedAt.IsZero() {
+		return Report{Status: StatusUnchecked, Checks: map[string]Result{}}
+	}
+
+	return c.last
+}
+
+// Degraded returns the names of the dependencies that failed the last checks.
+func (c *Checker) Degraded() []string {
+	return c.Last().Degraded()
+}
+
+// LiveHandler responds OK while the bot is running, without checking the dependencies.
+func (c *Checker) LiveHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, http.StatusOK, Report{Status: StatusOK, CheckedAt: time.Now(), Checks: map[string]Result{}})
+	})
+}
+
+// ReadyHandler serves the last report of each dependency, responding Service Unavailable if any are failing,
+// or they haven't been checked yet. The checks aren't run per request, so they're kept up to date with Run.
+func (c *Checker) ReadyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.Last()
+
+		status := http.StatusOK
+		if report.Status != StatusOK {
+			status = http.StatusServiceUnavailable
+		}
+
+		writeReport(w, status, report)
+	})
+}
+
+func writeReport(w http.ResponseWriter, status int, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.WithError(err).Warn("Failed to write health report")
+	}
+}
@@ -0,0 +1,107 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCheckerRun(t *testing.T) {
+	c := New()
+	c.Add("redis", func() error { return nil })
+	c.Add("booking_api", func() error { return errors.New("connection refused") })
+
+	report := c.Run()
+
+	if report.Status != StatusDegraded {
+		t.Errorf("Expected the report to be degraded, got %s", report.Status)
+	}
+	if report.Checks["redis"].Status != StatusOK {
+		t.Errorf("Expected redis to be ok, got %+v", report.Checks["redis"])
+	}
+	if result := report.Checks["booking_api"]; result.Status != StatusError || result.Error != "connection refused" {
+		t.Errorf("Expected the booking API to have failed, got %+v", result)
+	}
+	if degraded := c.Degraded(); !reflect.DeepEqual(degraded, []string{"booking_api"}) {
+		t.Errorf("Expected the last report to be kept, got %v", degraded)
+	}
+}
+
+func TestCheckerTimeout(t *testing.T) {
+	c := New()
+	c.Timeout = 10 * time.Millisecond
+	c.Add("discord", func() error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	if result := c.Run().Checks["discord"]; result.Error != ErrTimeout.Error() {
+		t.Errorf("Expected the check to time out, got %+v", result)
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	healthy := true
+	c := New()
+	c.Add("redis", func() error {
+		if !healthy {
+			return errors.New("down")
+		}
+		return nil
+	})
+
+	checks := 0
+	c.Add("counter", func() error {
+		checks++
+		return nil
+	})
+
+	rec := httptest.NewRecorder()
+	c.ReadyHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected the bot not to be ready before the first checks, got %d", rec.Code)
+	}
+
+	for _, test := range []struct {
+		healthy  bool
+		handler  http.Handler
+		expected int
+		status   string
+	}{
+		{true, c.LiveHandler(), http.StatusOK, StatusOK},
+		{true, c.ReadyHandler(), http.StatusOK, StatusOK},
+		{false, c.LiveHandler(), http.StatusOK, StatusOK},
+		{false, c.ReadyHandler(), http.StatusServiceUnavailable, StatusDegraded},
+	} {
+		healthy = test.healthy
+		c.Run()
+
+		rec := httptest.NewRecorder()
+		test.handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		var report Report
+		if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+			t.Fatalf("Failed to decode report: %s", err)
+		}
+
+		if rec.Code != test.expected || report.Status != test.status {
+			t.Errorf("Expected %d %s while healthy=%t, got %d %s", test.expected, test.status, test.healthy, rec.Code, report.Status)
+		}
+	}
+
+	// The handlers serve the last report, rather than running the checks themselves.
+	if checks != 4 {
+		t.Errorf("Expected the checks to only run when the checker is run, got %d runs", checks)
+	}
+}
+
+func TestNilChecker(t *testing.T) {
+	var c *Checker
+	if report := c.Run(); report.Status != StatusOK || len(c.Degraded()) != 0 {
+		t.Errorf("Expected a nil checker to be healthy, got %+v", report)
+	}
+}
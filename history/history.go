@@ -0,0 +1,113 @@
+package history
+
+import (
+	"encoding/json"
+	"time"
+
+	"alex-j-butler.com/tf2-booking/events"
+	"alex-j-butler.com/tf2-booking/logging"
+
+	redis "gopkg.in/redis.v5"
+)
+
+// DefaultKey is the Redis key of the booking history.
+const DefaultKey = "booking.history"
+
+// DefaultMax is the number of bookings kept in the history.
+const DefaultMax = 1000
+
+// Entry is a single finished booking.
+type Entry struct {
+	Server   string    `json:"server"`
+	UserID   string    `json:"user_id"`
+	BookedAt time.Time `json:"booked_at"`
+	EndedAt  time.Time `json:"ended_at"`
+
+	// Reason is why the booking ended, see events.EndReason.
+	Reason string `json:"reason"`
+}
+
+// Store keeps the most recent finished bookings in a Redis list, newest first.
+type Store struct {
+	Redis *redis.Client
+	Key   string
+	Max   int
+}
+
+// New creates a Store for the history in the Redis list at the key.
+func New(redisClient *redis.Client, key string) *Store {
+	return &Store{
+		Redis: redisClient,
+		Key:   key,
+		Max:   DefaultMax,
+	}
+}
+
+// Subscribe records each booking once it has ended.
+func (s *Store) Subscribe(bus *events.Bus) {
+	bus.Subscribe(s.BookingEnded)
+}
+
+// BookingEnded records the ended booking.
+func (s *Store) BookingEnded(e *events.BookingEnded) {
+	now := time.Now()
+
+	err := s.Add(Entry{
+		Server:   e.Server.Name,
+		UserID:   e.UserID,
+		BookedAt: now.Add(-e.BookedFor),
+		EndedAt:  now,
+		Reason:   string(e.Reason),
+	})
+	if err != nil {
+		logging.User(e.UserID).WithError(err).WithField(logging.ServerField, e.Server.Name).Error("Failed to record booking history")
+	}
+}
+
+// Add adds the entry to the history, removing the oldest entries past the maximum.
+func (s *Store) Add(entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Redis.LPush(s.Key, string(data)).Err(); err != nil {
+		return err
+	}
+
+	return s.Redis.LTrim(s.Key, 0, int64(s.Max-1)).Err()
+}
+
+// List returns the most recent entries, newest first.
+// If userID isn't empty, only the bookings of that user are returned.
+func (s *Store) List(userID string, limit int) ([]Entry, error) {
+	values, err := s.Redis.LRange(s.Key, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	return Filter(values, userID, limit), nil
+}
+
+// Filter decodes the stored entries, returning up to limit entries of the user.
+// An empty userID matches every user, and a limit of zero returns every entry.
+func Filter(values []string, userID string, limit int) []Entry {
+	entries := []Entry{}
+	for _, value := range values {
+		var entry Entry
+		if err := json.Unmarshal([]byte(value), &entry); err != nil {
+			continue
+		}
+
+		if userID != "" && entry.UserID != userID {
+			continue
+		}
+
+		entries = append(entries, entry)
+		if limit > 0 && len(entries) >= limit {
+			break
+		}
+	}
+
+	return entries
+}
@@ -0,0 +1,41 @@
+package history
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func encode(entries ...Entry) []string {
+	values := make([]string, len(entries))
+	for i, entry := range entries {
+		data, _ := json.Marshal(entry)
+		values[i] = string(data)
+	}
+	return values
+}
+
+func TestFilter(t *testing.T) {
+	values := encode(
+		Entry{Server: "Server 3", UserID: "1"},
+		Entry{Server: "Server 2", UserID: "2"},
+		Entry{Server: "Server 1", UserID: "1"},
+	)
+	values = append(values, "not json")
+
+	if entries := Filter(values, "", 0); len(entries) != 3 {
+		t.Errorf("Expected every valid entry, got %d", len(entries))
+	}
+
+	entries := Filter(values, "1", 0)
+	if len(entries) != 2 || entries[0].Server != "Server 3" || entries[1].Server != "Server 1" {
+		t.Errorf("Expected the entries of user 1, newest first, got %+v", entries)
+	}
+
+	if entries := Filter(values, "", 2); len(entries) != 2 {
+		t.Errorf("Expected the limit to be applied, got %d", len(entries))
+	}
+
+	if entries := Filter(nil, "1", 0); entries == nil || len(entries) != 0 {
+		t.Errorf("Expected an empty list, got %v", entries)
+	}
+}
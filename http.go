@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+
+	"alex-j-butler.com/tf2-booking/api"
+	"alex-j-butler.com/tf2-booking/config"
+	"alex-j-butler.com/tf2-booking/metrics"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/bwmarrin/discordgo"
+)
+
+// ServeHTTP serves the metrics & health checks, and the REST API & admin dashboard if they're enabled.
+func ServeHTTP() {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Default.Handler())
+	mux.Handle("/healthz", Health.LiveHandler())
+	mux.Handle("/readyz", Health.ReadyHandler())
+
+	if config.Get().API.Enabled {
+		mux.Handle("/api/", http.StripPrefix("/api", APIHandler()))
+	}
+	if config.Get().Dashboard.Enabled {
+		mux.Handle("/dashboard/", DashboardHandler())
+	}
+
+	log.WithField("address", config.Get().HTTP.Address).Info("Serving HTTP")
+	if err := http.ListenAndServe(config.Get().HTTP.Address, mux); err != nil {
+		log.WithError(err).Error("HTTP server failed")
+	}
+}
+
+// APIHandler returns the REST API handler, going through the same server pool & booking manager as the Discord commands.
+func APIHandler() http.Handler {
+	clients := make([]api.Client, len(config.Get().API.Clients))
+	for i, client := range config.Get().API.Clients {
+		clients[i] = api.Client{
+			Name:   client.Name,
+			Key:    client.Key,
+			UserID: client.UserID,
+			Admin:  client.Admin,
+		}
+	}
+
+	server := &api.Server{
+		Pool:     pool,
+		Bookings: Bookings,
+		History:  History,
+		Clients:  clients,
+		User: func(userID string) (*discordgo.User, error) {
+			return Session.User(userID)
+		},
+	}
+
+	return server.Handler()
+}
+
+// CollectServerMetrics measures the available & booked servers when the metrics are scraped.
+func CollectServerMetrics() {
+	tag := config.Get().BookingAPI.Tag
+
+	metrics.ServersAvailable.Set(float64(len(pool.GetAvailableServers())), tag)
+	metrics.ServersBooked.Set(float64(len(pool.GetBookedServers())), tag)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+
+	"alex-j-butler.com/tf2-booking/globals"
+	"alex-j-butler.com/tf2-booking/messages"
+)
+
+// UserLocaleKey is the Redis key storing the language preference of a user.
+const UserLocaleKey = "locale.%s"
+
+// UserLocale returns the language preference of the user, or the default locale if they don't have one.
+func UserLocale(userID string) string {
+	locale, err := globals.RedisClient.Get(fmt.Sprintf(UserLocaleKey, userID)).Result()
+	if err != nil || locale == "" {
+		return messages.Default.DefaultLocale
+	}
+
+	return locale
+}
+
+// SetUserLocale sets the language preference of the user, an empty locale removes it.
+func SetUserLocale(userID string, locale string) error {
+	if locale == "" {
+		return globals.RedisClient.Del(fmt.Sprintf(UserLocaleKey, userID)).Err()
+	}
+
+	return globals.RedisClient.Set(fmt.Sprintf(UserLocaleKey, userID), locale, 0).Err()
+}
+
+// Text renders the message in the language of the user.
+func Text(userID string, key string, data messages.Data) string {
+	return messages.Render(UserLocale(userID), key, data)
+}
+
+// AdminText renders the message in the default locale, for messages that aren't sent to a specific user.
+func AdminText(key string, data messages.Data) string {
+	return messages.Render(messages.Default.DefaultLocale, key, data)
+}
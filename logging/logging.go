@@ -0,0 +1,52 @@
+package logging
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// Output formats of the log.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Fields of the log entries, for filtering the log by server or user, and following a booking.
+const (
+	ServerField     = "server"
+	ServerUUIDField = "server_uuid"
+	UserIDField     = "user_id"
+	BookingIDField  = "booking_id"
+)
+
+// Configure sets the level & format of the log.
+// The standard library's log is sent through the logger, so every entry uses the same format.
+func Configure(level string, format string) error {
+	l, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case FormatText, "":
+		logrus.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+	case FormatJSON:
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	default:
+		return fmt.Errorf("unknown log format \"%s\"", format)
+	}
+
+	logrus.SetLevel(l)
+
+	log.SetFlags(0)
+	log.SetOutput(logrus.StandardLogger().Writer())
+
+	return nil
+}
+
+// User returns a log entry for the Discord user.
+func User(userID string) *logrus.Entry {
+	return logrus.WithField(UserIDField, userID)
+}
@@ -0,0 +1,45 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+)
+
+func TestConfigureJSON(t *testing.T) {
+	var buf bytes.Buffer
+	logrus.SetOutput(&buf)
+	defer logrus.SetOutput(os.Stderr)
+
+	if err := Configure("warn", FormatJSON); err != nil {
+		t.Fatalf("Expected the log to be configured, got %s", err)
+	}
+	defer Configure("info", FormatText)
+
+	User("1").Info("Filtered by the level")
+	User("1").WithField(BookingIDField, "abc").Warn("Logged")
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected a single JSON entry, got %q", buf.String())
+	}
+
+	if entry["msg"] != "Logged" || entry["level"] != "warning" {
+		t.Errorf("Expected the warning to be logged, got %v", entry)
+	}
+	if entry[UserIDField] != "1" || entry[BookingIDField] != "abc" {
+		t.Errorf("Expected the entry's fields, got %v", entry)
+	}
+}
+
+func TestConfigureInvalid(t *testing.T) {
+	if err := Configure("loud", FormatText); err == nil {
+		t.Errorf("Expected an unknown level to fail")
+	}
+	if err := Configure("info", "xml"); err == nil {
+		t.Errorf("Expected an unknown format to fail")
+	}
+}
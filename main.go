@@ -2,23 +2,31 @@ package main
 
 import (
 	"fmt"
-	"log"
 	"os"
-	"strings"
 	"time"
 
 	redis "gopkg.in/redis.v5"
 
+	"alex-j-butler.com/tf2-booking/audit"
+	"alex-j-butler.com/tf2-booking/booking"
 	"alex-j-butler.com/tf2-booking/commands"
 	"alex-j-butler.com/tf2-booking/commands/ingame"
 	"alex-j-butler.com/tf2-booking/commands/ingame/loghandler"
+	"alex-j-butler.com/tf2-booking/commands/parser"
 	"alex-j-butler.com/tf2-booking/config"
 	"alex-j-butler.com/tf2-booking/globals"
+	"alex-j-butler.com/tf2-booking/history"
+	"alex-j-butler.com/tf2-booking/logging"
+	"alex-j-butler.com/tf2-booking/messages"
+	"alex-j-butler.com/tf2-booking/metrics"
+	"alex-j-butler.com/tf2-booking/ratelimit"
 	"alex-j-butler.com/tf2-booking/servers"
 	"alex-j-butler.com/tf2-booking/util"
 	"alex-j-butler.com/tf2-booking/wait"
+	"alex-j-butler.com/tf2-booking/webhooks"
 
 	"github.com/Qixalite/booking-api/client"
+	log "github.com/Sirupsen/logrus"
 	"github.com/bwmarrin/discordgo"
 	"github.com/robfig/cron"
 
@@ -35,16 +43,30 @@ var Session *discordgo.Session
 // BotID represents the ID of the current user.
 var BotID string
 
-// UserReportTimeouts maps user's steamids to the time after which they can report again.
-var UserReportTimeouts map[string]time.Time
+// ReportLimiter limits how often each user (by steamid) can report a server.
+var ReportLimiter *ratelimit.Limiter
 
-// Redis script to retrieve a key, and if that key does not exist, then set a default value.
-var GetDefaultValue *redis.Script
+// Bookings performs the booking operations for the command handlers.
+var Bookings *booking.Manager
 
 // Command system
 var Command *commands.Command
+var SlashCommand *commands.SlashCommand
+var ComponentCommand *commands.ComponentCommand
 var IngameCommand *ingame.Command
 
+// History records the finished bookings.
+var History *history.Store
+
+// Audit records booking events to the audit channel, webhooks & admin dashboard.
+var Audit = audit.Recorders{RecentEvents}
+
+// AuditLog posts booking events to the audit channel, nil if no audit channel is configured.
+var AuditLog *audit.Logger
+
+// Webhooks delivers booking events to the configured webhooks, nil if no webhooks are configured.
+var Webhooks *webhooks.Dispatcher
+
 var pool servers.ServerPool
 
 // MessageCreateFunc stores the function that deletes the MessageCreate Discord event handler.
@@ -54,10 +76,20 @@ var pool servers.ServerPool
 // By storing the latest MessageCreate delete function, it can delete the previous MessageCreate handler before adding the new one.
 var MessageCreateFunc func()
 
-func main() {
-	config.InitialiseConfiguration()
+// InteractionCreateFunc stores the function that deletes the InteractionCreate Discord event handler,
+// for the same reason as MessageCreateFunc.
+var InteractionCreateFunc func()
 
+func main() {
 	app := cli.NewApp()
+	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:   "config, c",
+			Value:  config.DefaultPath,
+			Usage:  "path of the configuration file",
+			EnvVar: "TF2_BOOKING_CONFIG",
+		},
+	}
 	app.Commands = []cli.Command{
 		{
 			Name:    "run",
@@ -66,55 +98,119 @@ func main() {
 			Action:  RunServer,
 		},
 	}
+	app.Commands = append(app.Commands, AdminCommands...)
 
 	app.Run(os.Args)
 }
 
+// Configure loads the configuration file chosen with '--config', and the message catalogue.
+func Configure(ctx *cli.Context) error {
+	ConfigPath = ctx.GlobalString("config")
+	if err := config.InitialiseConfiguration(ConfigPath); err != nil {
+		return err
+	}
+
+	// Load the message catalogue, with the built-in English messages & the configured locale files.
+	catalogue, err := messages.Load(config.Get().Messages.Directory, config.Get().Messages.Locale)
+	if err != nil {
+		return fmt.Errorf("failed to load messages: %s", err)
+	}
+	messages.Default = catalogue
+
+	return logging.Configure(config.Get().Logging.Level, config.Get().Logging.Format)
+}
+
+// NewBookingClient creates the client of the booking API, nil if the booking API isn't configured.
+func NewBookingClient() *client.Client {
+	if config.Get().BookingAPI.Address == "" {
+		return nil
+	}
+
+	return client.New(
+		config.Get().BookingAPI.Address,
+		config.Get().BookingAPI.Port,
+		config.Get().BookingAPI.Key,
+	)
+}
+
+// NewServerPool creates & initialises the server pool chosen in the configuration.
+func NewServerPool(bookingClient *client.Client) (servers.ServerPool, error) {
+	var pool servers.ServerPool
+	switch config.Get().Servers.Pool {
+	case config.ServerPoolFile:
+		pool = &servers.FileServerPool{Path: config.Get().Servers.File, APIClient: bookingClient}
+	default:
+		pool = &servers.APIServerPool{Tag: config.Get().BookingAPI.Tag, APIClient: bookingClient}
+	}
+
+	if err := pool.Initialise(); err != nil {
+		return nil, err
+	}
+
+	return pool, nil
+}
+
+// ConnectRedis creates the Redis client,
+// and PINGs it to make sure we properly connected and can issue commands to it.
+func ConnectRedis() (*redis.Client, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     config.Get().Redis.Address,
+		Password: config.Get().Redis.Password,
+		DB:       config.Get().Redis.DB,
+	})
+
+	if err := client.Ping().Err(); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
 // RunServer is the subcommand handler that starts the TF2 Booking server.
 func RunServer(ctx *cli.Context) {
+	if err := Configure(ctx); err != nil {
+		log.Fatal(err)
+	}
+
 	// Create the Booking client.
-	bookingClient := client.New(
-		config.Conf.Booking.APIAddress,
-		config.Conf.Booking.APIPort,
-		config.Conf.Booking.APIKey,
-	)
+	bookingClient := NewBookingClient()
 
 	// Initialise the server pool.
-	pool = &servers.APIServerPool{Tag: config.Conf.Booking.Tag, APIClient: bookingClient}
-	err := pool.Initialise()
+	var err error
+	pool, err = NewServerPool(bookingClient)
 	if err != nil {
-		log.Println(err)
+		log.WithError(err).Error("Failed to initialise the server pool")
 		return
 	}
 
 	SetupCron()
 
-	// Setup the Redis client
-	// and PING it to make sure we properly connected
-	// and can issue commands to it.
-	client := redis.NewClient(&redis.Options{
-		Addr:     config.Conf.Redis.Address,
-		Password: config.Conf.Redis.Password,
-		DB:       config.Conf.Redis.DB,
-	})
-
-	_, err = client.Ping().Result()
+	client, err := ConnectRedis()
 	if err != nil {
 		// Application won't work without a Redis connection.
-		log.Fatalln("Redis ping failed:", err)
+		log.WithError(err).Fatal("Redis ping failed")
 	}
 	globals.RedisClient = client
 
-	// Create Redis scripts.
-	// Check if the user has already booked a server out.
-	GetDefaultValue = redis.NewScript(`
-		local value = redis.call("GET", KEYS[1])
-		if (not value) then
-			redis.call("SET", KEYS[1], ARGV[1])
-			return ARGV[1]
-		end
-		return value
-	`)
+	// Create the webhook dispatcher, queueing deliveries in Redis so they survive restarts.
+	if len(config.Get().Webhooks) > 0 {
+		endpoints := make([]webhooks.Endpoint, len(config.Get().Webhooks))
+		for i, webhook := range config.Get().Webhooks {
+			endpoints[i] = webhooks.Endpoint{URL: webhook.URL, Secret: webhook.Secret, Events: webhook.Events}
+		}
+
+		Webhooks = webhooks.New(endpoints, webhooks.NewRedisQueue(client, webhooks.DefaultQueueKey))
+		Audit = append(Audit, Webhooks)
+	}
+
+	// Create the booking manager.
+	Bookings = booking.New(pool, globals.RedisClient)
+	Bookings.Events = Events
+	SetupSubscribers()
+
+	// Record the finished bookings for the booking history.
+	History = history.New(globals.RedisClient, history.DefaultKey)
+	History.Subscribe(Events)
 
 	// Attempt to update all our servers (that we just got from the server pool) with the information from Redis.
 	// If no Redis entry exists, update Redis with the default server information.
@@ -127,113 +223,259 @@ func RunServer(ctx *cli.Context) {
 
 	// Create the loghandler server
 	// and bind it to the appropriate address & port.
-	logs, err := loghandler.Dial(config.Conf.LogServer.LogAddress, config.Conf.LogServer.LogPort)
+	logs, err := loghandler.Dial(config.Get().LogServer.LogAddress, config.Get().LogServer.LogPort, pool)
 	if err != nil {
 		// Loghandler server couldn't bind properly.
 		// Not a problem, results in ingame commands not being received by the
 		// booking bot.
-		log.Println("LogHandler bind failed:", err)
-		log.Println("NOTE: This will disable ingame commands from functioning correctly.")
+		log.WithError(err).Warn("LogHandler bind failed, ingame commands are disabled")
 	} else {
-		log.Println(fmt.Sprintf("LogHandler listening on %s:%d", logs.Address, logs.Port))
+		log.WithFields(log.Fields{"address": logs.Address, "port": logs.Port}).Info("LogHandler listening")
+
+		logs.AddHandler(IngameMessageCreate)
 	}
 
-	logs.AddHandler(IngameMessageCreate)
+	// Check the dependencies, so the presence & health endpoints show when they're failing.
+	// The health endpoints serve the last report, which is refreshed every minute.
+	Health = NewHealthChecker(bookingClient, logs)
+	go Health.Run()
 
 	// Register the commands and their command handlers.
 	Command = commands.New("")
+	Command.Bookings = Bookings
+	Command.Use(
+		commands.Logging,
+		commands.Metrics,
+		commands.Recover(NotifyCommandPanic),
+		commands.CheckPermissions,
+		commands.CheckRateLimit,
+	)
 	Command.Add(
-		commands.NewCommand(SyncServers),
+		commands.NewCommand(SyncServers).
+			Description("Reconcile the servers with the booking API").
+			Category("Moderation").
+			Permissions(discordgo.PermissionManageServer).
+			Group("moderation").
+			RespondToDM(true),
 		"sync",
 	)
 	Command.Add(
-		commands.NewCommand(Help),
+		commands.NewCommand(Help).
+			Params(parser.Param{Name: "command", Type: parser.Text, Optional: true}).
+			Description("Display the help message (you're reading it!)").
+			RespondToDM(true),
 		"help",
 		"/help",
 	)
 	Command.Add(
-		commands.NewCommand(DemoLink),
+		commands.NewCommand(DemoLink).
+			Params(parser.Param{Name: "target", Type: parser.Text, Optional: true}).
+			Description("Send the link to the uploaded demos").
+			Category("Booking"),
 		"demo",
 		"demos",
 		"/demo",
 		"/demos",
 	)
 	Command.Add(
-		commands.NewCommand(BookServer),
+		commands.NewCommand(BookServer).
+			Description("Book a new server").
+			Category("Booking"),
 		"book",
 		"/book",
 	)
 	Command.Add(
-		commands.NewCommand(UnbookServer),
-		"return",
+		commands.NewCommand(UnbookServer).
+			Use(RequireBooking).
+			Description("Unbook your current server").
+			Category("Booking"),
 		"unbook",
+		"return",
 		"/return",
 		"/unbook",
 	)
 	Command.Add(
-		commands.NewCommand(ExtendServer),
+		commands.NewCommand(ExtendServer).
+			Use(RequireBooking).
+			Params(parser.Param{Name: "duration", Type: parser.Duration, Optional: true}).
+			Description("Extend your current booking, eg. `extend 30m`").
+			Category("Booking"),
 		"extend",
 		"/extend",
 	)
 	Command.Add(
-		commands.NewCommand(SendPassword),
+		commands.NewCommand(TimeLeft).
+			Use(RequireBooking).
+			Description("Display the time left in your booking").
+			Category("Booking"),
+		"time",
+	)
+	Command.Add(
+		commands.NewCommand(SendPassword).
+			Use(RequireBooking).
+			Description("Send the updated server details").
+			Category("Booking"),
 		"send password",
 		"/string",
 	)
 	Command.Add(
 		commands.NewCommand(PrintStats).
+			Description("Display the status of all servers").
+			Category("Moderation").
 			Permissions(discordgo.PermissionManageServer).
+			Group("moderation").
 			RespondToDM(true),
 		"stats",
 	)
+	Command.Add(
+		commands.NewCommand(ForceUnbookServer).
+			Params(parser.Param{Name: "server", Type: parser.Text}).
+			Description("Unbook a server, regardless of who booked it").
+			Category("Moderation").
+			Permissions(discordgo.PermissionManageServer).
+			Group("moderation").
+			RespondToDM(true),
+		"force unbook",
+	)
+	Command.Add(
+		commands.NewCommand(Language).
+			Params(parser.Param{Name: "locale", Type: parser.String, Optional: true}).
+			Description("Set the language you're sent messages in").
+			RespondToDM(true),
+		"language",
+	)
+	Command.Add(
+		commands.NewCommand(ExplainPermissions).
+			Description("Display the commands you have access to, and why").
+			RespondToDM(true),
+		"permissions",
+	)
 	Command.Add(
 		commands.NewCommand(Update).
+			Params(parser.Param{Name: "tag", Type: parser.String}).
+			Description("Update the bot to a release").
+			Category("Admin").
 			Permissions(discordgo.PermissionManageServer).
+			Group("admin").
 			RespondToDM(true),
 		"update",
 	)
 	Command.Add(
 		commands.NewCommand(Exit).
+			Description("Stop the bot").
+			Category("Admin").
 			Permissions(discordgo.PermissionManageServer).
+			Group("admin").
 			RespondToDM(true),
 		"exit",
 	)
+	Command.Add(
+		commands.NewCommand(Reload).
+			Description("Reload the configuration file").
+			Category("Admin").
+			Permissions(discordgo.PermissionManageServer).
+			Group("admin").
+			RespondToDM(true),
+		"reload",
+	)
 	Command.Add(
 		commands.NewCommand(Version).
+			Description("Display the running revision of the bot").
+			Category("Admin").
 			Permissions(discordgo.PermissionManageServer).
+			Group("admin").
 			RespondToDM(true),
 		"version",
 	)
 
+	// Reply to the commands in the language of each user.
+	commands.UserLocale = UserLocale
+
+	// Register the slash commands and their command handlers.
+	SlashCommand = commands.NewSlashCommand()
+	SlashCommand.Use(
+		commands.Logging,
+		commands.Metrics,
+		commands.Recover(NotifyCommandPanic),
+	)
+	RegisterSlashCommands(SlashCommand)
+
+	// Register the handlers for the buttons attached to booking messages.
+	ComponentCommand = commands.NewComponentCommand()
+	ComponentCommand.Use(
+		commands.Logging,
+		commands.Metrics,
+		commands.Recover(NotifyCommandPanic),
+	)
+	RegisterButtons(ComponentCommand)
+
 	// Register the ingame commands and their command handlers.
 	IngameCommand = ingame.New("!")
+	IngameCommand.Use(
+		commands.Logging,
+		commands.Metrics,
+		commands.Recover(NotifyCommandPanic),
+		commands.CheckRateLimit,
+	)
 	IngameCommand.Add(
-		ingame.NewCommand(ReportServer),
+		ingame.NewCommand(ReportServer).
+			Params(parser.Param{Name: "reason", Type: parser.Text}).
+			Description("Report the server to the admins"),
 		"report",
 	)
 	IngameCommand.Add(
-		ingame.NewCommand(TimeLeft),
+		ingame.NewCommand(TimeLeft).
+			Use(RequireBooking).
+			Description("Display the time left in the booking"),
 		"time",
 	)
+	IngameCommand.Add(
+		ingame.NewCommand(ExtendServer).
+			Use(RequireBooking).
+			Params(parser.Param{Name: "duration", Type: parser.Duration, Optional: true}).
+			Description("Extend the booking").
+			Group("ingame"),
+		"extend",
+	)
+	IngameCommand.Add(
+		ingame.NewCommand(UnbookServer).
+			Use(RequireBooking).
+			Description("Unbook the server").
+			Group("ingame"),
+		"unbook",
+		"return",
+	)
+	IngameCommand.Add(
+		ingame.NewCommand(Language).
+			Params(parser.Param{Name: "locale", Type: parser.String, Optional: true}).
+			Description("Set the language you're sent messages in"),
+		"language",
+	)
+	IngameCommand.Add(
+		ingame.NewCommand(IngameHelp).
+			Params(parser.Param{Name: "command", Type: parser.Text, Optional: true}).
+			Description("Display the ingame commands"),
+		"help",
+	)
 
-	// Create maps.
-	UserReportTimeouts = make(map[string]time.Time)
+	// Create the report rate limiter.
+	ReportLimiter = ratelimit.New()
 
 	// Create the Discord client from the bot token in the configuration.
-	dg, err := discordgo.New(fmt.Sprintf("Bot %s", config.Conf.Discord.Token))
+	dg, err := discordgo.New(fmt.Sprintf("Bot %s", config.Get().Discord.Token))
 	if err != nil {
-		log.Println("Discord session creation failed:", err)
+		log.WithError(err).Error("Discord session creation failed")
 		return
 	}
 
-	if config.Conf.Discord.Debug {
+	if config.Get().Logging.DiscordDebug {
 		dg.LogLevel = discordgo.LogDebug
 	}
 
 	// Get user information of the Discord user that is currently logged in (the bot).
 	u, err := dg.User("@me")
 	if err != nil {
-		log.Println("Discord bot information obtain failed:", err)
+		log.WithError(err).Error("Discord bot information obtain failed")
 		return
 	}
 
@@ -241,20 +483,42 @@ func RunServer(ctx *cli.Context) {
 	Session = dg
 	BotID = u.ID
 
+	// Create the audit log, if an audit channel is configured.
+	if config.Get().Discord.AuditChannel != "" {
+		AuditLog = audit.NewChannel(dg, config.Get().Discord.AuditChannel)
+		Audit = append(Audit, AuditLog)
+	}
+
+	metrics.Default.OnCollect(CollectServerMetrics)
+
+	// Serve the metrics, health checks & REST API, once the Discord session can be used to look up the API users.
+	if config.Get().HTTP.Address != "" {
+		go ServeHTTP()
+	}
+
 	// Register the OnReady handler.
 	dg.AddHandler(OnReady)
 
 	// Open the Discord websocket.
 	err = dg.Open()
 	if err != nil {
-		log.Println("Discord websocket opening failed:", err)
+		log.WithError(err).Error("Discord websocket opening failed")
 		return
 	}
 
+	// Reconcile any state that became inconsistent while the bot wasn't running.
+	go CronReconcile()
+
+	// Apply configuration changes without restarting, on SIGHUP or when the file is saved.
+	go WatchConfiguration()
+
 	// Keep running until Control-C pressed.
 	// <-make(chan struct{})
 	wait.Wait()
 
+	// Post any batched audit events before disconnecting.
+	AuditLog.Flush()
+
 	Session.Close()
 
 	// Stop cron.
@@ -264,12 +528,12 @@ func RunServer(ctx *cli.Context) {
 // OnReady handler for Discord.
 // Called when the connection has been completely setup.
 func OnReady(s *discordgo.Session, r *discordgo.Ready) {
-	log.Println("Updating game string with currently booked servers.")
+	log.Debug("Updating game string with currently booked servers")
 	err := UpdateGameString()
 	if err != nil {
-		log.Println("Game string update failed:", err)
+		log.WithError(err).Error("Game string update failed")
 	} else {
-		log.Println("Successfully updated game string.")
+		log.Debug("Successfully updated game string")
 	}
 
 	// Register a message create handler.
@@ -280,7 +544,18 @@ func OnReady(s *discordgo.Session, r *discordgo.Ready) {
 	}
 	MessageCreateFunc = s.AddHandler(MessageCreate)
 
-	log.Println("Discord bot successfully started.")
+	if InteractionCreateFunc != nil {
+		InteractionCreateFunc()
+	}
+	InteractionCreateFunc = s.AddHandler(InteractionCreate)
+
+	// Register the slash commands with Discord, replacing any that were previously registered.
+	err = SlashCommand.Register(s, r.User.ID, config.Get().Discord.GuildID)
+	if err != nil {
+		log.WithError(err).Error("Slash command registration failed")
+	}
+
+	log.Info("Discord bot successfully started")
 }
 
 // MessageCreate handler for Discord.
@@ -291,47 +566,86 @@ func MessageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
 		return
 	}
 
-	permissionsChannelID := m.ChannelID
-
 	// Lookup Discord channel.
 	channel, err := s.State.Channel(m.ChannelID)
 	if err != nil {
-		log.Println("Channel lookup failed:", err)
-	}
-
-	if channel.Type == discordgo.ChannelTypeDM {
-		permissionsChannelID = config.Conf.Discord.DefaultChannel
+		log.WithError(err).WithField("channel_id", m.ChannelID).Error("Channel lookup failed")
 	}
 
 	// Configuration has a string slice containing channels the bot should operate in.
 	// If the channel of the newly received message is not in the slice, stop now.
-	if !util.Contains(config.Conf.Discord.AcceptableChannels, m.ChannelID) && channel.Type != discordgo.ChannelTypeDM {
+	if !util.Contains(config.Get().Discord.AcceptableChannels, m.ChannelID) && channel.Type != discordgo.ChannelTypeDM {
 		return
 	}
 
+	Permissions := MessagePermissions(m)
+
+	// Send the message content to the command handler to be dispatched appropriately.
+	Command.Handle(Session, m, m.Content, Permissions)
+}
+
+// MessagePermissions looks up the Discord permissions of the message author in the channel the message was sent in.
+// Direct messages use the permissions from the default channel.
+func MessagePermissions(m *discordgo.MessageCreate) int64 {
+	permissionsChannelID := m.ChannelID
+
+	channel, err := Session.State.Channel(m.ChannelID)
+	if err == nil && channel.Type == discordgo.ChannelTypeDM {
+		permissionsChannelID = config.Get().Discord.DefaultChannel
+	}
+
 	Permissions, err := Session.State.UserChannelPermissions(m.Author.ID, permissionsChannelID)
 	if err != nil {
-		// Grab the timestamp of this error in GMT+10 time.
-		gmt10 := time.FixedZone("GMT+10", 10*60*60)
-		timestamp := time.Now().In(gmt10)
-
-		log.Println("discord error: failed to lookup permissions.", err, fmt.Sprintf("(id %s name %s time %s)", m.Author.ID, m.Author.Username, timestamp.String()))
+		logging.User(m.Author.ID).WithError(err).WithField("username", m.Author.Username).Error("Failed to lookup Discord permissions")
 
 		// Assume permissions = 0
-		Permissions = 0
+		return 0
+	}
 
-		// Session.ChannelMessageSend(m.ChannelID, fmt.Sprintf("%s: Sorry, we couldn't look up your Discord permissions, please contact an admin for assistance. (id %s time %s)", fmt.Sprintf("<@%s>", m.Author.ID), m.Author.ID, timestamp.String()))
+	return Permissions
+}
+
+// InteractionCreate handler for Discord.
+// Called when a user uses one of the bot's slash commands, or presses a button on one of its messages.
+func InteractionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Type == discordgo.InteractionMessageComponent {
+		ComponentCommand.Handle(s, i)
+		return
 	}
 
-	// Send the message content to the command handler to be dispatched appropriately.
-	Command.Handle(Session, m, strings.ToLower(m.Content), Permissions)
+	if i.Type != discordgo.InteractionApplicationCommand {
+		return
+	}
+
+	user := commands.InteractionUser(i)
+
+	// Slash commands from guild channels are limited to the acceptable channels, same as text commands.
+	if i.GuildID != "" && !util.Contains(config.Get().Discord.AcceptableChannels, i.ChannelID) {
+		commands.Respond(s, i, Text(user.ID, "command.wrong_channel", nil), true)
+		return
+	}
+
+	var Permissions int64
+	if i.Member != nil {
+		Permissions = i.Member.Permissions
+	} else {
+		// Direct messages use the permissions from the default channel.
+		var err error
+		Permissions, err = s.State.UserChannelPermissions(user.ID, config.Get().Discord.DefaultChannel)
+		if err != nil {
+			logging.User(user.ID).WithError(err).WithField("username", user.Username).Error("Failed to lookup Discord permissions")
+			Permissions = 0
+		}
+	}
+
+	SlashCommand.Handle(s, i, Permissions)
 }
 
 // IngameMessageCreate handler for the ingame TF2 log handler.
 // Called when a message is sent in any TF2 server that is logging to the remote logging server.
 func IngameMessageCreate(lh *loghandler.LogHandler, server *servers.Server, event *loghandler.SayEvent) {
-	log.Println(fmt.Sprintf("Received command from '%s' on server '%s': %s", event.Username, server.Name, event.Message))
-	IngameCommand.Handle(ingame.CommandInfo{SayEvent: *event, Server: server}, event.Message, 0)
+	server.Log().WithFields(log.Fields{"steam_id": event.SteamID, "username": event.Username, "message": event.Message}).Info("Received ingame command")
+	IngameCommand.Handle(commands.NewTF2CommandInformation(server, event.SteamID, event.Username), event.Message)
 }
 
 // SetupCron creates the cron scheduler and adds the functions and their respective schedules.
@@ -342,6 +656,17 @@ func SetupCron() {
 
 	c.AddFunc("@every 1m", Cron1Minute)
 
+	// Retry any failed webhook deliveries.
+	c.AddFunc("@every 10s", func() { Webhooks.Deliver() })
+
+	reconcileSchedule := config.Get().Reconcile.Schedule
+	if reconcileSchedule == "" {
+		reconcileSchedule = DefaultReconcileSchedule
+	}
+	if err := c.AddFunc(reconcileSchedule, CronReconcile); err != nil {
+		log.WithError(err).Error("Failed to schedule state reconciliation")
+	}
+
 	c.Start()
 }
 
@@ -0,0 +1,132 @@
+package messages
+
+// English is the built-in English catalogue, used for any message missing from the other locales.
+// Messages are Go templates, see the funcs for the functions available to them.
+var English = map[string]string{
+	// Errors returned by the booking manager.
+	"booking.already_booked":     "You've already booked a server. Type `unbook` to return the server.",
+	"booking.not_booked":         "You haven't booked a server. Type `book` to book a server.",
+	"booking.no_servers":         "No servers are currently available.",
+	"booking.server_unavailable": "That server isn't available right now.",
+	"booking.unknown_preset":     "That preset doesn't exist.",
+	"booking.failed":             "Something went wrong while trying to book your server, please try again later.",
+	"booking.max_duration":       "Your booking has reached the maximum booking length, and can't be extended.",
+	"booking.start_failed":       "Uh oh! The server failed to start, contact an admin for further information.",
+	"booking.stop_failed":        "Uh oh! The server failed to stop, contact an admin for further information, or leave us to handle it.",
+	"error":                      "Oops, looked like an error has occurred. Please contact an admin for assistance.",
+
+	// Server details sent after booking.
+	"details.content":       "**Here are the details for your booked server:**",
+	"details.address":       "Server Address",
+	"details.password":      "Server Password",
+	"details.rcon_password": "RCON Password",
+	"details.connect":       "Connect String",
+	"details.stv":           "STV String",
+	"details.password_message": "Here is your server details:\n" +
+		"\tServer address: {{.Address}}\n" +
+		"\tPassword: {{.Password}}\n" +
+		"\tConnect string: `connect {{.Address}}; password {{.Password}}`",
+
+	// Booking commands.
+	"book.details_sent":      "Server details have been sent via private message.",
+	"unbook.returned":        "Server returned.",
+	"unbook.returned_ingame": "Your server was returned by `{{.Username}}` ingame.",
+	"extend.extended":        "Your booking has been extended by {{duration .Duration}}.",
+	"time.no_return":         "The booking doesn't have a return time.",
+	"time.left":              "{{duration .Duration}} remaining in booking.",
+	"password.sent":          "Server password have been sent via private message.",
+	"password.failed":        "We failed to retrieve your server password.",
+	"demos.link":             "https://stv.qixalite.com/?q={{query .Target}}",
+	"demos.none":             "No demos were uploaded, demos are only available once a recording has finished.",
+
+	// Refusals of any command.
+	"command.no_dm":         "That command can't be used in direct messages.",
+	"command.wrong_channel": "Booking commands can't be used in this channel.",
+	"command.forbidden":     "You don't have permission for that command.",
+	"command.rate_limited":  "Slow down! Try again in {{duration .RetryAfter}}.",
+
+	// Buttons of the booking message, and the lines describing the booking under it.
+	"button.extend":     "Extend",
+	"button.password":   "Resend password",
+	"button.demos":      "Upload demos",
+	"button.unbook":     "Unbook",
+	"button.not_yours":  "That isn't your booking.",
+	"button.ended":      "This booking has ended.",
+	"button.extended":   "Booking extended by {{duration .Duration}}.",
+	"booking.booked_at": "Booked at {{.Time}}.",
+
+	// Help & general commands.
+	"help.list":          "**Qixalite Bookable Help**:```{{.Commands}}```Type `help <command>` for the details of a command.",
+	"help.unknown":       "Unknown command `{{.Command}}`, type `help` for a list of commands.",
+	"permissions.groups": "Your permission groups: {{.Groups}}.",
+	"permissions.none":   "You aren't in any permission groups.",
+	"permissions.list":   "{{.Groups}}\nYou may run: {{.Commands}}",
+	"language.current":   "Your language is `{{.Locale}}`. Available languages: {{.Locales}}.",
+	"language.unknown":   "The language `{{.Locale}}` isn't available. Available languages: {{.Locales}}.",
+	"language.set":       "Your language has been set to `{{.Locale}}`.",
+
+	// Moderation & admin commands.
+	"sync.failed":             "Failed to reconcile servers, check the logs for details.",
+	"sync.done":               "Synchronised all servers, found {{.Count}} inconsistencies.",
+	"stats.header":            "Server stats:",
+	"force_unbook.unknown":    "That server doesn't exist.",
+	"force_unbook.not_booked": "That server isn't booked.",
+	"force_unbook.failed":     "Failed to unbook the server.",
+	"force_unbook.done":       "Server `{{.Server}}` has been unbooked.",
+	"force_unbook.notify":     "Your server was unbooked by an admin.",
+	"update.release_failed":   "Failed to retrieve release.",
+	"update.asset_failed":     "Failed to retrieve release asset.",
+	"update.starting":         "Starting update to release {{.Release}}",
+	"update.restarting":       "Updated `tf2-booking` & restarting now.",
+	"version":                 "`tf2-booking` running git revision `{{.Version}}`",
+	"exit":                    "Shutting down `tf2-booking`.",
+	"reload.failed":           "Failed to reload the configuration, the current configuration is kept:```{{.Error}}```",
+	"reload.unchanged":        "Reloaded the configuration, nothing has changed.",
+	"reload.done":             "Reloaded the configuration:```{{.Changes}}```",
+	"command.panicked":        "Command `{{.Command}}` from `{{.Username}}` ({{.UserID}}) in `{{.Channel}}` panicked: {{.Error}}",
+
+	// Automatic unbooking.
+	"auto_unbook.unbooked": "Your server was automatically unbooked ({{.Reason}}).",
+	"auto_unbook.expired":  "booking time is up",
+	"auto_unbook.idle":     "not enough players",
+	"auto_unbook.stopped":  "the server stopped",
+
+	// Server query errors sent to the admins.
+	"query_error.booked":   "The server `{{.Server}}` failed to be contacted after {{.Retries}} retries after being booked by `{{.Booker}}`. Check to ensure the server is correctly working.",
+	"query_error.unbooked": "The server `{{.Server}}` failed to be contacted after {{.Retries}} retries while unbooked. Check to ensure the server is correctly working.",
+
+	// Ingame commands, replied to in the server's chat.
+	"ingame.usage":    "Usage: {{.Usage}}",
+	"ingame.help":     "Commands: {{.Commands}} (type {{.Prefix}}help <command> for details)",
+	"ingame.unknown":  "Unknown command, type {{.Prefix}}help for a list of commands.",
+	"report.too_soon": "You can't report that quickly! Try again in a few minutes.",
+	"report.sent":     "Server reported! Thank you for your input.",
+
+	// Ingame reports sent to the admins.
+	"report.notify": "Server `{{.Server}}` has been reported by `{{.Username}}` ({{.Profile}}) with reason: '{{.Reason}}'",
+
+	// Inconsistencies found by the state reconciler, sent to the admins.
+	"reconcile.found":                  "State reconciliation found the following inconsistencies:",
+	"reconcile.unbooked_server":        "Server `{{.Server}}` is running without a booking.",
+	"reconcile.unbooked_server.fixed":  "Server `{{.Server}}` was running without a booking and has been stopped.",
+	"reconcile.unbooked_server.failed": "Server `{{.Server}}` is running without a booking, but failed to stop: {{.Error}}",
+	"reconcile.stopped_booking":        "Server `{{.Server}}` is booked by `{{.Booker}}` but not running.",
+	"reconcile.stopped_booking.fixed":  "Server `{{.Server}}` was booked by `{{.Booker}}` but not running, and has been unbooked.",
+	"reconcile.orphaned_user":          "User `{{.User}}` is mapped to server `{{.Server}}` which they haven't booked.",
+	"reconcile.orphaned_user.fixed":    "User `{{.User}}` was mapped to server `{{.Server}}` which they haven't booked, and has been cleared.",
+	"reconcile.orphaned_user.failed":   "User `{{.User}}` is mapped to server `{{.Server}}` which they haven't booked, but failed to clear: {{.Error}}",
+
+	// Status board of every server, in the status channel.
+	"status_board.title":       "Server Status",
+	"status_board.booked":      "Booked",
+	"status_board.maintenance": "Maintenance",
+	"status_board.available":   "Available",
+	"status_board.unavailable": "Unavailable",
+	"status_board.more":        "{{.Count}} more servers not shown.",
+	"status_board.server": "**{{.State}}**\nMap: `{{.Map}}`\nPlayers: `{{.Players}}`" +
+		"{{if .Booker}}\nBooker: {{.Booker}}{{end}}" +
+		"{{if .TimeLeft}}\nTime left: {{duration .TimeLeft}}{{end}}",
+
+	// Sent to players on the server.
+	"server.kick": "Server has been unbooked!",
+}
@@ -0,0 +1,163 @@
+package messages
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"alex-j-butler.com/tf2-booking/util"
+
+	log "github.com/Sirupsen/logrus"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// EnglishLocale is the locale of the built-in English messages.
+const EnglishLocale = "en"
+
+// Data is the data passed to a message template.
+type Data map[string]interface{}
+
+// funcs are the functions available to message templates.
+var funcs = template.FuncMap{
+	// duration formats a duration to be human readable, eg. "1 hour 30 minutes".
+	"duration": func(d time.Duration) string {
+		return util.ToHuman(&d)
+	},
+	// query escapes a string to be used in a URL query.
+	"query": url.QueryEscape,
+}
+
+// Catalogue holds the message templates of each locale.
+// Messages missing from a locale fall back to the default locale, then the built-in English messages.
+type Catalogue struct {
+	DefaultLocale string
+
+	locales map[string]map[string]*template.Template
+}
+
+// Default is the catalogue used to render messages, which only has the built-in English messages
+// until a catalogue is loaded.
+var Default = mustParseEnglish()
+
+func mustParseEnglish() *Catalogue {
+	c := &Catalogue{
+		DefaultLocale: EnglishLocale,
+		locales:       make(map[string]map[string]*template.Template),
+	}
+
+	if err := c.Add(EnglishLocale, English); err != nil {
+		panic(err)
+	}
+
+	return c
+}
+
+// Load creates a catalogue from the built-in English messages and the locale files in the directory.
+// Each locale file is a YAML map of message keys to templates, named after its locale, eg. "de.yml".
+// An empty directory only loads the built-in English messages.
+func Load(directory string, defaultLocale string) (*Catalogue, error) {
+	c := mustParseEnglish()
+
+	if defaultLocale != "" {
+		c.DefaultLocale = defaultLocale
+	}
+
+	if directory != "" {
+		files, err := filepath.Glob(filepath.Join(directory, "*.yml"))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+
+			var texts map[string]string
+			if err := yaml.Unmarshal(data, &texts); err != nil {
+				return nil, fmt.Errorf("%s: %s", file, err)
+			}
+
+			locale := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+			if err := c.Add(locale, texts); err != nil {
+				return nil, fmt.Errorf("%s: %s", file, err)
+			}
+		}
+	}
+
+	if !c.Has(c.DefaultLocale) {
+		return nil, fmt.Errorf("default locale \"%s\" has no messages", c.DefaultLocale)
+	}
+
+	return c, nil
+}
+
+// Add parses the message templates of a locale, replacing any existing templates with the same keys.
+func (c *Catalogue) Add(locale string, texts map[string]string) error {
+	templates, ok := c.locales[locale]
+	if !ok {
+		templates = make(map[string]*template.Template)
+		c.locales[locale] = templates
+	}
+
+	for key, text := range texts {
+		tmpl, err := template.New(key).Funcs(funcs).Parse(text)
+		if err != nil {
+			return err
+		}
+
+		templates[key] = tmpl
+	}
+
+	return nil
+}
+
+// Has returns whether the catalogue has messages for the locale.
+func (c *Catalogue) Has(locale string) bool {
+	_, ok := c.locales[locale]
+	return ok
+}
+
+// Locales returns the locales in the catalogue, sorted alphabetically.
+func (c *Catalogue) Locales() []string {
+	locales := make([]string, 0, len(c.locales))
+	for locale := range c.locales {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+
+	return locales
+}
+
+// Render renders the message in the locale, falling back to the default locale & English.
+// The key is returned if no locale has the message.
+func (c *Catalogue) Render(locale string, key string, data interface{}) string {
+	for _, l := range []string{locale, c.DefaultLocale, EnglishLocale} {
+		tmpl, ok := c.locales[l][key]
+		if !ok {
+			continue
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			log.WithError(err).WithFields(log.Fields{"key": key, "locale": l}).Error("Failed to render message")
+			continue
+		}
+
+		return buf.String()
+	}
+
+	return key
+}
+
+// Render renders the message in the locale from the default catalogue.
+func Render(locale string, key string, data interface{}) string {
+	return Default.Render(locale, key, data)
+}
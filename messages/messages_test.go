@@ -0,0 +1,64 @@
+package messages
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRenderEnglish(t *testing.T) {
+	message := Default.Render("", "extend.extended", Data{"Duration": 90 * time.Minute})
+	if message != "Your booking has been extended by 1 hour 30 minutes." {
+		t.Fatalf("Unexpected message: %q", message)
+	}
+
+	if message := Default.Render("", "demos.link", Data{"Target": "a b"}); message != "https://stv.qixalite.com/?q=a+b" {
+		t.Fatalf("Unexpected message: %q", message)
+	}
+
+	if message := Default.Render("", "missing.key", nil); message != "missing.key" {
+		t.Fatalf("Expected a missing message to render as its key, got %q", message)
+	}
+}
+
+func TestLoadFallsBack(t *testing.T) {
+	directory, err := ioutil.TempDir("", "messages")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+
+	locale := []byte("unbook.returned: \"Server zurückgegeben.\"\nexit: \"Tschüss {{.Name}}\"\n")
+	if err := ioutil.WriteFile(filepath.Join(directory, "de.yml"), locale, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Load(directory, "de")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if message := c.Render("", "unbook.returned", nil); message != "Server zurückgegeben." {
+		t.Fatalf("Expected the default locale to be used, got %q", message)
+	}
+
+	if message := c.Render("en", "unbook.returned", nil); message != "Server returned." {
+		t.Fatalf("Expected the requested locale to be used, got %q", message)
+	}
+
+	if message := c.Render("fr", "password.sent", nil); message != English["password.sent"] {
+		t.Fatalf("Expected a message missing from the locale to fall back to English, got %q", message)
+	}
+
+	if locales := c.Locales(); len(locales) != 2 || locales[0] != "de" || locales[1] != "en" {
+		t.Fatalf("Unexpected locales: %v", locales)
+	}
+}
+
+func TestLoadUnknownDefaultLocale(t *testing.T) {
+	if _, err := Load("", "fr"); err == nil {
+		t.Fatalf("Expected an error for a default locale without messages")
+	}
+}
@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"time"
+)
+
+// The bot's metrics, registered in the default registry.
+var (
+	BookingsStarted = Default.NewCounterVec("tf2booking_bookings_started_total", "Bookings started.")
+	BookingsEnded   = Default.NewCounterVec("tf2booking_bookings_ended_total", "Bookings ended, by the reason they ended.", "reason")
+
+	ServersAvailable = Default.NewGaugeVec("tf2booking_servers_available", "Servers available to be booked, by server pool tag.", "tag")
+	ServersBooked    = Default.NewGaugeVec("tf2booking_servers_booked", "Servers currently booked, by server pool tag.", "tag")
+
+	CommandInvocations = Default.NewCounterVec("tf2booking_command_invocations_total", "Commands run, by command & where they were run from.", "command", "source")
+	CommandLatency     = Default.NewHistogramVec("tf2booking_command_duration_seconds", "Time taken to run commands.", DefaultBuckets, "command")
+
+	BookingAPILatency = Default.NewHistogramVec("tf2booking_booking_api_duration_seconds", "Time taken by booking API calls from the server runner, by operation.", DefaultBuckets, "operation")
+	BookingAPIErrors  = Default.NewCounterVec("tf2booking_booking_api_errors_total", "Failed booking API calls from the server runner, by operation.", "operation")
+
+	RCONFailures = Default.NewCounterVec("tf2booking_rcon_failures_total", "Failed RCON commands, by server.", "server")
+	QueryErrors  = Default.NewCounterVec("tf2booking_query_errors_total", "Failed A2S server queries, by server.", "server")
+
+	LogPacketsReceived     = Default.NewCounterVec("tf2booking_log_packets_received_total", "Log packets received by the log handler.")
+	LogPacketsUnrecognised = Default.NewCounterVec("tf2booking_log_packets_unrecognised_total", "Log packets the log handler ignored, by why they weren't recognised.", "reason")
+)
+
+// ObserveBookingAPI records the latency of a booking API call that started at the time, and whether it failed.
+// Use it deferred, with a pointer to the call's named error result.
+func ObserveBookingAPI(operation string, start time.Time, err *error) {
+	BookingAPILatency.Observe(time.Since(start).Seconds(), operation)
+
+	if err != nil && *err != nil {
+		BookingAPIErrors.Inc(operation)
+	}
+}
@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	r := NewRegistry()
+
+	counter := r.NewCounterVec("test_events_total", "Events.", "reason")
+	counter.Inc("idle")
+	counter.Inc("idle")
+	counter.Add(3, `say "hi"`)
+
+	gauge := r.NewGaugeVec("test_servers", "Servers.")
+	r.OnCollect(func() { gauge.Set(4) })
+
+	var buf bytes.Buffer
+	r.Write(&buf)
+
+	expected := `# HELP test_events_total Events.
+# TYPE test_events_total counter
+test_events_total{reason="idle"} 2
+test_events_total{reason="say \"hi\""} 3
+# HELP test_servers Servers.
+# TYPE test_servers gauge
+test_servers 4
+`
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, buf.String())
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	r := NewRegistry()
+
+	histogram := r.NewHistogramVec("test_duration_seconds", "Durations.", []float64{0.1, 1}, "command")
+	histogram.Observe(0.05, "book")
+	histogram.Observe(0.5, "book")
+	histogram.Observe(5, "book")
+
+	var buf bytes.Buffer
+	r.Write(&buf)
+
+	for _, line := range []string{
+		`test_duration_seconds_bucket{command="book",le="0.1"} 1`,
+		`test_duration_seconds_bucket{command="book",le="1"} 2`,
+		`test_duration_seconds_bucket{command="book",le="+Inf"} 3`,
+		`test_duration_seconds_sum{command="book"} 5.55`,
+		`test_duration_seconds_count{command="book"} 3`,
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("Expected the line %s, got:\n%s", line, buf.String())
+		}
+	}
+}
+
+func TestWrongLabelCount(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected the wrong number of label values to panic")
+		}
+	}()
+
+	NewRegistry().NewCounterVec("test_total", "Test.", "a", "b").Inc("a")
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("test_total", "Test.").Inc()
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") || !strings.Contains(rec.Body.String(), "test_total 1\n") {
+		t.Errorf("Unexpected response %s: %s", rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+}
+
+func TestObserveBookingAPI(t *testing.T) {
+	before := BookingAPIErrors.Value("test")
+
+	var err error
+	ObserveBookingAPI("test", time.Now(), &err)
+	err = errors.New("failed")
+	ObserveBookingAPI("test", time.Now(), &err)
+
+	if BookingAPILatency.Count("test") != 2 || BookingAPIErrors.Value("test") != before+1 {
+		t.Errorf("Expected 2 calls & 1 error, got %d calls & %g errors", BookingAPILatency.Count("test"), BookingAPIErrors.Value("test")-before)
+	}
+}
@@ -0,0 +1,134 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// pruneInterval is how often buckets that have refilled are removed from the limiter.
+const pruneInterval = time.Minute
+
+// Limit allows a number of requests over a period of time.
+// A limit with no requests is unlimited.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// Unlimited returns whether the limit doesn't restrict requests.
+func (l Limit) Unlimited() bool {
+	return l.Requests <= 0 || l.Per <= 0
+}
+
+// Result is the outcome of a request against a limiter.
+type Result struct {
+	// Allowed is whether the request may proceed.
+	Allowed bool
+
+	// Notify is set on the first denied request since the last allowed request,
+	// so that the user is only told to slow down once.
+	Notify bool
+
+	// RetryAfter is how long until the next request will be allowed.
+	RetryAfter time.Duration
+}
+
+type bucket struct {
+	tokens   float64
+	last     time.Time
+	limit    Limit
+	notified bool
+}
+
+// refill adds the tokens gained since the bucket was last used.
+func (b *bucket) refill(now time.Time) {
+	rate := float64(b.limit.Requests) / float64(b.limit.Per)
+
+	b.tokens += float64(now.Sub(b.last)) * rate
+	if b.tokens > float64(b.limit.Requests) {
+		b.tokens = float64(b.limit.Requests)
+	}
+	b.last = now
+}
+
+// Limiter is a token bucket rate limiter, holding a separate bucket for each key.
+type Limiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastPrune time.Time
+
+	// Now returns the current time, it can be replaced for testing.
+	Now func() time.Time
+}
+
+// New creates a new rate limiter.
+func New() *Limiter {
+	return &Limiter{
+		buckets: make(map[string]*bucket),
+		Now:     time.Now,
+	}
+}
+
+// Allow takes a token from the bucket for the key, creating a full bucket for the limit if none exists.
+func (l *Limiter) Allow(key string, limit Limit) Result {
+	if limit.Unlimited() {
+		return Result{Allowed: true}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.Now()
+	l.prune(now)
+
+	b, ok := l.buckets[key]
+	if !ok || b.limit != limit {
+		b = &bucket{
+			tokens: float64(limit.Requests),
+			last:   now,
+			limit:  limit,
+		}
+		l.buckets[key] = b
+	}
+
+	b.refill(now)
+
+	if b.tokens >= 1 {
+		b.tokens--
+		b.notified = false
+		return Result{Allowed: true}
+	}
+
+	rate := float64(limit.Requests) / float64(limit.Per)
+	result := Result{
+		Allowed:    false,
+		Notify:     !b.notified,
+		RetryAfter: time.Duration((1 - b.tokens) / rate),
+	}
+	b.notified = true
+
+	return result
+}
+
+// Reset removes the bucket for the key, allowing requests again immediately.
+func (l *Limiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.buckets, key)
+}
+
+// prune removes the buckets that have completely refilled, as they're equivalent to a new bucket.
+func (l *Limiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < pruneInterval {
+		return
+	}
+	l.lastPrune = now
+
+	for key, b := range l.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.limit.Requests) {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestLimiter() (*Limiter, *time.Time) {
+	now := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := New()
+	l.Now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestAllowBurst(t *testing.T) {
+	l, _ := newTestLimiter()
+	limit := Limit{Requests: 3, Per: time.Minute}
+
+	for i := 0; i < 3; i++ {
+		if !l.Allow("user", limit).Allowed {
+			t.Fatalf("Expected request %d to be allowed", i+1)
+		}
+	}
+
+	if l.Allow("user", limit).Allowed {
+		t.Fatalf("Expected request 4 to be denied")
+	}
+}
+
+func TestAllowRefill(t *testing.T) {
+	l, now := newTestLimiter()
+	limit := Limit{Requests: 2, Per: time.Minute}
+
+	l.Allow("user", limit)
+	l.Allow("user", limit)
+
+	result := l.Allow("user", limit)
+	if result.Allowed {
+		t.Fatalf("Expected request to be denied")
+	}
+	if result.RetryAfter != 30*time.Second {
+		t.Fatalf("Expected retry after 30s but got %s", result.RetryAfter)
+	}
+
+	*now = now.Add(30 * time.Second)
+
+	if !l.Allow("user", limit).Allowed {
+		t.Fatalf("Expected request to be allowed after refill")
+	}
+}
+
+func TestNotifyOnce(t *testing.T) {
+	l, now := newTestLimiter()
+	limit := Limit{Requests: 1, Per: time.Minute}
+
+	l.Allow("user", limit)
+
+	if result := l.Allow("user", limit); !result.Notify {
+		t.Fatalf("Expected first denied request to notify")
+	}
+	if result := l.Allow("user", limit); result.Notify {
+		t.Fatalf("Expected second denied request not to notify")
+	}
+
+	*now = now.Add(time.Minute)
+	l.Allow("user", limit)
+
+	if result := l.Allow("user", limit); !result.Notify {
+		t.Fatalf("Expected denied request after an allowed request to notify")
+	}
+}
+
+func TestSeparateKeys(t *testing.T) {
+	l, _ := newTestLimiter()
+	limit := Limit{Requests: 1, Per: time.Minute}
+
+	if !l.Allow("user1", limit).Allowed || !l.Allow("user2", limit).Allowed {
+		t.Fatalf("Expected each key to have its own bucket")
+	}
+}
+
+func TestUnlimited(t *testing.T) {
+	l, _ := newTestLimiter()
+
+	for i := 0; i < 100; i++ {
+		if !l.Allow("user", Limit{}).Allowed {
+			t.Fatalf("Expected an empty limit to be unlimited")
+		}
+	}
+}
+
+func TestPrune(t *testing.T) {
+	l, now := newTestLimiter()
+	limit := Limit{Requests: 1, Per: time.Second}
+
+	l.Allow("user1", limit)
+
+	*now = now.Add(2 * time.Minute)
+	l.Allow("user2", limit)
+
+	if _, ok := l.buckets["user1"]; ok {
+		t.Fatalf("Expected refilled bucket to be pruned")
+	}
+}
+
+func TestReset(t *testing.T) {
+	l, _ := newTestLimiter()
+	limit := Limit{Requests: 1, Per: time.Hour}
+
+	l.Allow("user", limit)
+	l.Reset("user")
+
+	if !l.Allow("user", limit).Allowed {
+		t.Fatalf("Expected request to be allowed after reset")
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"alex-j-butler.com/tf2-booking/config"
+	"alex-j-butler.com/tf2-booking/globals"
+	"alex-j-butler.com/tf2-booking/logging"
+	"alex-j-butler.com/tf2-booking/messages"
+	"alex-j-butler.com/tf2-booking/servers"
+
+	log "github.com/Sirupsen/logrus"
+	redis "gopkg.in/redis.v5"
+)
+
+// Reconciliation policies, configured per inconsistency in the 'reconcile' config section.
+const (
+	// ReconcileFix corrects the inconsistency and reports the correction.
+	ReconcileFix = "fix"
+	// ReconcileReport only reports the inconsistency.
+	ReconcileReport = "report"
+	// ReconcileIgnore neither corrects nor reports the inconsistency.
+	ReconcileIgnore = "ignore"
+)
+
+// DefaultReconcileSchedule is the cron schedule used when none is configured.
+const DefaultReconcileSchedule = "@every 10m"
+
+// DefaultReconcileGracePeriod is the grace period used when none is configured.
+const DefaultReconcileGracePeriod = 2 * time.Minute
+
+// Reconciliation is a single inconsistency found between Redis and the booking API.
+type Reconciliation struct {
+	// Policy is the policy that was applied to the inconsistency.
+	Policy string
+
+	// Message describes the inconsistency, and the correction if one was made.
+	Message string
+}
+
+// reconcilePolicy returns the policy to use, defaulting to only reporting
+// when the configured policy is empty or unrecognised.
+func reconcilePolicy(policy string) string {
+	switch policy {
+	case ReconcileFix, ReconcileIgnore:
+		return policy
+	default:
+		return ReconcileReport
+	}
+}
+
+// ReconcileState compares the user mappings & server records stored in Redis against the
+// running state reported by the booking API, and corrects any inconsistencies according to
+// the configured policies.
+// Returns the inconsistencies that were found (excluding ignored ones).
+func ReconcileState() ([]Reconciliation, error) {
+	var reconciliations []Reconciliation
+
+	gracePeriod := config.Get().Reconcile.GracePeriod.Duration
+	if gracePeriod <= 0 {
+		gracePeriod = DefaultReconcileGracePeriod
+	}
+
+	// Maps server UUIDs to the server, for the user mapping check.
+	serverMap := make(map[string]*servers.Server)
+
+	// The user mappings can only be checked once every server's booking is known.
+	synchronised := true
+
+	for _, server := range pool.GetServers() {
+		// Synchronise the server from Redis, to get information for existing servers.
+		err := server.Synchronise(globals.RedisClient)
+		if err == redis.Nil {
+			// The server has no record, eg. after Redis was flushed, so it isn't booked.
+			server.ResetServerVars()
+		} else if err != nil {
+			server.Log().WithError(err).Error("Failed to Redis sync")
+			synchronised = false
+			continue
+		}
+
+		serverMap[server.UUID] = server
+
+		// Skip servers that the booking API can't be contacted about,
+		// we can't tell whether they're running or not.
+		if !server.Runner.IsAvailable(server) {
+			continue
+		}
+
+		// Skip servers that were only just booked, they may still be starting.
+		if server.Booked && time.Since(server.BookedDate) < gracePeriod {
+			continue
+		}
+
+		running := server.Runner.IsBooked(server)
+
+		if running && !server.Booked {
+			// Server is running without a booking.
+			if r, ok := reconcileUnbookedServer(server); ok {
+				reconciliations = append(reconciliations, r)
+			}
+		} else if !running && server.Booked {
+			// Server is booked, but the server has stopped.
+			if r, ok := reconcileStoppedBooking(server); ok {
+				reconciliations = append(reconciliations, r)
+			}
+		}
+	}
+
+	if !synchronised {
+		return reconciliations, errors.New("skipped checking the user mappings, as not every server could be synchronised")
+	}
+
+	// Scan the user mappings, checking they point at a server booked by that user.
+	var cursor uint64
+	for {
+		keys, nextCursor, err := globals.RedisClient.Scan(cursor, "user.*", 100).Result()
+		if err != nil {
+			return reconciliations, err
+		}
+
+		for _, key := range keys {
+			if r, ok := reconcileUserMapping(key, serverMap); ok {
+				reconciliations = append(reconciliations, r)
+			}
+		}
+
+		cursor = nextCursor
+		if cursor == 0 {
+			break
+		}
+	}
+
+	return reconciliations, nil
+}
+
+// reconcileUnbookedServer handles a server that is running without a booking.
+func reconcileUnbookedServer(server *servers.Server) (Reconciliation, bool) {
+	policy := reconcilePolicy(config.Get().Reconcile.UnbookedServers)
+
+	switch policy {
+	case ReconcileIgnore:
+		return Reconciliation{}, false
+	case ReconcileFix:
+		if err := server.Stop(); err != nil {
+			return Reconciliation{
+				Policy:  policy,
+				Message: AdminText("reconcile.unbooked_server.failed", messages.Data{"Server": server.Name, "Error": err}),
+			}, true
+		}
+
+		return Reconciliation{
+			Policy:  policy,
+			Message: AdminText("reconcile.unbooked_server.fixed", messages.Data{"Server": server.Name}),
+		}, true
+	}
+
+	return Reconciliation{
+		Policy:  policy,
+		Message: AdminText("reconcile.unbooked_server", messages.Data{"Server": server.Name}),
+	}, true
+}
+
+// reconcileStoppedBooking handles a server that is booked, but isn't running.
+func reconcileStoppedBooking(server *servers.Server) (Reconciliation, bool) {
+	policy := reconcilePolicy(config.Get().Reconcile.StoppedBookings)
+
+	switch policy {
+	case ReconcileIgnore:
+		return Reconciliation{}, false
+	case ReconcileFix:
+		booker := server.Booker
+
+		// Unbook the server through the booking manager, so the end of the booking is published.
+		if err := Bookings.UnbookStopped(server); err != nil {
+			logging.User(booker).WithError(err).Error("Failed to unbook stopped server")
+		}
+
+		return Reconciliation{
+			Policy:  policy,
+			Message: AdminText("reconcile.stopped_booking.fixed", messages.Data{"Server": server.Name, "Booker": booker}),
+		}, true
+	}
+
+	return Reconciliation{
+		Policy:  policy,
+		Message: AdminText("reconcile.stopped_booking", messages.Data{"Server": server.Name, "Booker": server.Booker}),
+	}, true
+}
+
+// reconcileUserMapping handles checking a single 'user.<id>' key, returning a reconciliation
+// if the server it points to doesn't exist or isn't booked by that user.
+func reconcileUserMapping(key string, serverMap map[string]*servers.Server) (Reconciliation, bool) {
+	uuid, err := globals.RedisClient.Get(key).Result()
+	if err != nil || uuid == "" {
+		return Reconciliation{}, false
+	}
+
+	userID := strings.TrimPrefix(key, "user.")
+
+	server, ok := serverMap[uuid]
+	if ok && server.Booked && server.Booker == userID {
+		return Reconciliation{}, false
+	}
+
+	policy := reconcilePolicy(config.Get().Reconcile.OrphanedUsers)
+
+	switch policy {
+	case ReconcileIgnore:
+		return Reconciliation{}, false
+	case ReconcileFix:
+		if err := globals.RedisClient.Set(key, "", 0).Err(); err != nil {
+			logging.User(userID).WithError(err).Error("Failed to set user information")
+
+			return Reconciliation{
+				Policy:  policy,
+				Message: AdminText("reconcile.orphaned_user.failed", messages.Data{"User": userID, "Server": uuid, "Error": err}),
+			}, true
+		}
+
+		return Reconciliation{
+			Policy:  policy,
+			Message: AdminText("reconcile.orphaned_user.fixed", messages.Data{"User": userID, "Server": uuid}),
+		}, true
+	}
+
+	return Reconciliation{
+		Policy:  policy,
+		Message: AdminText("reconcile.orphaned_user", messages.Data{"User": userID, "Server": uuid}),
+	}, true
+}
+
+// ReportReconciliations sends the inconsistencies found by the reconciler to the notification users.
+func ReportReconciliations(reconciliations []Reconciliation) {
+	if len(reconciliations) == 0 {
+		return
+	}
+
+	message := AdminText("reconcile.found", nil)
+	for _, r := range reconciliations {
+		log.WithField("policy", r.Policy).Warn("Reconcile: ", r.Message)
+
+		message = fmt.Sprintf("%s\n\t%s", message, r.Message)
+	}
+
+	NotifyAdmins(message)
+}
+
+// CronReconcile runs the state reconciler and reports the result.
+func CronReconcile() {
+	reconciliations, err := ReconcileState()
+	if err != nil {
+		log.WithError(err).Error("State reconciliation failed")
+	}
+
+	ReportReconciliations(reconciliations)
+
+	if len(reconciliations) > 0 {
+		UpdateGameString()
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"alex-j-butler.com/tf2-booking/booking"
+	"alex-j-butler.com/tf2-booking/config"
+	"alex-j-butler.com/tf2-booking/events"
+	"alex-j-butler.com/tf2-booking/globals"
+	"alex-j-butler.com/tf2-booking/servers"
+
+	redis "gopkg.in/redis.v5"
+)
+
+// fakeRedis is a Redis server supporting the GET, SET, DEL & SCAN commands used by the reconciler & state commands.
+type fakeRedis struct {
+	listener net.Listener
+
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &fakeRedis{listener: listener, values: make(map[string]string)}
+	go r.serve()
+
+	return r
+}
+
+func (r *fakeRedis) serve() {
+	for {
+		conn, err := r.listener.Accept()
+		if err != nil {
+			return
+		}
+		go r.handle(conn)
+	}
+}
+
+func (r *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+
+		io.WriteString(conn, r.execute(args))
+	}
+}
+
+// readCommand reads a command, sent as an array of bulk strings.
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		if _, err := reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		arg, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args[i] = strings.TrimSuffix(arg, "\r\n")
+	}
+
+	return args, nil
+}
+
+func (r *fakeRedis) execute(args []string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch strings.ToUpper(args[0]) {
+	case "GET":
+		value, ok := r.values[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return bulkString(value)
+	case "SET":
+		r.values[args[1]] = args[2]
+		return "+OK\r\n"
+	case "DEL":
+		deleted := 0
+		for _, key := range args[1:] {
+			if _, ok := r.values[key]; ok {
+				delete(r.values, key)
+				deleted++
+			}
+		}
+		return fmt.Sprintf(":%d\r\n", deleted)
+	case "SCAN":
+		var keys []string
+		for key := range r.values {
+			if matched, _ := path.Match(args[3], key); matched {
+				keys = append(keys, bulkString(key))
+			}
+		}
+		return fmt.Sprintf("*2\r\n%s*%d\r\n%s", bulkString("0"), len(keys), strings.Join(keys, ""))
+	}
+
+	return fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+}
+
+func bulkString(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
+
+func (r *fakeRedis) get(key string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.values[key]
+}
+
+func (r *fakeRedis) has(key string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.values[key]
+	return ok
+}
+
+// testRunner is a runner whose servers are running when they're listed.
+type testRunner struct {
+	running map[string]bool
+}
+
+func (tr *testRunner) Setup(server *servers.Server) (string, string, error) { return "", "", nil }
+func (tr *testRunner) Start(server *servers.Server) error {
+	tr.running[server.UUID] = true
+	return nil
+}
+func (tr *testRunner) Stop(server *servers.Server) error {
+	tr.running[server.UUID] = false
+	return nil
+}
+func (tr *testRunner) UploadSTV(server *servers.Server) ([]string, error)          { return nil, nil }
+func (tr *testRunner) SendCommand(server *servers.Server, command string) error    { return nil }
+func (tr *testRunner) Console(server *servers.Server, lines int) ([]string, error) { return nil, nil }
+func (tr *testRunner) IsAvailable(server *servers.Server) bool                     { return true }
+func (tr *testRunner) IsBooked(server *servers.Server) bool                        { return tr.running[server.UUID] }
+
+// testPool is a server pool of the listed servers.
+type testPool struct {
+	servers []*servers.Server
+}
+
+func (tp *testPool) Initialise() error                                          { return nil }
+func (tp *testPool) GetServers() []*servers.Server                              { return tp.servers }
+func (tp *testPool) GetAvailableServer() *servers.Server                        { return nil }
+func (tp *testPool) GetAvailableServers() []*servers.Server                     { return nil }
+func (tp *testPool) GetBookedServers() []*servers.Server                        { return nil }
+func (tp *testPool) GetServerByAddress(address string) (*servers.Server, error) { return nil, nil }
+func (tp *testPool) GetServerByName(name string) (*servers.Server, error)       { return nil, nil }
+func (tp *testPool) GetServerByUUID(uuid string) (*servers.Server, error)       { return nil, nil }
+
+// setupReconcile creates the servers & Redis state of the reconciler tests, with the policy for every inconsistency:
+//   - "running" is running, but has no record in Redis.
+//   - "stopped" is booked by user 1, but isn't running.
+//   - "booked" is booked by user 2, and is running.
+//   - user 3 is mapped to a server that doesn't exist, and user 4 to the server without a record.
+func setupReconcile(t *testing.T, policy string) (*fakeRedis, *testRunner, *testPool) {
+	redisServer := newFakeRedis(t)
+	globals.RedisClient = redis.NewClient(&redis.Options{Addr: redisServer.listener.Addr().String()})
+
+	conf := &config.Config{}
+	conf.Booking.KickMessage = "Unbooked"
+	conf.Reconcile.OrphanedUsers = policy
+	conf.Reconcile.UnbookedServers = policy
+	conf.Reconcile.StoppedBookings = policy
+	config.Set(conf)
+
+	runner := &testRunner{running: map[string]bool{"running": true, "booked": true}}
+	testServers := &testPool{}
+	for _, uuid := range []string{"running", "stopped", "booked"} {
+		testServers.servers = append(testServers.servers, &servers.Server{UUID: uuid, Name: uuid, Runner: runner})
+	}
+	pool = testServers
+	Bookings = booking.New(pool, globals.RedisClient)
+
+	bookedDate := time.Now().Add(-time.Hour).Format(time.RFC3339Nano)
+	redisServer.values["server.stopped"] = fmt.Sprintf(`{"Booked":true,"Booker":"1","BookedDate":"%s"}`, bookedDate)
+	redisServer.values["server.booked"] = fmt.Sprintf(`{"Booked":true,"Booker":"2","BookedDate":"%s"}`, bookedDate)
+	redisServer.values["user.1"] = "stopped"
+	redisServer.values["user.2"] = "booked"
+	redisServer.values["user.3"] = "removed"
+	redisServer.values["user.4"] = "running"
+
+	return redisServer, runner, testServers
+}
+
+func TestReconcileStateFix(t *testing.T) {
+	redisServer, runner, testServers := setupReconcile(t, ReconcileFix)
+	defer redisServer.listener.Close()
+
+	var ended []*events.BookingEnded
+	Bookings.Events = events.New()
+	Bookings.Events.Subscribe(func(e *events.BookingEnded) { ended = append(ended, e) })
+
+	reconciliations, err := ReconcileState()
+	if err != nil {
+		t.Fatalf("Expected the reconciler to succeed, got %s", err)
+	}
+	if len(reconciliations) != 4 {
+		t.Errorf("Expected 4 inconsistencies, got %+v", reconciliations)
+	}
+	if len(ended) != 1 || ended[0].UserID != "1" || ended[0].Reason != events.Stopped {
+		t.Errorf("Expected the end of the stopped booking to be published, got %+v", ended)
+	}
+
+	if runner.running["running"] {
+		t.Error("Expected the server without a record to be stopped")
+	}
+	if testServers.servers[1].Booked {
+		t.Error("Expected the stopped server to be unbooked")
+	}
+	if !runner.running["booked"] || !testServers.servers[2].Booked {
+		t.Error("Expected the booked server to be left running")
+	}
+
+	for user, expected := range map[string]string{"user.1": "", "user.2": "booked", "user.3": "", "user.4": ""} {
+		if value := redisServer.get(user); value != expected {
+			t.Errorf("Expected %s to be \"%s\", got \"%s\"", user, expected, value)
+		}
+	}
+}
+
+func TestReconcileStateReport(t *testing.T) {
+	redisServer, runner, testServers := setupReconcile(t, ReconcileReport)
+	defer redisServer.listener.Close()
+
+	reconciliations, err := ReconcileState()
+	if err != nil {
+		t.Fatalf("Expected the reconciler to succeed, got %s", err)
+	}
+	if len(reconciliations) != 4 {
+		t.Errorf("Expected 4 inconsistencies, got %+v", reconciliations)
+	}
+	for _, r := range reconciliations {
+		if r.Policy != ReconcileReport {
+			t.Errorf("Expected the inconsistencies to be reported, got %+v", r)
+		}
+	}
+
+	if !runner.running["running"] || !testServers.servers[1].Booked {
+		t.Error("Expected the servers to be left as they were")
+	}
+	for _, user := range []string{"user.1", "user.3", "user.4"} {
+		if redisServer.get(user) == "" {
+			t.Errorf("Expected %s to be left as it was", user)
+		}
+	}
+}
+
+func TestReconcileStateIgnore(t *testing.T) {
+	redisServer, runner, testServers := setupReconcile(t, ReconcileIgnore)
+	defer redisServer.listener.Close()
+
+	reconciliations, err := ReconcileState()
+	if err != nil {
+		t.Fatalf("Expected the reconciler to succeed, got %s", err)
+	}
+	if len(reconciliations) != 0 {
+		t.Errorf("Expected the inconsistencies to be ignored, got %+v", reconciliations)
+	}
+
+	if !runner.running["running"] || !testServers.servers[1].Booked {
+		t.Error("Expected the servers to be left as they were")
+	}
+}
+
+func TestReconcileStateSyncError(t *testing.T) {
+	redisServer, _, _ := setupReconcile(t, ReconcileFix)
+	defer redisServer.listener.Close()
+
+	// The booked server's record can't be read, so its booker's mapping can't be checked.
+	redisServer.values["server.booked"] = "{"
+
+	if _, err := ReconcileState(); err == nil {
+		t.Error("Expected the reconciler to report the failed synchronisation")
+	}
+
+	for _, user := range []string{"user.2", "user.3"} {
+		if redisServer.get(user) == "" {
+			t.Errorf("Expected %s to be left as it was, as the user mappings weren't checked", user)
+		}
+	}
+}
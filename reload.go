@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"alex-j-butler.com/tf2-booking/audit"
+	"alex-j-butler.com/tf2-booking/commands"
+	"alex-j-butler.com/tf2-booking/config"
+	"alex-j-butler.com/tf2-booking/logging"
+	"alex-j-butler.com/tf2-booking/messages"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// ConfigPollInterval is how often the configuration file is checked for changes.
+const ConfigPollInterval = 5 * time.Second
+
+// ConfigPath is the path of the configuration file, chosen with '--config'.
+var ConfigPath = config.DefaultPath
+
+// ReloadConfiguration reloads the configuration file, logging the changed settings.
+func ReloadConfiguration(reason string) ([]config.Change, error) {
+	changes, err := config.Reload(ConfigPath)
+	if err != nil {
+		log.WithError(err).WithField("reason", reason).Error("Failed to reload configuration")
+		return nil, err
+	}
+
+	// Apply the logging settings, which can change without a restart.
+	if err := logging.Configure(config.Get().Logging.Level, config.Get().Logging.Format); err != nil {
+		log.WithError(err).Error("Failed to configure logging")
+	}
+
+	log.WithFields(log.Fields{"reason": reason, "changes": len(changes)}).Info("Reloaded configuration")
+	for _, change := range changes {
+		log.WithFields(log.Fields{"setting": change.Setting, "restart": change.Restart}).Info("Setting changed")
+	}
+
+	return changes, nil
+}
+
+// FormatChanges lists the changed settings, with the values of the settings that have been applied.
+// The values of the settings that need a restart aren't shown, as they include the bot's credentials.
+func FormatChanges(changes []config.Change) string {
+	lines := make([]string, len(changes))
+	for i, change := range changes {
+		if change.Restart {
+			lines[i] = fmt.Sprintf("! %s changed, restart to apply", change.Setting)
+		} else {
+			lines[i] = fmt.Sprintf("~ %s: %s -> %s", change.Setting, change.Old, change.New)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// WatchConfiguration reloads the configuration when the bot receives SIGHUP, or the configuration file changes.
+func WatchConfiguration() {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	ticker := time.NewTicker(ConfigPollInterval)
+	defer ticker.Stop()
+
+	modified := modTime(ConfigPath)
+	for {
+		select {
+		case <-hangup:
+			ReloadConfiguration("SIGHUP")
+		case <-ticker.C:
+			if t := modTime(ConfigPath); !t.Equal(modified) {
+				modified = t
+				ReloadConfiguration("file changed")
+			}
+		}
+	}
+}
+
+// modTime returns when the file was last modified, zero if it can't be read.
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return info.ModTime()
+}
+
+// Reload command handler.
+// Called when an admin types the 'reload' command into the Discord channel.
+// This function reloads the configuration file, and replies with the changed settings.
+func Reload(ctx *commands.Context) {
+	changes, err := ReloadConfiguration(fmt.Sprintf("reload command from \"%s\"", ctx.GetUserID()))
+	if err != nil {
+		ctx.Reply(Text(ctx.GetUserID(), "reload.failed", messages.Data{"Error": err.Error()}))
+		return
+	}
+
+	if len(changes) == 0 {
+		ctx.Reply(Text(ctx.GetUserID(), "reload.unchanged", nil))
+		return
+	}
+
+	ctx.Reply(Text(ctx.GetUserID(), "reload.done", messages.Data{"Changes": FormatChanges(changes)}))
+
+	Audit.Log(audit.Event{
+		Type:     audit.AdminAction,
+		UserID:   ctx.GetUserID(),
+		Username: ctx.GetUsername(),
+		Reason:   fmt.Sprintf("Reloaded the configuration, %d settings changed", len(changes)),
+	})
+}
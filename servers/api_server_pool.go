@@ -3,10 +3,10 @@ package servers
 import (
 	"errors"
 	"fmt"
-	"log"
 	"path"
 
 	"github.com/Qixalite/booking-api/client"
+	log "github.com/Sirupsen/logrus"
 )
 
 // APIServerPool is a server pool that is loaded from the booking API.
@@ -20,7 +20,7 @@ func (asp *APIServerPool) Initialise() error {
 	asp.CachedServers = make(map[string]*Server)
 	err := asp.updateCache()
 	if err != nil {
-		log.Println("APIServerPool UpdateCache:", err)
+		log.WithError(err).Error("Failed to update the server cache from the booking API")
 	}
 
 	return nil
@@ -68,7 +68,7 @@ func (asp *APIServerPool) updateCache() error {
 				STVAddress:   fmt.Sprintf("%s:%d", apiServer.IPAddress, apiServer.STVPort),
 				RCONPassword: apiServer.RCONPassword,
 			}
-			server.Runner = NewRunner(asp.APIClient)
+			server.Runner = NewAPIRunner(asp.APIClient)
 			asp.CachedServers[apiServer.UUID] = server
 		}
 	}
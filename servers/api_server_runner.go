@@ -0,0 +1,161 @@
+package servers
+
+import (
+	"time"
+
+	"alex-j-butler.com/tf2-booking/metrics"
+
+	"github.com/Qixalite/booking-api/client"
+)
+
+// APIServerRunner runs the servers through the booking API, which knows the servers by their UUID.
+type APIServerRunner struct {
+	APIClient     *client.Client
+	cachedServers map[string]*client.ServerResource
+}
+
+func NewAPIRunner(apiClient *client.Client) *APIServerRunner {
+	return &APIServerRunner{
+		APIClient:     apiClient,
+		cachedServers: make(map[string]*client.ServerResource),
+	}
+}
+
+func (sr APIServerRunner) getServer(uuid string) (*client.ServerResource, error) {
+	if server, ok := sr.cachedServers[uuid]; ok {
+		return server, nil
+	}
+
+	server, err := sr.APIClient.GetServer(uuid)
+	if err != nil {
+		return nil, err
+	}
+	sr.cachedServers[uuid] = &server
+	return &server, nil
+}
+
+func (sr APIServerRunner) Setup(server *Server) (rconPassword string, srvPassword string, err error) {
+	defer metrics.ObserveBookingAPI("setup", time.Now(), &err)
+
+	// Generate an RCON and server password.
+	rconPassword = generatePassword()
+	srvPassword = generatePassword()
+
+	// Retrieve the API server instance from the API client.
+	apiServer, err := sr.getServer(server.UUID)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Set the password on the server.
+	err = apiServer.SetPassword(sr.APIClient, rconPassword, srvPassword)
+
+	return rconPassword, srvPassword, err
+}
+
+func (sr APIServerRunner) Start(server *Server) (err error) {
+	defer metrics.ObserveBookingAPI("start", time.Now(), &err)
+
+	// Retrieve the API server instance from the API client.
+	apiServer, err := sr.getServer(server.UUID)
+	if err != nil {
+		return err
+	}
+
+	// Start the server.
+	err = apiServer.Start(sr.APIClient)
+
+	return err
+}
+
+func (sr APIServerRunner) Stop(server *Server) (err error) {
+	defer metrics.ObserveBookingAPI("stop", time.Now(), &err)
+
+	// Retrieve the API server instance from the API client.
+	apiServer, err := sr.getServer(server.UUID)
+	if err != nil {
+		return err
+	}
+
+	// Stop the server.
+	err = apiServer.Stop(sr.APIClient)
+
+	return err
+}
+
+func (sr APIServerRunner) UploadSTV(server *Server) (demos []string, err error) {
+	defer metrics.ObserveBookingAPI("upload_stv", time.Now(), &err)
+
+	// Retrieve the API server instance from the API client.
+	apiServer, err := sr.getServer(server.UUID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Upload demos.
+	demoURLs, err := apiServer.UploadDemos(sr.APIClient, server.BookerFullname)
+	if err != nil {
+		return nil, err
+	}
+
+	demos = make([]string, 0, len(demoURLs))
+	for _, demoURL := range demoURLs {
+		demos = append(demos, demoURL)
+	}
+
+	return demos, nil
+}
+
+func (sr APIServerRunner) SendCommand(server *Server, command string) (err error) {
+	defer metrics.ObserveBookingAPI("send_command", time.Now(), &err)
+
+	// Retrieve the API server instance from the API client.
+	apiServer, err := sr.getServer(server.UUID)
+	if err != nil {
+		return err
+	}
+
+	// Send the command.
+	err = apiServer.SendCommand(sr.APIClient, command)
+
+	return err
+}
+
+func (sr APIServerRunner) Console(server *Server, lines int) (consoleLines []string, err error) {
+	defer metrics.ObserveBookingAPI("console", time.Now(), &err)
+
+	// Retrieve the API server instance from the API client.
+	apiServer, err := sr.getServer(server.UUID)
+	if err != nil {
+		return nil, err
+	}
+	consoleLines, err = apiServer.Console(sr.APIClient, lines)
+
+	return consoleLines, err
+}
+
+func (sr APIServerRunner) IsAvailable(server *Server) bool {
+	var err error
+	defer metrics.ObserveBookingAPI("get_server", time.Now(), &err)
+
+	// Attempt to request the server information, if it fails, the server is unavailable.
+	_, err = sr.APIClient.GetServer(server.UUID)
+	if err != nil {
+		// Unavailable!
+		return false
+	}
+
+	return true
+}
+
+func (sr APIServerRunner) IsBooked(server *Server) bool {
+	var err error
+	defer metrics.ObserveBookingAPI("get_server", time.Now(), &err)
+
+	apiServer, err := sr.APIClient.GetServer(server.UUID)
+	if err != nil {
+		return false
+	}
+
+	return apiServer.Running
+}
@@ -0,0 +1,295 @@
+package servers
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+
+	"alex-j-butler.com/tf2-booking/config"
+	"alex-j-butler.com/tf2-booking/util"
+
+	"github.com/Qixalite/booking-api/client"
+)
+
+// Types of runner that can run the servers of the file pool.
+const (
+	// RunnerAPI runs the server through the booking API, which knows the server by its UUID.
+	RunnerAPI = "api"
+
+	// RunnerLocal runs the server on the bot's machine.
+	RunnerLocal = "local"
+)
+
+// RunnerSettings choose how a server of the file pool is run.
+type RunnerSettings struct {
+	// Type of the runner, "api" (default) or "local".
+	Type string `yaml:"type"`
+
+	// Executable of the server run by the local runner, eg. "./srcds_run",
+	// with its templated arguments, eg. "+rcon_password {{.RCONPassword}}".
+	Command string   `yaml:"command"`
+	Args    []string `yaml:"args"`
+
+	// Templated scripts of the local runner, run in the server's directory.
+	// The server is started & stopped with the scripts if there isn't a command.
+	// Defaults to the scripts of the 'booking' section.
+	SetupCommand     string `yaml:"setup_command"`
+	StartCommand     string `yaml:"start_command"`
+	StopCommand      string `yaml:"stop_command"`
+	UploadSTVCommand string `yaml:"upload_stv_command"`
+
+	// Directory of the demos recorded by the server, relative to the server's directory.
+	Demos string `yaml:"demos"`
+
+	// Number of console lines kept for the dashboard.
+	ConsoleLines int `yaml:"console_lines"`
+
+	// How long the server has to quit before it's killed.
+	StopTimeout util.DurationUtil `yaml:"stop_timeout"`
+}
+
+// FileServer is a server listed in the server file.
+type FileServer struct {
+	Name string `yaml:"name"`
+
+	// UUID identifies the server in Redis, and in the booking API for the "api" runner.
+	// The name is used if it's empty.
+	UUID string `yaml:"uuid"`
+
+	Address      string `yaml:"address"`
+	STVAddress   string `yaml:"stv_address"`
+	RCONPassword string `yaml:"rcon_password"`
+
+	// Directory the server is installed in.
+	Path string `yaml:"path"`
+
+	Runner RunnerSettings `yaml:"runner"`
+}
+
+// ServerFile is the YAML file listing the servers of the file pool.
+type ServerFile struct {
+	Servers []FileServer `yaml:"servers"`
+}
+
+// LoadServerFile reads & validates the server file.
+// The APIClient is the booking API used by the "api" runners, nil if the booking API isn't configured.
+func LoadServerFile(path string, apiClient *client.Client) (*ServerFile, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read server file: %s", err)
+	}
+
+	file := &ServerFile{}
+	if err := config.UnmarshalStrict(contents, file); err != nil {
+		if errs, ok := err.(config.Errors); ok {
+			return nil, serverFileErrors(path, errs)
+		}
+		return nil, fmt.Errorf("failed to parse %s: %s", path, err)
+	}
+
+	file.applyDefaults()
+
+	if errs := file.Validate(apiClient != nil); len(errs) > 0 {
+		return nil, serverFileErrors(path, errs)
+	}
+
+	return file, nil
+}
+
+func serverFileErrors(path string, errs []error) error {
+	problems := make([]string, len(errs))
+	for i, err := range errs {
+		problems[i] = err.Error()
+	}
+
+	return fmt.Errorf("invalid server file %s:\n  %s", path, strings.Join(problems, "\n  "))
+}
+
+// applyDefaults sets the scripts of the local runners without a command to the scripts of the 'booking' section.
+func (f *ServerFile) applyDefaults() {
+	booking := config.Get().Booking
+
+	for i := range f.Servers {
+		runner := &f.Servers[i].Runner
+		if runner.Type != RunnerLocal || runner.Command != "" {
+			continue
+		}
+
+		if runner.SetupCommand == "" {
+			runner.SetupCommand = booking.SetupCommand
+		}
+		if runner.StartCommand == "" {
+			runner.StartCommand = booking.StartCommand
+		}
+		if runner.StopCommand == "" {
+			runner.StopCommand = booking.StopCommand
+		}
+		if runner.UploadSTVCommand == "" {
+			runner.UploadSTVCommand = booking.UploadSTVCommand
+		}
+	}
+}
+
+// Validate checks the servers can be run, returning every problem found.
+// API is whether the booking API is configured, which the "api" runners need.
+func (f *ServerFile) Validate(api bool) []error {
+	var errs []error
+	require := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	require(len(f.Servers) > 0, "servers is required")
+
+	names := make(map[string]bool)
+	uuids := make(map[string]bool)
+	addresses := make(map[string]bool)
+
+	for i, server := range f.Servers {
+		require(server.Name != "", "servers[%d].name is required", i)
+		require(server.Name == "" || !names[server.Name], "servers[%d].name \"%s\" is used by another server", i, server.Name)
+		names[server.Name] = true
+
+		uuid := server.uuid()
+		require(uuid == "" || !uuids[uuid], "servers[%d].uuid \"%s\" is used by another server", i, uuid)
+		uuids[uuid] = true
+
+		require(isHostPort(server.Address), "servers[%d].address must be a host:port address, got \"%s\"", i, server.Address)
+		require(server.Address == "" || !addresses[server.Address], "servers[%d].address \"%s\" is used by another server", i, server.Address)
+		addresses[server.Address] = true
+
+		require(server.STVAddress == "" || isHostPort(server.STVAddress), "servers[%d].stv_address must be a host:port address, got \"%s\"", i, server.STVAddress)
+
+		runner := server.Runner
+		switch runner.Type {
+		case RunnerAPI, "":
+			require(api, "servers[%d].runner.type \"api\" requires the booking_api settings", i)
+		case RunnerLocal:
+			require(runner.Command != "" || (runner.StartCommand != "" && runner.StopCommand != ""),
+				"servers[%d].runner requires a command, or a start_command & stop_command", i)
+			require(runner.Command == "" || runner.StartCommand == "",
+				"servers[%d].runner can't have both a command & a start_command", i)
+			require(runner.ConsoleLines >= 0, "servers[%d].runner.console_lines can't be negative", i)
+			require(runner.StopTimeout.Duration >= 0, "servers[%d].runner.stop_timeout can't be negative", i)
+
+			templates := append([]string{}, runner.Args...)
+			for _, script := range []string{runner.SetupCommand, runner.StartCommand, runner.StopCommand, runner.UploadSTVCommand} {
+				templates = append(templates, splitScript(script)...)
+			}
+			for _, text := range templates {
+				if _, err := render([]string{text}, RunnerData{Server: &Server{}}); err != nil {
+					errs = append(errs, fmt.Errorf("servers[%d].runner has an invalid template \"%s\": %s", i, text, err))
+				}
+			}
+		default:
+			errs = append(errs, fmt.Errorf("servers[%d].runner.type must be \"api\" or \"local\", got \"%s\"", i, runner.Type))
+		}
+	}
+
+	return errs
+}
+
+// newRunner creates the runner of the server.
+func (s FileServer) newRunner(apiClient *client.Client) ServerRunner {
+	if s.Runner.Type == RunnerLocal {
+		return NewLocalRunner(s.Runner)
+	}
+
+	return NewAPIRunner(apiClient)
+}
+
+func (s FileServer) uuid() string {
+	if s.UUID != "" {
+		return s.UUID
+	}
+
+	return s.Name
+}
+
+func isHostPort(address string) bool {
+	host, port, err := net.SplitHostPort(address)
+	return err == nil && host != "" && port != ""
+}
+
+// FileServerPool is a server pool that is loaded from a YAML file listing the servers,
+// so the servers can be run without the booking API.
+type FileServerPool struct {
+	// Path of the server file.
+	Path string
+
+	// APIClient is the booking API used by the "api" runners, nil if the booking API isn't configured.
+	APIClient *client.Client
+
+	servers []*Server
+}
+
+// Initialise loads the servers from the server file, failing if it's invalid.
+func (fsp *FileServerPool) Initialise() error {
+	file, err := LoadServerFile(fsp.Path, fsp.APIClient)
+	if err != nil {
+		return err
+	}
+
+	fsp.servers = make([]*Server, len(file.Servers))
+	for i, listed := range file.Servers {
+		fsp.servers[i] = &Server{
+			UUID:         listed.uuid(),
+			Name:         listed.Name,
+			Path:         listed.Path,
+			Address:      listed.Address,
+			STVAddress:   listed.STVAddress,
+			RCONPassword: listed.RCONPassword,
+			Runner:       listed.newRunner(fsp.APIClient),
+		}
+	}
+
+	return nil
+}
+
+func (fsp *FileServerPool) GetServers() []*Server {
+	// Return a copy of the servers, so callers can reorder it.
+	servers := make([]*Server, len(fsp.servers))
+	copy(servers, fsp.servers)
+
+	return servers
+}
+
+func (fsp *FileServerPool) GetAvailableServer() *Server {
+	return GetAvailableServer(fsp.servers)
+}
+
+func (fsp *FileServerPool) GetAvailableServers() []*Server {
+	return GetAvailableServers(fsp.servers)
+}
+
+func (fsp *FileServerPool) GetBookedServers() []*Server {
+	servers := make([]*Server, 0)
+	for _, server := range fsp.servers {
+		if server.IsBooked() {
+			servers = append(servers, server)
+		}
+	}
+
+	return servers
+}
+
+func (fsp *FileServerPool) GetServerByAddress(address string) (*Server, error) {
+	return GetServerByAddress(fsp.servers, address)
+}
+
+func (fsp *FileServerPool) GetServerByName(name string) (*Server, error) {
+	for _, server := range fsp.servers {
+		if server.Name == name {
+			return server, nil
+		}
+	}
+
+	return nil, errors.New("Server not found")
+}
+
+func (fsp *FileServerPool) GetServerByUUID(uuid string) (*Server, error) {
+	return GetServerBySessionName(fsp.servers, uuid)
+}
@@ -0,0 +1,149 @@
+package servers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Qixalite/booking-api/client"
+)
+
+func writeServerFile(t *testing.T, contents string) string {
+	dir, err := ioutil.TempDir("", "servers")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "servers.yml")
+	if err := ioutil.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const serverFile = `
+servers:
+  - name: "Server 1"
+    uuid: "server-1"
+    address: "127.0.0.1:27015"
+    stv_address: "127.0.0.1:27020"
+    rcon_password: "example"
+  - name: "Server 2"
+    address: "127.0.0.1:27115"
+    runner:
+      type: "api"
+`
+
+func TestFileServerPool(t *testing.T) {
+	path := writeServerFile(t, serverFile)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	pool := &FileServerPool{Path: path, APIClient: &client.Client{}}
+	if err := pool.Initialise(); err != nil {
+		t.Fatalf("Expected the server pool to initialise, got %s", err)
+	}
+
+	if len(pool.GetServers()) != 2 {
+		t.Fatalf("Expected 2 servers, got %d", len(pool.GetServers()))
+	}
+
+	// Reordering the servers doesn't reorder the pool.
+	servs := pool.GetServers()
+	servs[0], servs[1] = servs[1], servs[0]
+	if pool.GetServers()[0].Name != "Server 1" {
+		t.Errorf("Expected the pool's servers to be copied, got %s first", pool.GetServers()[0].Name)
+	}
+
+	server, err := pool.GetServerByName("Server 1")
+	if err != nil {
+		t.Fatalf("Expected to find the server by name, got %s", err)
+	}
+	if server.UUID != "server-1" || server.STVAddress != "127.0.0.1:27020" || server.RCONPassword != "example" || server.Runner == nil {
+		t.Errorf("Expected the server's settings, got %+v", server)
+	}
+
+	server, err = pool.GetServerByAddress("127.0.0.1:27115")
+	if err != nil || server.Name != "Server 2" {
+		t.Errorf("Expected to find the server by address, got %v, %v", server, err)
+	}
+
+	server, err = pool.GetServerByUUID("Server 2")
+	if err != nil || server.Name != "Server 2" {
+		t.Errorf("Expected the server's UUID to default to its name, got %v, %v", server, err)
+	}
+
+	if _, err := pool.GetServerByName("Server 3"); err == nil {
+		t.Errorf("Expected an unknown server not to be found")
+	}
+}
+
+func TestFileServerPoolInvalid(t *testing.T) {
+	path := writeServerFile(t, `
+servers:
+  - name: "Server 1"
+    address: "127.0.0.1"
+  - name: "Server 1"
+    address: "127.0.0.1:27115"
+    stv_adress: "127.0.0.1:27120"
+`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	pool := &FileServerPool{Path: path, APIClient: &client.Client{}}
+	err := pool.Initialise()
+	if err == nil || !strings.Contains(err.Error(), "unknown setting servers[1].stv_adress") {
+		t.Fatalf("Expected the unknown setting to be reported, got %v", err)
+	}
+}
+
+func TestServerFileValidate(t *testing.T) {
+	file := &ServerFile{Servers: []FileServer{
+		{Name: "Server 1", Address: "127.0.0.1"},
+		{Name: "Server 1", Address: "127.0.0.1:27015", STVAddress: "stv"},
+		{Address: "127.0.0.1:27015", Runner: RunnerSettings{Type: "docker"}},
+		{Name: "Server 4", Address: "127.0.0.1:27315", Runner: RunnerSettings{Type: RunnerLocal, ConsoleLines: -1}},
+		{Name: "Server 5", Address: "127.0.0.1:27415", Runner: RunnerSettings{
+			Type:         RunnerLocal,
+			Command:      "./srcds_run",
+			Args:         []string{"+rcon_password", "{{.RCONPasword}}"},
+			StartCommand: "./start.sh",
+		}},
+		// Template actions may have spaces.
+		{Name: "Server 6", Address: "127.0.0.1:27515", Runner: RunnerSettings{
+			Type:         RunnerLocal,
+			StartCommand: "./start.sh {{ .Port }}",
+			StopCommand:  "./stop.sh {{ .Name }}",
+		}},
+	}}
+
+	expected := []string{
+		"servers[0].address must be a host:port address, got \"127.0.0.1\"",
+		"servers[0].runner.type \"api\" requires the booking_api settings",
+		"servers[1].name \"Server 1\" is used by another server",
+		"servers[1].uuid \"Server 1\" is used by another server",
+		"servers[1].stv_address must be a host:port address, got \"stv\"",
+		"servers[1].runner.type \"api\" requires the booking_api settings",
+		"servers[2].name is required",
+		"servers[2].address \"127.0.0.1:27015\" is used by another server",
+		"servers[2].runner.type must be \"api\" or \"local\", got \"docker\"",
+		"servers[3].runner requires a command, or a start_command & stop_command",
+		"servers[3].runner.console_lines can't be negative",
+		"servers[4].runner can't have both a command & a start_command",
+		"servers[4].runner has an invalid template \"{{.RCONPasword}}\": template: arg:1:2: executing \"arg\" at <.RCONPasword>: can't evaluate field RCONPasword in type servers.RunnerData",
+	}
+
+	errs := file.Validate(false)
+	problems := make([]string, len(errs))
+	for i, err := range errs {
+		problems[i] = err.Error()
+	}
+
+	if strings.Join(problems, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(problems, "\n"))
+	}
+
+	if errs := (&ServerFile{}).Validate(true); len(errs) != 1 {
+		t.Errorf("Expected an empty server file to be invalid, got %v", errs)
+	}
+}
@@ -0,0 +1,503 @@
+package servers
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/james4k/rcon"
+)
+
+// Defaults of the local runner's settings.
+const (
+	DefaultConsoleLines = 500
+	DefaultStopTimeout  = 10 * time.Second
+	DefaultDemos        = "tf"
+)
+
+// probeTimeout is how long a server that the runner didn't start has to accept a connection.
+const probeTimeout = time.Second
+
+// RunnerData is the data of the local runner's templated arguments & scripts,
+// eg. "+rcon_password {{.RCONPassword}}".
+type RunnerData struct {
+	*Server
+
+	// Host & ports of the server's addresses.
+	Host    string
+	Port    string
+	STVHost string
+	STVPort string
+
+	// Passwords of the current booking, the RCON password replaces the server's previous password.
+	RCONPassword string
+	Password     string
+
+	// Path of the demo being uploaded, for the upload script.
+	Demo string
+}
+
+// LocalServerRunner runs a server on the bot's machine, either as a process it manages,
+// or with scripts that start & stop the server.
+type LocalServerRunner struct {
+	Settings RunnerSettings
+
+	mu sync.Mutex
+
+	// Passwords generated for the current booking.
+	rconPassword string
+	password     string
+
+	// The server process, while it's running.
+	process *exec.Cmd
+	stdin   io.WriteCloser
+	done    chan struct{}
+
+	// Whether the start script has run, for servers run with scripts.
+	started bool
+
+	// When the server was last started, demos recorded since then are uploaded.
+	startedAt time.Time
+
+	console *consoleBuffer
+}
+
+// NewLocalRunner creates a local runner with the settings, which must have been validated.
+func NewLocalRunner(settings RunnerSettings) *LocalServerRunner {
+	lines := settings.ConsoleLines
+	if lines == 0 {
+		lines = DefaultConsoleLines
+	}
+
+	return &LocalServerRunner{
+		Settings: settings,
+		console:  newConsoleBuffer(lines),
+	}
+}
+
+// Setup generates the passwords of a new booking, and runs the setup script if there is one.
+func (lr *LocalServerRunner) Setup(server *Server) (rconPassword string, srvPassword string, err error) {
+	rconPassword, srvPassword = generatePassword(), generatePassword()
+
+	lr.mu.Lock()
+	lr.rconPassword, lr.password = rconPassword, srvPassword
+	lr.mu.Unlock()
+
+	if lr.Settings.SetupCommand != "" {
+		if err := lr.runScript(server, lr.Settings.SetupCommand, lr.data(server)); err != nil {
+			return "", "", err
+		}
+	}
+
+	return rconPassword, srvPassword, nil
+}
+
+// Start starts the server process, or runs the start script.
+func (lr *LocalServerRunner) Start(server *Server) error {
+	data := lr.data(server)
+
+	// File systems keep coarser timestamps than the clock, so demos are compared to the second the server started.
+	startedAt := time.Now().Truncate(time.Second)
+
+	if lr.Settings.Command == "" {
+		if err := lr.runScript(server, lr.Settings.StartCommand, data); err != nil {
+			return err
+		}
+
+		lr.mu.Lock()
+		lr.started = true
+		lr.startedAt = startedAt
+		lr.mu.Unlock()
+
+		return nil
+	}
+
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+
+	if lr.process != nil || answering(server) {
+		return errors.New("server is already running")
+	}
+
+	args, err := render(lr.Settings.Args, data)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(lr.Settings.Command, args...)
+	cmd.Dir = server.Path
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+
+	// Capture the console output into the buffer.
+	output, writer := io.Pipe()
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+	go lr.capture(output)
+
+	if err := cmd.Start(); err != nil {
+		writer.Close()
+		return err
+	}
+
+	lr.process = cmd
+	lr.stdin = stdin
+	lr.done = make(chan struct{})
+	lr.startedAt = startedAt
+
+	go lr.wait(server, cmd, writer, lr.done)
+
+	return nil
+}
+
+// wait waits for the server process to exit, so it can be started again.
+func (lr *LocalServerRunner) wait(server *Server, cmd *exec.Cmd, writer *io.PipeWriter, done chan struct{}) {
+	err := cmd.Wait()
+	writer.Close()
+
+	lr.mu.Lock()
+	lr.process = nil
+	lr.stdin = nil
+	lr.mu.Unlock()
+
+	close(done)
+
+	if err != nil {
+		server.Log().WithError(err).Warn("Server process exited")
+	} else {
+		server.Log().Info("Server process exited")
+	}
+}
+
+// Stop asks the server process to quit, killing it if it hasn't exited after the stop timeout,
+// or runs the stop script.
+func (lr *LocalServerRunner) Stop(server *Server) error {
+	if lr.Settings.Command == "" {
+		if err := lr.runScript(server, lr.Settings.StopCommand, lr.data(server)); err != nil {
+			return err
+		}
+
+		lr.mu.Lock()
+		lr.started = false
+		lr.mu.Unlock()
+
+		return nil
+	}
+
+	lr.mu.Lock()
+	process, stdin, done := lr.process, lr.stdin, lr.done
+	lr.mu.Unlock()
+
+	if process == nil {
+		// The server may still be running from before the bot restarted, without its console.
+		if answering(server) {
+			return sendRCON(server, "quit")
+		}
+		return nil
+	}
+
+	timeout := lr.Settings.StopTimeout.Duration
+	if timeout == 0 {
+		timeout = DefaultStopTimeout
+	}
+
+	io.WriteString(stdin, "quit\n")
+
+	select {
+	case <-done:
+		return nil
+	case <-time.After(timeout):
+	}
+
+	server.Log().Warn("Server process didn't quit, killing it")
+	if err := process.Process.Kill(); err != nil {
+		return err
+	}
+	<-done
+
+	return nil
+}
+
+// UploadSTV collects the demos recorded since the server was started, uploading them with the upload script.
+// No demos are returned if there isn't an upload script, as the demos' paths don't link to anything.
+func (lr *LocalServerRunner) UploadSTV(server *Server) (demos []string, err error) {
+	if lr.Settings.UploadSTVCommand == "" {
+		return nil, nil
+	}
+
+	directory := lr.Settings.Demos
+	if directory == "" {
+		directory = DefaultDemos
+	}
+	if !filepath.IsAbs(directory) {
+		directory = filepath.Join(server.Path, directory)
+	}
+
+	lr.mu.Lock()
+	startedAt := lr.startedAt
+	lr.mu.Unlock()
+
+	files, err := filepath.Glob(filepath.Join(directory, "*.dem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	demos = make([]string, 0, len(files))
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil || info.ModTime().Before(startedAt) {
+			continue
+		}
+
+		data := lr.data(server)
+		data.Demo = file
+
+		output, err := lr.script(server, lr.Settings.UploadSTVCommand, data)
+		if err != nil {
+			return nil, err
+		}
+
+		// The last line of the upload script's output is the demo's link.
+		if lines := strings.Split(strings.TrimSpace(output), "\n"); lines[len(lines)-1] != "" {
+			demos = append(demos, strings.TrimSpace(lines[len(lines)-1]))
+		}
+	}
+
+	return demos, nil
+}
+
+// SendCommand writes the command to the server process' console,
+// or sends it over RCON for servers run with scripts, and servers still running from before the bot restarted.
+func (lr *LocalServerRunner) SendCommand(server *Server, command string) error {
+	if lr.Settings.Command == "" {
+		return sendRCON(server, command)
+	}
+
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+
+	if lr.stdin == nil {
+		if answering(server) {
+			return sendRCON(server, command)
+		}
+		return errors.New("server isn't running")
+	}
+
+	_, err := io.WriteString(lr.stdin, command+"\n")
+	return err
+}
+
+// sendRCON sends the command to the server over RCON.
+func sendRCON(server *Server, command string) error {
+	rc, err := rcon.Dial(server.Address, server.RCONPassword)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	_, err = rc.Write(command)
+	return err
+}
+
+// Console returns the latest lines of the server's console output.
+func (lr *LocalServerRunner) Console(server *Server, lines int) ([]string, error) {
+	return lr.console.Lines(lines), nil
+}
+
+// IsAvailable returns whether the server's directory exists.
+func (lr *LocalServerRunner) IsAvailable(server *Server) bool {
+	if server.Path == "" {
+		return true
+	}
+
+	info, err := os.Stat(server.Path)
+	return err == nil && info.IsDir()
+}
+
+// IsBooked returns whether the server process is running, or the start script has run.
+// Otherwise the server is probed, as it may still be running from before the bot restarted.
+func (lr *LocalServerRunner) IsBooked(server *Server) bool {
+	lr.mu.Lock()
+	running := lr.process != nil || lr.started
+	lr.mu.Unlock()
+
+	return running || answering(server)
+}
+
+// answering returns whether the server accepts connections on its address, ie. whether it's running.
+func answering(server *Server) bool {
+	if server.Address == "" {
+		return false
+	}
+
+	conn, err := net.DialTimeout("tcp", server.Address, probeTimeout)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+
+	return true
+}
+
+// data returns the data of the templates for the server.
+func (lr *LocalServerRunner) data(server *Server) RunnerData {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+
+	data := RunnerData{Server: server, RCONPassword: lr.rconPassword, Password: lr.password}
+	if data.RCONPassword == "" {
+		data.RCONPassword = server.RCONPassword
+	}
+
+	data.Host, data.Port, _ = net.SplitHostPort(server.Address)
+	data.STVHost, data.STVPort, _ = net.SplitHostPort(server.STVAddress)
+
+	return data
+}
+
+// runScript runs the script, keeping its output in the console.
+func (lr *LocalServerRunner) runScript(server *Server, script string, data RunnerData) error {
+	_, err := lr.script(server, script, data)
+	return err
+}
+
+// script runs the script in the server's directory, returning its output.
+// The script is split into arguments before they're templated, so the data can't add arguments.
+func (lr *LocalServerRunner) script(server *Server, script string, data RunnerData) (string, error) {
+	args, err := render(splitScript(script), data)
+	if err != nil {
+		return "", err
+	}
+	if len(args) == 0 {
+		return "", errors.New("script is empty")
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = server.Path
+
+	output, err := cmd.CombinedOutput()
+	lr.capture(bytes.NewReader(output))
+	if err != nil {
+		return string(output), fmt.Errorf("%s failed: %s", args[0], err)
+	}
+
+	return string(output), nil
+}
+
+// splitScript splits the script into arguments at the spaces outside of template actions,
+// so actions with spaces, eg. "{{ .Name }}", are kept in a single argument.
+func splitScript(script string) []string {
+	var args []string
+	var arg strings.Builder
+	actions := 0
+
+	for i := 0; i < len(script); i++ {
+		switch {
+		case strings.HasPrefix(script[i:], "{{"):
+			actions++
+			arg.WriteString("{{")
+			i++
+		case actions > 0 && strings.HasPrefix(script[i:], "}}"):
+			actions--
+			arg.WriteString("}}")
+			i++
+		case actions == 0 && unicode.IsSpace(rune(script[i])):
+			if arg.Len() > 0 {
+				args = append(args, arg.String())
+				arg.Reset()
+			}
+		default:
+			arg.WriteByte(script[i])
+		}
+	}
+	if arg.Len() > 0 {
+		args = append(args, arg.String())
+	}
+
+	return args
+}
+
+// capture adds each line of the output to the console.
+func (lr *LocalServerRunner) capture(output io.Reader) {
+	scanner := bufio.NewScanner(output)
+	for scanner.Scan() {
+		lr.console.Add(scanner.Text())
+	}
+}
+
+// render executes each of the templated arguments with the data.
+func render(args []string, data RunnerData) ([]string, error) {
+	rendered := make([]string, len(args))
+	for i, arg := range args {
+		tmpl, err := template.New("arg").Option("missingkey=error").Parse(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		var b bytes.Buffer
+		if err := tmpl.Execute(&b, data); err != nil {
+			return nil, err
+		}
+		rendered[i] = b.String()
+	}
+
+	return rendered, nil
+}
+
+// consoleBuffer keeps the latest lines of a server's console, dropping the oldest.
+type consoleBuffer struct {
+	mu    sync.Mutex
+	lines []string
+	next  int
+	full  bool
+}
+
+func newConsoleBuffer(size int) *consoleBuffer {
+	return &consoleBuffer{lines: make([]string, size)}
+}
+
+// Add adds a line to the buffer, replacing the oldest line if the buffer is full.
+func (b *consoleBuffer) Add(line string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lines[b.next] = line
+	b.next = (b.next + 1) % len(b.lines)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// Lines returns the latest lines, oldest first, or every line if n is 0.
+func (b *consoleBuffer) Lines(n int) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var lines []string
+	if b.full {
+		lines = append(lines, b.lines[b.next:]...)
+	}
+	lines = append(lines, b.lines[:b.next]...)
+
+	if n > 0 && n < len(lines) {
+		lines = lines[len(lines)-n:]
+	}
+
+	return lines
+}
@@ -0,0 +1,262 @@
+package servers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeServer echoes its arguments & the commands written to its console, and records a demo.
+const fakeServer = `#!/bin/sh
+echo "args: $*"
+mkdir -p tf
+touch tf/match.dem
+while read line; do
+  echo "command: $line"
+  if [ "$line" = "quit" ]; then
+    exit 0
+  fi
+done
+`
+
+func setupLocalServer(t *testing.T) *Server {
+	if runtime.GOOS == "windows" {
+		t.Skip("The fake server is a shell script")
+	}
+
+	dir, err := ioutil.TempDir("", "server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "srcds_run"), []byte(fakeServer), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	return &Server{Name: "Server 1", Path: dir, Address: "127.0.0.1:27015", STVAddress: "127.0.0.1:27020"}
+}
+
+// waitForLine waits for the runner's console to contain the line.
+func waitForLine(t *testing.T, runner *LocalServerRunner, server *Server, line string) {
+	for i := 0; i < 100; i++ {
+		lines, _ := runner.Console(server, 0)
+		for _, l := range lines {
+			if l == line {
+				return
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	lines, _ := runner.Console(server, 0)
+	t.Fatalf("Expected the console to contain \"%s\", got %q", line, lines)
+}
+
+func TestLocalRunnerProcess(t *testing.T) {
+	server := setupLocalServer(t)
+	defer os.RemoveAll(server.Path)
+
+	runner := NewLocalRunner(RunnerSettings{
+		Type:    RunnerLocal,
+		Command: "./srcds_run",
+		Args:    []string{"+ip", "{{.Host}}", "-port", "{{.Port}}", "+rcon_password", "{{.RCONPassword}}"},
+	})
+
+	rconPassword, password, err := runner.Setup(server)
+	if err != nil {
+		t.Fatalf("Expected the server to be set up, got %s", err)
+	}
+	if rconPassword == "" || password == "" || rconPassword == password {
+		t.Errorf("Expected different passwords, got \"%s\" & \"%s\"", rconPassword, password)
+	}
+
+	if runner.IsBooked(server) {
+		t.Error("Expected the server not to be booked before it's started")
+	}
+	if err := runner.Start(server); err != nil {
+		t.Fatalf("Expected the server to start, got %s", err)
+	}
+	if !runner.IsBooked(server) {
+		t.Error("Expected the server to be booked while it's running")
+	}
+	if err := runner.Start(server); err == nil {
+		t.Error("Expected starting a running server to fail")
+	}
+
+	waitForLine(t, runner, server, "args: +ip 127.0.0.1 -port 27015 +rcon_password "+rconPassword)
+
+	if err := runner.SendCommand(server, "changelevel cp_badlands"); err != nil {
+		t.Fatalf("Expected the command to be sent, got %s", err)
+	}
+	waitForLine(t, runner, server, "command: changelevel cp_badlands")
+
+	if err := runner.Stop(server); err != nil {
+		t.Fatalf("Expected the server to stop, got %s", err)
+	}
+	if runner.IsBooked(server) {
+		t.Error("Expected the server not to be booked after it's stopped")
+	}
+	if err := runner.SendCommand(server, "status"); err == nil {
+		t.Error("Expected sending a command to a stopped server to fail")
+	}
+
+	// The recorded demo isn't linked to without an upload script.
+	demos, err := runner.UploadSTV(server)
+	if err != nil || len(demos) != 0 {
+		t.Errorf("Expected no demos without an upload script, got %q, %v", demos, err)
+	}
+}
+
+func TestLocalRunnerAfterRestart(t *testing.T) {
+	server := setupLocalServer(t)
+	defer os.RemoveAll(server.Path)
+
+	// The server is still running from before the bot restarted, so the new runner didn't start it.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Address = listener.Addr().String()
+
+	runner := NewLocalRunner(RunnerSettings{Type: RunnerLocal, Command: "./srcds_run"})
+	if !runner.IsBooked(server) {
+		t.Error("Expected the running server to be booked")
+	}
+	if err := runner.Start(server); err == nil {
+		t.Error("Expected starting the running server to fail")
+	}
+
+	listener.Close()
+	if runner.IsBooked(server) {
+		t.Error("Expected the server not to be booked after it stopped")
+	}
+}
+
+func TestLocalRunnerStopTimeout(t *testing.T) {
+	server := setupLocalServer(t)
+	defer os.RemoveAll(server.Path)
+
+	// The server ignores its console, so it has to be killed.
+	runner := NewLocalRunner(RunnerSettings{Type: RunnerLocal, Command: "sleep", Args: []string{"60"}})
+	runner.Settings.StopTimeout.Duration = 50 * time.Millisecond
+
+	if err := runner.Start(server); err != nil {
+		t.Fatalf("Expected the server to start, got %s", err)
+	}
+	if err := runner.Stop(server); err != nil {
+		t.Fatalf("Expected the server to be killed, got %s", err)
+	}
+	if runner.IsBooked(server) {
+		t.Error("Expected the server not to be booked after it's killed")
+	}
+}
+
+func TestLocalRunnerScripts(t *testing.T) {
+	server := setupLocalServer(t)
+	defer os.RemoveAll(server.Path)
+
+	runner := NewLocalRunner(RunnerSettings{
+		Type:             RunnerLocal,
+		SetupCommand:     "echo setup {{ .Name }}",
+		StartCommand:     "mkdir -p tf",
+		StopCommand:      "echo stop",
+		UploadSTVCommand: "echo https://demos.example.com/{{.Demo}}",
+	})
+
+	if _, _, err := runner.Setup(server); err != nil {
+		t.Fatalf("Expected the setup script to run, got %s", err)
+	}
+	// The templated argument is a single argument, even though the server's name has a space.
+	waitForLine(t, runner, server, "setup Server 1")
+
+	if err := runner.Start(server); err != nil {
+		t.Fatalf("Expected the start script to run, got %s", err)
+	}
+	if !runner.IsBooked(server) {
+		t.Error("Expected the server to be booked after the start script")
+	}
+
+	demo := filepath.Join(server.Path, "tf", "match.dem")
+	if err := ioutil.WriteFile(demo, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	demos, err := runner.UploadSTV(server)
+	if err != nil {
+		t.Fatalf("Expected the demos to be uploaded, got %s", err)
+	}
+	if !reflect.DeepEqual(demos, []string{"https://demos.example.com/" + demo}) {
+		t.Errorf("Expected the demo's link, got %q", demos)
+	}
+
+	if err := runner.Stop(server); err != nil {
+		t.Fatalf("Expected the stop script to run, got %s", err)
+	}
+	if runner.IsBooked(server) {
+		t.Error("Expected the server not to be booked after the stop script")
+	}
+
+	runner.Settings.StopCommand = "false"
+	if err := runner.Stop(server); err == nil || !strings.Contains(err.Error(), "false failed") {
+		t.Errorf("Expected the failing stop script's error, got %v", err)
+	}
+}
+
+func TestLocalRunnerNotSerialised(t *testing.T) {
+	runner := NewLocalRunner(RunnerSettings{Type: RunnerLocal, Command: "./srcds_run"})
+	server := &Server{Runner: runner, Booked: true}
+
+	serialised, err := json.Marshal(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(serialised), "srcds_run") {
+		t.Errorf("Expected the runner not to be serialised, got %s", serialised)
+	}
+
+	// Synchronising the server's state from Redis keeps the configured runner.
+	if err := json.Unmarshal([]byte(`{"Runner":{"Settings":{"Command":"other"}},"Booked":false}`), server); err != nil {
+		t.Fatal(err)
+	}
+	if server.Runner != runner || runner.Settings.Command != "./srcds_run" || server.Booked {
+		t.Errorf("Expected only the server's state to be replaced, got %+v", runner.Settings)
+	}
+}
+
+func TestSplitScript(t *testing.T) {
+	args := splitScript("./upload.sh  {{ .Demo }} --name={{ printf \"%s\" .Name }}\t-v")
+	expected := []string{"./upload.sh", "{{ .Demo }}", "--name={{ printf \"%s\" .Name }}", "-v"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("Expected %q, got %q", expected, args)
+	}
+}
+
+func TestConsoleBuffer(t *testing.T) {
+	buffer := newConsoleBuffer(3)
+	if lines := buffer.Lines(0); len(lines) != 0 {
+		t.Errorf("Expected no lines, got %q", lines)
+	}
+
+	for _, line := range []string{"a", "b"} {
+		buffer.Add(line)
+	}
+	if lines := buffer.Lines(0); !reflect.DeepEqual(lines, []string{"a", "b"}) {
+		t.Errorf("Expected every line, got %q", lines)
+	}
+
+	for _, line := range []string{"c", "d", "e"} {
+		buffer.Add(line)
+	}
+	if lines := buffer.Lines(0); !reflect.DeepEqual(lines, []string{"c", "d", "e"}) {
+		t.Errorf("Expected the oldest lines to be dropped, got %q", lines)
+	}
+	if lines := buffer.Lines(2); !reflect.DeepEqual(lines, []string{"d", "e"}) {
+		t.Errorf("Expected the latest 2 lines, got %q", lines)
+	}
+}
@@ -1,23 +1,31 @@
 package servers
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"regexp"
+	"strings"
+	"sync"
 	"time"
 
 	"alex-j-butler.com/tf2-booking/config"
 	"alex-j-butler.com/tf2-booking/globals"
+	"alex-j-butler.com/tf2-booking/logging"
+	"alex-j-butler.com/tf2-booking/messages"
+	"alex-j-butler.com/tf2-booking/metrics"
 	"alex-j-butler.com/tf2-booking/util"
+	log "github.com/Sirupsen/logrus"
 	"github.com/bwmarrin/discordgo"
 	"github.com/james4k/rcon"
 	redis "gopkg.in/redis.v5"
 )
 
 type Server struct {
-	Runner *ServerRunner
+	// Runner is configured from the server pool, so it isn't stored with the server's state.
+	Runner ServerRunner `json:"-"`
 
 	UUID       string `json:"-"`
 	Name       string `json:"-"`
@@ -35,9 +43,15 @@ type Server struct {
 	// Specifies whether the server is currently booked.
 	Booked bool
 
+	// BookingID identifies the current booking in the log, it changes each time the server is booked.
+	BookingID string
+
 	// Specifies when the server was booked.
 	BookedDate time.Time
 
+	// Specifies when the booking expires, zero if the booking doesn't expire.
+	ReturnDate time.Time
+
 	// The ID of the Discord user who booked the server.
 	Booker string
 
@@ -52,10 +66,33 @@ type Server struct {
 
 	// ErrorMinutes is the number of minutes the server has been in an errored state for.
 	ErrorMinutes int
+
+	// Maintenance is set by admins to take the server out of the pool of bookable servers.
+	// It's changed from the dashboard, so is accessed with InMaintenance & SetMaintenance.
+	Maintenance bool
+
+	maintenanceMu sync.RWMutex
+}
+
+// InMaintenance returns whether the server has been taken out of the pool of bookable servers.
+func (s *Server) InMaintenance() bool {
+	s.maintenanceMu.RLock()
+	defer s.maintenanceMu.RUnlock()
+
+	return s.Maintenance
+}
+
+// SetMaintenance takes the server out of, or returns it to, the pool of bookable servers.
+func (s *Server) SetMaintenance(maintenance bool) {
+	s.maintenanceMu.Lock()
+	defer s.maintenanceMu.Unlock()
+
+	s.Maintenance = maintenance
 }
 
 func (s *Server) SetServerVars(userID string, fullname string) {
 	s.Booked = true
+	s.BookingID = newBookingID()
 	s.BookedDate = time.Now()
 	s.Booker = userID
 	s.BookerMention = fmt.Sprintf("<@%s>", userID)
@@ -67,7 +104,9 @@ func (s *Server) SetServerVars(userID string, fullname string) {
 
 func (s *Server) ResetServerVars() {
 	s.Booked = false
+	s.BookingID = ""
 	s.BookedDate = time.Time{}
+	s.ReturnDate = time.Time{}
 	s.Booker = ""
 	s.BookerMention = ""
 	s.SentIdleWarning = false
@@ -75,19 +114,42 @@ func (s *Server) ResetServerVars() {
 	s.ErrorMinutes = 0
 }
 
+// Log returns a log entry with the server's fields, and the booking's fields while it's booked.
+func (s *Server) Log() *log.Entry {
+	fields := log.Fields{logging.ServerField: s.Name, logging.ServerUUIDField: s.UUID}
+	if s.Booked {
+		fields[logging.UserIDField] = s.Booker
+		fields[logging.BookingIDField] = s.BookingID
+	}
+
+	return log.WithFields(fields)
+}
+
+// newBookingID returns a random booking ID.
+func newBookingID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+
+	return hex.EncodeToString(b)
+}
+
 // Update performs an update of the server into the specified Redis client.
 func (s *Server) Update(redisClient *redis.Client) error {
 	// Serialise the server as JSON.
+	s.maintenanceMu.RLock()
 	serialised, err := json.Marshal(s)
+	s.maintenanceMu.RUnlock()
 	if err != nil {
-		log.Println("marshal error:", err)
+		s.Log().WithError(err).Error("Failed to marshal server")
 		return err
 	}
 
 	// Perform a SET command on the Redis client.
 	err = redisClient.Set(fmt.Sprintf("server.%s", s.UUID), serialised, 0).Err()
 	if err != nil {
-		log.Println("redis error:", err)
+		s.Log().WithError(err).Error("Failed to update server in Redis")
 		return err
 	}
 
@@ -102,7 +164,9 @@ func (s *Server) Synchronise(redisClient *redis.Client) error {
 	}
 
 	// Deserialise the JSON.
+	s.maintenanceMu.Lock()
 	err = json.Unmarshal([]byte(result), &s)
+	s.maintenanceMu.Unlock()
 	if err != nil {
 		return err
 	}
@@ -113,7 +177,7 @@ func (s *Server) Synchronise(redisClient *redis.Client) error {
 // Available returns whether the server is currently bookable,
 // or whether it's experiencing an error that would prevent it from being successfully booked.
 func (s *Server) Available() bool {
-	return s.Runner.IsAvailable(s) && !s.Runner.IsBooked(s)
+	return !s.InMaintenance() && s.Runner.IsAvailable(s) && !s.Runner.IsBooked(s)
 }
 
 // IsBooked returns whether the server is currently booked
@@ -121,6 +185,20 @@ func (s *Server) IsBooked() bool {
 	return s.Booked && s.Runner.IsBooked(s)
 }
 
+// Expired returns whether the booking has passed its return date.
+func (s *Server) Expired() bool {
+	return s.Booked && !s.ReturnDate.IsZero() && time.Now().After(s.ReturnDate)
+}
+
+// TimeLeft returns the time remaining until the booking expires.
+func (s *Server) TimeLeft() time.Duration {
+	if s.ReturnDate.IsZero() {
+		return 0
+	}
+
+	return time.Until(s.ReturnDate)
+}
+
 func (s *Server) AddIdleMinute() {
 	s.IdleMinutes++
 
@@ -152,9 +230,10 @@ func (s *Server) GetCurrentPassword() (string, error) {
 
 // Setup the server with a randomised RCON password & server password from a bash script.
 // Returns:
-//  string - RCON password
-//  string - Server password
-//  error - Error of a failed setup, or nil if none
+//
+//	string - RCON password
+//	string - Server password
+//	error - Error of a failed setup, or nil if none
 func (s *Server) Setup() (string, string, error) {
 	// Run the setup function from the runner implementation.
 	rconPassword, srvPassword, err := s.Runner.Setup(s)
@@ -170,7 +249,8 @@ func (s *Server) Setup() (string, string, error) {
 
 // Start the server using a bash script.
 // Returns:
-//  error - Error of a failed start, or nil if none
+//
+//	error - Error of a failed start, or nil if none
 func (s *Server) Start() error {
 	// Run the start function from the runner implementation.
 	err := s.Runner.Start(s)
@@ -180,10 +260,15 @@ func (s *Server) Start() error {
 
 // Stop the server using a bash script.
 // Returns:
-// 	error - Error of a failed stop, or nil if none
+//
+//	error - Error of a failed stop, or nil if none
 func (s *Server) Stop() error {
 	// Stop the STV recording and kick all players cleanly.
-	KickCommand := fmt.Sprintf("tv_stop; kickall \"%s\"", config.Conf.Booking.KickMessage)
+	KickMessage := config.Get().Booking.KickMessage
+	if KickMessage == "" {
+		KickMessage = messages.Render(messages.Default.DefaultLocale, "server.kick", nil)
+	}
+	KickCommand := fmt.Sprintf("tv_stop; kickall \"%s\"", KickMessage)
 	s.SendCommand(KickCommand)
 
 	// Wait 1 second to the kick command to properly kick everyone.
@@ -237,11 +322,16 @@ func (s *Server) Unbook() error {
 	return nil
 }
 
-func (s *Server) ExtendBooking() {
-	// TODO: Implement this.
+// ExtendBooking resets the idle time of the booking, and pushes back
+// the return date by the specified duration if the booking expires.
+func (s *Server) ExtendBooking(duration time.Duration) {
 	// Reset the number of idle minutes, and allow the timeout warning message to be sent again.
 	s.SentIdleWarning = false
-	s.ResetIdleMinutes()
+	s.IdleMinutes = 0
+
+	if !s.ReturnDate.IsZero() {
+		s.ReturnDate = s.ReturnDate.Add(duration)
+	}
 
 	// Update the server in Redis.
 	s.Update(globals.RedisClient)
@@ -271,6 +361,20 @@ func (s *Server) UploadSTV() (string, error) {
 	return message, nil
 }
 
+// chatReplacer removes the characters that would end a chat message, and run the rest of it as console commands.
+var chatReplacer = strings.NewReplacer(";", "", "\"", "", "\r", " ", "\n", " ")
+
+// SayCommand returns the console command that says the message in the server's chat, addressed to the player.
+// The player's name & the message may have been written by players, so they're sanitised.
+func SayCommand(player string, message string) string {
+	return fmt.Sprintf("say \"@%s: %s\"", chatReplacer.Replace(player), chatReplacer.Replace(message))
+}
+
+// Say says the message in the server's chat, addressed to the player.
+func (s *Server) Say(player string, message string) error {
+	return s.SendCommand(SayCommand(player, message))
+}
+
 func (s *Server) SendCommand(command string) error {
 	// Run the SendCommand function from the runner implementation.
 	err := s.Runner.SendCommand(s, command)
@@ -278,7 +382,13 @@ func (s *Server) SendCommand(command string) error {
 	return err
 }
 
-func (s *Server) SendRCONCommand(command string) (string, error) {
+func (s *Server) SendRCONCommand(command string) (output string, err error) {
+	defer func() {
+		if err != nil {
+			metrics.RCONFailures.Inc(s.Name)
+		}
+	}()
+
 	rc, err := rcon.Dial(s.Address, s.RCONPassword)
 
 	if err == rcon.ErrAuthFailed {
@@ -300,7 +410,7 @@ func (s *Server) SendRCONCommand(command string) (string, error) {
 	}
 
 	// Grab the output.
-	output, _, err := rc.Read()
+	output, _, err = rc.Read()
 
 	if err != nil {
 		return "", err
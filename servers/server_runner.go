@@ -3,20 +3,29 @@ package servers
 import (
 	"math/rand"
 	"time"
-
-	"github.com/Qixalite/booking-api/client"
 )
 
-type ServerRunner struct {
-	APIClient     *client.Client
-	cachedServers map[string]*client.ServerResource
-}
+// ServerRunner runs the servers of a server pool.
+type ServerRunner interface {
+	// Setup generates the RCON & server passwords of a new booking.
+	Setup(server *Server) (rconPassword string, srvPassword string, err error)
 
-func NewRunner(apiClient *client.Client) *ServerRunner {
-	return &ServerRunner{
-		APIClient:     apiClient,
-		cachedServers: make(map[string]*client.ServerResource),
-	}
+	Start(server *Server) error
+	Stop(server *Server) error
+
+	// UploadSTV uploads the demos recorded since the server was started, returning their links.
+	UploadSTV(server *Server) (demos []string, err error)
+
+	SendCommand(server *Server, command string) error
+
+	// Console returns the latest console lines of the server, all of the kept lines if lines is 0.
+	Console(server *Server, lines int) ([]string, error)
+
+	// IsAvailable returns whether the server can be run.
+	IsAvailable(server *Server) bool
+
+	// IsBooked returns whether the server is running.
+	IsBooked(server *Server) bool
 }
 
 const letterBytes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
@@ -28,7 +37,7 @@ const (
 
 var src = rand.NewSource(time.Now().UnixNano())
 
-func (sr ServerRunner) generatePassword() string {
+func generatePassword() string {
 	n := 10
 	b := make([]byte, n)
 
@@ -46,124 +55,3 @@ func (sr ServerRunner) generatePassword() string {
 
 	return string(b)
 }
-
-func (sr ServerRunner) getServer(uuid string) (*client.ServerResource, error) {
-	if server, ok := sr.cachedServers[uuid]; ok {
-		return server, nil
-	}
-
-	server, err := sr.APIClient.GetServer(uuid)
-	if err != nil {
-		return nil, err
-	}
-	sr.cachedServers[uuid] = &server
-	return &server, nil
-}
-
-func (sr ServerRunner) Setup(server *Server) (rconPassword string, srvPassword string, err error) {
-	// Generate an RCON and server password.
-	rconPassword = sr.generatePassword()
-	srvPassword = sr.generatePassword()
-
-	// Retrieve the API server instance from the API client.
-	apiServer, err := sr.getServer(server.UUID)
-	if err != nil {
-		return "", "", err
-	}
-
-	// Set the password on the server.
-	err = apiServer.SetPassword(sr.APIClient, rconPassword, srvPassword)
-
-	return rconPassword, srvPassword, err
-}
-
-func (sr ServerRunner) Start(server *Server) error {
-	// Retrieve the API server instance from the API client.
-	apiServer, err := sr.getServer(server.UUID)
-	if err != nil {
-		return err
-	}
-
-	// Start the server.
-	err = apiServer.Start(sr.APIClient)
-
-	return err
-}
-
-func (sr ServerRunner) Stop(server *Server) error {
-	// Retrieve the API server instance from the API client.
-	apiServer, err := sr.getServer(server.UUID)
-	if err != nil {
-		return err
-	}
-
-	// Stop the server.
-	err = apiServer.Stop(sr.APIClient)
-
-	return err
-}
-
-func (sr ServerRunner) UploadSTV(server *Server) ([]string, error) {
-	// Retrieve the API server instance from the API client.
-	apiServer, err := sr.getServer(server.UUID)
-	if err != nil {
-		return nil, err
-	}
-
-	// Upload demos.
-	demoURLs, err := apiServer.UploadDemos(sr.APIClient, server.BookerFullname)
-	if err != nil {
-		return nil, err
-	}
-
-	demos := make([]string, 0, len(demoURLs))
-	for _, demoURL := range demoURLs {
-		demos = append(demos, demoURL)
-	}
-
-	return demos, nil
-}
-
-func (sr ServerRunner) SendCommand(server *Server, command string) error {
-	// Retrieve the API server instance from the API client.
-	apiServer, err := sr.getServer(server.UUID)
-	if err != nil {
-		return err
-	}
-
-	// Send the command.
-	err = apiServer.SendCommand(sr.APIClient, command)
-
-	return err
-}
-
-func (sr ServerRunner) Console(server *Server, lines int) ([]string, error) {
-	// Retrieve the API server instance from the API client.
-	apiServer, err := sr.getServer(server.UUID)
-	if err != nil {
-		return nil, err
-	}
-	consoleLines, err := apiServer.Console(sr.APIClient, lines)
-
-	return consoleLines, err
-}
-
-func (sr ServerRunner) IsAvailable(server *Server) bool {
-	// Attempt to request the server information, if it fails, the server is unavailable.
-	_, err := sr.APIClient.GetServer(server.UUID)
-	if err != nil {
-		// Unavailable!
-		return false
-	}
-
-	return true
-}
-
-func (sr ServerRunner) IsBooked(server *Server) bool {
-	apiServer, err := sr.APIClient.GetServer(server.UUID)
-	if err != nil {
-		return false
-	}
-
-	return apiServer.Running
-}
@@ -0,0 +1,19 @@
+package servers
+
+import "testing"
+
+func TestSayCommand(t *testing.T) {
+	for _, test := range []struct {
+		player   string
+		message  string
+		expected string
+	}{
+		{"player", "Booking extended", `say "@player: Booking extended"`},
+		{"player", "Unknown command x;quit", `say "@player: Unknown command xquit"`},
+		{`play"er;rcon_password x`, "line one\r\nline two", `say "@playerrcon_password x: line one  line two"`},
+	} {
+		if command := SayCommand(test.player, test.message); command != test.expected {
+			t.Errorf("Expected %s, got %s", test.expected, command)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"alex-j-butler.com/tf2-booking/config"
+	"alex-j-butler.com/tf2-booking/globals"
+	"alex-j-butler.com/tf2-booking/messages"
+	"alex-j-butler.com/tf2-booking/servers"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/bwmarrin/discordgo"
+	"github.com/kidoman/go-steam"
+	redis "gopkg.in/redis.v5"
+)
+
+// StatusBoardKey is the Redis key storing the message ID of the status board.
+const StatusBoardKey = "status_board.message"
+
+// Discord only allows this many fields on a single embed.
+const maxEmbedFields = 25
+
+// serverStatus is the state of a single server shown on the status board.
+type serverStatus struct {
+	Server *servers.Server
+	// State is the message key of the server's state.
+	State   string
+	Map     string
+	Players string
+}
+
+// queryServerStatus retrieves the state, map & player count of a server.
+func queryServerStatus(s *servers.Server) serverStatus {
+	status := serverStatus{
+		Server:  s,
+		Map:     "-",
+		Players: "-",
+	}
+
+	if s.IsBooked() {
+		status.State = "status_board.booked"
+	} else if s.InMaintenance() {
+		status.State = "status_board.maintenance"
+	} else if s.Available() {
+		status.State = "status_board.available"
+	} else {
+		status.State = "status_board.unavailable"
+	}
+
+	server, err := steam.Connect(s.Address)
+	if err != nil {
+		return status
+	}
+	defer server.Close()
+
+	info, err := server.Info()
+	if err != nil {
+		return status
+	}
+
+	status.Map = info.Map
+	status.Players = fmt.Sprintf("%d/%d", info.Players, info.MaxPlayers)
+
+	return status
+}
+
+// statusBoardEmbed builds the status board embed from the state of every server in the pool.
+func statusBoardEmbed() *discordgo.MessageEmbed {
+	servs := pool.GetServers()
+	sort.Slice(servs, func(i, j int) bool {
+		return servs[i].Name < servs[j].Name
+	})
+
+	// Query the servers concurrently, so one slow server doesn't hold up the board.
+	statuses := make([]serverStatus, len(servs))
+	var wg sync.WaitGroup
+	for i, serv := range servs {
+		wg.Add(1)
+		go func(i int, serv *servers.Server) {
+			defer wg.Done()
+			statuses[i] = queryServerStatus(serv)
+		}(i, serv)
+	}
+	wg.Wait()
+
+	embed := &discordgo.MessageEmbed{
+		Title:     AdminText("status_board.title", nil),
+		Color:     12763842,
+		Type:      "rich",
+		Timestamp: time.Now().Format(time.RFC3339),
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: GetGameString(len(pool.GetAvailableServers())),
+		},
+	}
+
+	for i, status := range statuses {
+		if i >= maxEmbedFields {
+			embed.Description = AdminText("status_board.more", messages.Data{"Count": len(statuses) - maxEmbedFields})
+			break
+		}
+
+		data := messages.Data{
+			"State":   AdminText(status.State, nil),
+			"Map":     status.Map,
+			"Players": status.Players,
+		}
+		if status.Server.Booked {
+			data["Booker"] = status.Server.BookerMention
+
+			if !status.Server.ReturnDate.IsZero() {
+				data["TimeLeft"] = status.Server.TimeLeft()
+			}
+		}
+
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   status.Server.Name,
+			Value:  AdminText("status_board.server", data),
+			Inline: true,
+		})
+	}
+
+	return embed
+}
+
+// isUnknownMessage returns whether the error is Discord reporting that the message doesn't exist.
+func isUnknownMessage(err error) bool {
+	restErr, ok := err.(*discordgo.RESTError)
+	if !ok {
+		return false
+	}
+
+	if restErr.Message != nil && restErr.Message.Code == discordgo.ErrCodeUnknownMessage {
+		return true
+	}
+
+	return restErr.Response != nil && restErr.Response.StatusCode == http.StatusNotFound
+}
+
+// UpdateStatusBoard edits the status board message in the status channel with the current server states.
+// If the message doesn't exist (or was deleted), a new message is created and its ID is stored in Redis.
+func UpdateStatusBoard() error {
+	channelID := config.Get().Discord.StatusChannel
+	if channelID == "" {
+		return nil
+	}
+
+	embed := statusBoardEmbed()
+
+	messageID, err := globals.RedisClient.Get(StatusBoardKey).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+
+	if messageID != "" {
+		_, err := Session.ChannelMessageEditEmbed(channelID, messageID, embed)
+		if err == nil {
+			return nil
+		}
+
+		if !isUnknownMessage(err) {
+			return err
+		}
+
+		log.Info("Status board message was deleted, creating a new one")
+	}
+
+	message, err := Session.ChannelMessageSendEmbed(channelID, embed)
+	if err != nil {
+		return err
+	}
+
+	return globals.RedisClient.Set(StatusBoardKey, message.ID, 0).Err()
+}
@@ -0,0 +1,108 @@
+package subscribers
+
+import (
+	"fmt"
+
+	"alex-j-butler.com/tf2-booking/audit"
+	"alex-j-butler.com/tf2-booking/events"
+)
+
+// endReasons are the reasons shown in the audit log for bookings ended without the booker's request.
+var endReasons = map[events.EndReason]string{
+	events.Expired: "Booking expired",
+	events.Idle:    "Idle timeout from too little players",
+	events.Stopped: "Server stopped while booked",
+}
+
+// Auditor records the booking events to the audit log & webhooks.
+type Auditor struct {
+	Recorder audit.Recorder
+}
+
+// Subscribe subscribes the auditor to the events it records.
+func (a *Auditor) Subscribe(bus *events.Bus) {
+	bus.Subscribe(a.BookingStarted)
+	bus.Subscribe(a.BookingStartFailed)
+	bus.Subscribe(a.BookingEnded)
+	bus.Subscribe(a.BookingExtended)
+	bus.Subscribe(a.ServerErrored)
+	bus.Subscribe(a.PlayerReported)
+}
+
+func (a *Auditor) BookingStarted(e *events.BookingStarted) {
+	event := audit.Event{
+		Type:     audit.Book,
+		UserID:   e.UserID,
+		Username: e.Username,
+		Server:   e.Server.Name,
+		Duration: e.Duration,
+	}
+	if e.Preset != "" {
+		event.Reason = fmt.Sprintf("Preset `%s`", e.Preset)
+	}
+
+	a.Recorder.Log(event)
+}
+
+func (a *Auditor) BookingStartFailed(e *events.BookingStartFailed) {
+	a.Recorder.Log(audit.Event{
+		Type:     audit.StartFailed,
+		UserID:   e.UserID,
+		Username: e.Username,
+		Server:   e.Server.Name,
+		Reason:   e.Err.Error(),
+	})
+}
+
+// BookingEnded records the bookings ended by their booker or automatically.
+// Forced unbooks are recorded as admin actions by the command instead.
+func (a *Auditor) BookingEnded(e *events.BookingEnded) {
+	event := audit.Event{
+		UserID:   e.UserID,
+		Server:   e.Server.Name,
+		Duration: e.BookedFor,
+	}
+
+	switch e.Reason {
+	case events.Returned:
+		event.Type = audit.Unbook
+	case events.ReturnedIngame:
+		event.Type = audit.Unbook
+		event.Reason = fmt.Sprintf("Returned ingame by %s", e.By)
+	case events.Expired, events.Idle, events.Stopped:
+		event.Type = audit.IdleUnbook
+		event.Reason = endReasons[e.Reason]
+	default:
+		return
+	}
+
+	a.Recorder.Log(event)
+}
+
+func (a *Auditor) BookingExtended(e *events.BookingExtended) {
+	a.Recorder.Log(audit.Event{
+		Type:     audit.Extend,
+		UserID:   e.UserID,
+		Server:   e.Server.Name,
+		Duration: e.Duration,
+	})
+}
+
+func (a *Auditor) ServerErrored(e *events.ServerErrored) {
+	a.Recorder.Log(audit.Event{
+		Type:   audit.ErrorThreshold,
+		UserID: e.Server.Booker,
+		Server: e.Server.Name,
+		Reason: fmt.Sprintf("Failed to be contacted after %d retries: %s", e.Retries, e.Err),
+	})
+}
+
+func (a *Auditor) PlayerReported(e *events.PlayerReported) {
+	a.Recorder.Log(audit.Event{
+		Type:     audit.Report,
+		UserID:   e.SteamID,
+		Username: e.Username,
+		Server:   e.Server,
+		Reason:   e.Reason,
+	})
+}
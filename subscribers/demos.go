@@ -0,0 +1,53 @@
+package subscribers
+
+import (
+	"fmt"
+
+	"alex-j-butler.com/tf2-booking/events"
+	"alex-j-butler.com/tf2-booking/logging"
+	"alex-j-butler.com/tf2-booking/servers"
+)
+
+// Demos uploads the STV demos of a booking once it has ended, and sends the booker the links.
+type Demos struct {
+	// Upload uploads the server's demos, returning the message with their links.
+	Upload func(server *servers.Server) (string, error)
+
+	// Send sends a message to a channel.
+	Send func(channelID string, message string) error
+
+	// Channel is where the demo links are sent.
+	Channel string
+}
+
+// NewDemos creates a Demos that uploads the demos using the server's runner.
+func NewDemos(send func(channelID string, message string) error, channel string) *Demos {
+	return &Demos{
+		Upload:  (*servers.Server).UploadSTV,
+		Send:    send,
+		Channel: channel,
+	}
+}
+
+// Subscribe subscribes to the end of bookings.
+func (d *Demos) Subscribe(bus *events.Bus) {
+	bus.Subscribe(d.BookingEnded)
+}
+
+// BookingEnded uploads the demos of the booking in the background, so the upload doesn't hold up
+// the other subscribers.
+func (d *Demos) BookingEnded(e *events.BookingEnded) {
+	go d.upload(e)
+}
+
+func (d *Demos) upload(e *events.BookingEnded) {
+	message, err := d.Upload(e.Server)
+	if err != nil || message == "" {
+		// No demos were recorded.
+		return
+	}
+
+	if err := d.Send(d.Channel, fmt.Sprintf("%s: %s", e.UserMention, message)); err != nil {
+		e.Server.Log().WithError(err).WithField(logging.UserIDField, e.UserID).Error("Failed to send demo links")
+	}
+}
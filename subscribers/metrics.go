@@ -0,0 +1,23 @@
+package subscribers
+
+import (
+	"alex-j-butler.com/tf2-booking/events"
+	"alex-j-butler.com/tf2-booking/metrics"
+)
+
+// Metrics counts the bookings started & ended for the metrics endpoint.
+type Metrics struct{}
+
+// Subscribe subscribes to the start & end of bookings.
+func (m Metrics) Subscribe(bus *events.Bus) {
+	bus.Subscribe(m.BookingStarted)
+	bus.Subscribe(m.BookingEnded)
+}
+
+func (m Metrics) BookingStarted(e *events.BookingStarted) {
+	metrics.BookingsStarted.Inc()
+}
+
+func (m Metrics) BookingEnded(e *events.BookingEnded) {
+	metrics.BookingsEnded.Inc(string(e.Reason))
+}
@@ -0,0 +1,117 @@
+package subscribers
+
+import (
+	"fmt"
+
+	"alex-j-butler.com/tf2-booking/events"
+	"alex-j-butler.com/tf2-booking/logging"
+	"alex-j-butler.com/tf2-booking/messages"
+	"alex-j-butler.com/tf2-booking/util"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// endReasonMessages are the keys of the messages explaining to the booker why their booking was ended,
+// for bookings the booker didn't end themselves.
+var endReasonMessages = map[events.EndReason]string{
+	events.Expired: "auto_unbook.expired",
+	events.Idle:    "auto_unbook.idle",
+	events.Stopped: "auto_unbook.stopped",
+}
+
+// Notifier lets the bookers & admins know about booking events on Discord.
+type Notifier struct {
+	// Send sends a message to a channel.
+	Send func(channelID string, message string) error
+
+	// DM sends a private message to a user.
+	DM func(userID string, message string) error
+
+	// NotifyAdmins sends a message to the admins.
+	NotifyAdmins func(message string)
+
+	// Text renders a message in the user's language, and AdminText in the admins' language.
+	Text      func(userID string, key string, data messages.Data) string
+	AdminText func(key string, data messages.Data) string
+
+	// Channel is where bookers are notified about their bookings.
+	Channel string
+}
+
+// Subscribe subscribes the notifier to the events it notifies about.
+func (n *Notifier) Subscribe(bus *events.Bus) {
+	bus.Subscribe(n.BookingStartFailed)
+	bus.Subscribe(n.ServerStopFailed)
+	bus.Subscribe(n.BookingEnded)
+	bus.Subscribe(n.ServerErrored)
+	bus.Subscribe(n.PlayerReported)
+}
+
+// BookingStartFailed lets the booker know their server failed to start.
+func (n *Notifier) BookingStartFailed(e *events.BookingStartFailed) {
+	n.dm(e.UserID, n.Text(e.UserID, "booking.start_failed", nil))
+}
+
+// ServerStopFailed lets the booker know their server failed to stop.
+func (n *Notifier) ServerStopFailed(e *events.ServerStopFailed) {
+	n.dm(e.UserID, n.Text(e.UserID, "booking.stop_failed", nil))
+}
+
+// BookingEnded lets the booker know their booking was ended by someone else.
+func (n *Notifier) BookingEnded(e *events.BookingEnded) {
+	var message string
+	switch e.Reason {
+	case events.ReturnedIngame:
+		message = n.Text(e.UserID, "unbook.returned_ingame", messages.Data{"Username": e.By})
+	case events.Expired, events.Idle, events.Stopped:
+		reason := n.Text(e.UserID, endReasonMessages[e.Reason], nil)
+		message = n.Text(e.UserID, "auto_unbook.unbooked", messages.Data{"Reason": reason})
+	case events.Forced:
+		message = n.Text(e.UserID, "force_unbook.notify", nil)
+	default:
+		// The booker ended the booking themselves, and has already been replied to.
+		return
+	}
+
+	n.send(fmt.Sprintf("%s: %s", e.UserMention, message))
+}
+
+// ServerErrored lets the admins know a server can't be contacted.
+func (n *Notifier) ServerErrored(e *events.ServerErrored) {
+	if e.Booker != "" {
+		n.NotifyAdmins(n.AdminText("query_error.booked", messages.Data{
+			"Server":  e.Server.Name,
+			"Retries": e.Retries,
+			"Booker":  e.Booker,
+		}))
+	} else {
+		n.NotifyAdmins(n.AdminText("query_error.unbooked", messages.Data{
+			"Server":  e.Server.Name,
+			"Retries": e.Retries,
+		}))
+	}
+}
+
+// PlayerReported lets the admins know a player reported a server.
+func (n *Notifier) PlayerReported(e *events.PlayerReported) {
+	steamID := util.FromSteamID3(e.SteamID)
+
+	n.NotifyAdmins(n.AdminText("report.notify", messages.Data{
+		"Server":   e.Server,
+		"Username": e.Username,
+		"Profile":  steamID.GetCommunityURL(),
+		"Reason":   e.Reason,
+	}))
+}
+
+func (n *Notifier) send(message string) {
+	if err := n.Send(n.Channel, message); err != nil {
+		log.WithError(err).WithField("channel_id", n.Channel).Error("Failed to send notification")
+	}
+}
+
+func (n *Notifier) dm(userID string, message string) {
+	if err := n.DM(userID, message); err != nil {
+		logging.User(userID).WithError(err).Error("Failed to send notification")
+	}
+}
@@ -0,0 +1,26 @@
+package subscribers
+
+import (
+	"alex-j-butler.com/tf2-booking/events"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Presence updates the bot's presence when the number of available servers changes.
+type Presence struct {
+	// Update updates the bot's presence.
+	Update func() error
+}
+
+// Subscribe subscribes to the events that change the number of available servers.
+func (p *Presence) Subscribe(bus *events.Bus) {
+	bus.Subscribe(func(*events.BookingStarted) { p.refresh() })
+	bus.Subscribe(func(*events.BookingStartFailed) { p.refresh() })
+	bus.Subscribe(func(*events.BookingEnded) { p.refresh() })
+}
+
+func (p *Presence) refresh() {
+	if err := p.Update(); err != nil {
+		log.WithError(err).Error("Failed to update game string")
+	}
+}
@@ -0,0 +1,203 @@
+package subscribers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"alex-j-butler.com/tf2-booking/audit"
+	"alex-j-butler.com/tf2-booking/events"
+	"alex-j-butler.com/tf2-booking/messages"
+	"alex-j-butler.com/tf2-booking/servers"
+)
+
+// sent records the messages sent by the subscribers.
+type sent struct {
+	messages []string
+}
+
+func (s *sent) send(channelID string, message string) error {
+	s.messages = append(s.messages, fmt.Sprintf("%s %s", channelID, message))
+	return nil
+}
+
+func newNotifier(s *sent) *Notifier {
+	return &Notifier{
+		Send: s.send,
+		DM: func(userID string, message string) error {
+			return s.send("dm:"+userID, message)
+		},
+		NotifyAdmins: func(message string) {
+			s.send("admins", message)
+		},
+		Text: func(userID string, key string, data messages.Data) string {
+			return messages.Render(messages.EnglishLocale, key, data)
+		},
+		AdminText: func(key string, data messages.Data) string {
+			return messages.Render(messages.EnglishLocale, key, data)
+		},
+		Channel: "default",
+	}
+}
+
+func TestNotifierBookingEnded(t *testing.T) {
+	tests := []struct {
+		reason   events.EndReason
+		expected string
+	}{
+		{events.Returned, ""},
+		{events.ReturnedIngame, "default <@1>: Your server was returned by `player` ingame."},
+		{events.Expired, "default <@1>: Your server was automatically unbooked (booking time is up)."},
+		{events.Idle, "default <@1>: Your server was automatically unbooked (not enough players)."},
+		{events.Forced, "default <@1>: Your server was unbooked by an admin."},
+	}
+
+	for _, test := range tests {
+		s := &sent{}
+		bus := events.New()
+		newNotifier(s).Subscribe(bus)
+
+		bus.Publish(&events.BookingEnded{UserID: "1", UserMention: "<@1>", Reason: test.reason, By: "player"})
+
+		actual := strings.Join(s.messages, "\n")
+		if actual != test.expected {
+			t.Errorf("Expected %s to send \"%s\", got \"%s\"", test.reason, test.expected, actual)
+		}
+	}
+}
+
+func TestNotifierStartFailed(t *testing.T) {
+	s := &sent{}
+	bus := events.New()
+	newNotifier(s).Subscribe(bus)
+
+	bus.Publish(&events.BookingStartFailed{Server: &servers.Server{Name: "Server 1"}, UserID: "1", Err: errors.New("failed")})
+
+	if len(s.messages) != 1 || !strings.HasPrefix(s.messages[0], "dm:1 ") {
+		t.Errorf("Expected a private message to the booker, got %v", s.messages)
+	}
+}
+
+func TestNotifierServerErrored(t *testing.T) {
+	s := &sent{}
+	bus := events.New()
+	newNotifier(s).Subscribe(bus)
+
+	bus.Publish(&events.ServerErrored{Server: &servers.Server{Name: "Server 1"}, Retries: 3, Booker: "user"})
+	bus.Publish(&events.ServerErrored{Server: &servers.Server{Name: "Server 2"}, Retries: 3})
+
+	if len(s.messages) != 2 || !strings.Contains(s.messages[0], "booked by `user`") || !strings.Contains(s.messages[1], "while unbooked") {
+		t.Errorf("Expected a booked & unbooked message to the admins, got %v", s.messages)
+	}
+}
+
+func TestDemosSendsLinks(t *testing.T) {
+	s := &sent{}
+	bus := events.New()
+
+	uploaded := ""
+	done := make(chan struct{})
+	d := &Demos{
+		Upload: func(server *servers.Server) (string, error) {
+			uploaded = server.Name
+			return "demo links", nil
+		},
+		Send: func(channelID string, message string) error {
+			defer close(done)
+			return s.send(channelID, message)
+		},
+		Channel: "default",
+	}
+	d.Subscribe(bus)
+
+	bus.Publish(&events.BookingEnded{Server: &servers.Server{Name: "Server 1"}, UserMention: "<@1>"})
+
+	// The demos are uploaded in the background.
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the demo links to be sent")
+	}
+
+	if uploaded != "Server 1" {
+		t.Errorf("Expected the demos of \"Server 1\" to be uploaded, got \"%s\"", uploaded)
+	}
+	if len(s.messages) != 1 || s.messages[0] != "default <@1>: demo links" {
+		t.Errorf("Expected the demo links to be sent, got %v", s.messages)
+	}
+}
+
+func TestDemosWithoutRecording(t *testing.T) {
+	s := &sent{}
+	bus := events.New()
+
+	uploaded := make(chan struct{})
+	d := &Demos{
+		Upload: func(server *servers.Server) (string, error) {
+			defer close(uploaded)
+			return "", errors.New("no demos")
+		},
+		Send: s.send,
+	}
+	d.Subscribe(bus)
+
+	bus.Publish(&events.BookingEnded{Server: &servers.Server{Name: "Server 1"}})
+
+	<-uploaded
+	time.Sleep(10 * time.Millisecond)
+
+	if len(s.messages) != 0 {
+		t.Errorf("Expected no messages without demos, got %v", s.messages)
+	}
+}
+
+func TestPresenceRefreshes(t *testing.T) {
+	bus := events.New()
+
+	var updates int
+	p := &Presence{Update: func() error {
+		updates++
+		return nil
+	}}
+	p.Subscribe(bus)
+
+	bus.Publish(&events.BookingStarted{})
+	bus.Publish(&events.BookingExtended{})
+	bus.Publish(&events.BookingEnded{})
+
+	if updates != 2 {
+		t.Errorf("Expected 2 presence updates, got %d", updates)
+	}
+}
+
+// recorded records the audit events.
+type recorded struct {
+	events []audit.Event
+}
+
+func (r *recorded) Log(event audit.Event) {
+	r.events = append(r.events, event)
+}
+
+func TestAuditorRecordsEvents(t *testing.T) {
+	r := &recorded{}
+	bus := events.New()
+	(&Auditor{Recorder: r}).Subscribe(bus)
+
+	server := &servers.Server{Name: "Server 1"}
+	bus.Publish(&events.BookingStarted{Server: server, UserID: "1", Preset: "ultiduo", Duration: time.Hour})
+	bus.Publish(&events.BookingEnded{Server: server, UserID: "1", Reason: events.Idle})
+	bus.Publish(&events.BookingEnded{Server: server, UserID: "1", Reason: events.Forced})
+
+	if len(r.events) != 2 {
+		t.Fatalf("Expected 2 audit events, got %d", len(r.events))
+	}
+	if r.events[0].Type != audit.Book || r.events[0].Reason != "Preset `ultiduo`" || r.events[0].Duration != time.Hour {
+		t.Errorf("Unexpected book event %+v", r.events[0])
+	}
+	if r.events[1].Type != audit.IdleUnbook || r.events[1].Reason != "Idle timeout from too little players" {
+		t.Errorf("Unexpected idle unbook event %+v", r.events[1])
+	}
+}
@@ -11,5 +11,10 @@ func ChooseRandomTip() string {
 	// Set the random seed (this doesn't need to be secure since we're just using it for a tip message).
 	rand.Seed(time.Now().UTC().UnixNano())
 
-	return config.Conf.Tips[rand.Intn(len(config.Conf.Tips))]
+	tips := config.Get().Tips
+	if len(tips) == 0 {
+		return ""
+	}
+
+	return tips[rand.Intn(len(tips))]
 }
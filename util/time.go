@@ -38,6 +38,11 @@ func (du *DurationUtil) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	return err
 }
 
+// MarshalYAML implements yaml.Marshaler interface.
+func (du DurationUtil) MarshalYAML() (interface{}, error) {
+	return du.String(), nil
+}
+
 // ToHuman converts a duration into a human-readable string with support for only hours and minutes.
 // Prints in the format '1 hour 45 minutes'
 func ToHuman(duration *time.Duration) string {
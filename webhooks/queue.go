@@ -0,0 +1,211 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	redis "gopkg.in/redis.v5"
+)
+
+// Delivery is a single webhook payload waiting to be delivered to an endpoint.
+type Delivery struct {
+	ID    string `json:"id"`
+	URL   string `json:"url"`
+	Event string `json:"event"`
+	Body  []byte `json:"body"`
+
+	// Attempts is the number of failed attempts so far.
+	Attempts    int       `json:"attempts"`
+	NextAttempt time.Time `json:"next_attempt"`
+
+	// member is the delivery as it's stored in the RedisQueue.
+	member string
+}
+
+// Queue stores the deliveries until they've been delivered.
+// Deliveries are leased while they're attempted, and stay in the queue until they're completed,
+// so a delivery that was being attempted when the bot stopped is attempted again.
+type Queue interface {
+	// Push adds the delivery, to be attempted at its next attempt time.
+	Push(delivery Delivery) error
+
+	// Lease returns the next delivery that is due at the time, or false if there isn't one.
+	// The delivery isn't due again until the lease expires, unless it's retried.
+	Lease(now time.Time, lease time.Duration) (Delivery, bool, error)
+
+	// Retry replaces the leased delivery with its next attempt.
+	Retry(delivery Delivery) error
+
+	// Complete removes the leased delivery, once it's delivered or given up on.
+	Complete(delivery Delivery) error
+}
+
+// RedisQueue is a Queue persisted in a Redis sorted set, scored by the next attempt time,
+// so pending deliveries survive restarts.
+type RedisQueue struct {
+	Redis *redis.Client
+	Key   string
+
+	// Redis script to lease the next due delivery, by scoring it with the lease's expiry.
+	lease *redis.Script
+}
+
+// DefaultQueueKey is the Redis key of the webhook delivery queue.
+const DefaultQueueKey = "webhooks.queue"
+
+// NewRedisQueue creates a queue stored in the Redis sorted set at the key.
+func NewRedisQueue(redisClient *redis.Client, key string) *RedisQueue {
+	return &RedisQueue{
+		Redis: redisClient,
+		Key:   key,
+		lease: redis.NewScript(`
+			local members = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", ARGV[1], "LIMIT", 0, 1)
+			if (#members == 0) then
+				return false
+			end
+			redis.call("ZADD", KEYS[1], ARGV[2], members[1])
+			return members[1]
+		`),
+	}
+}
+
+func (q *RedisQueue) Push(delivery Delivery) error {
+	member, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+
+	return q.Redis.ZAdd(q.Key, redis.Z{
+		Score:  float64(delivery.NextAttempt.Unix()),
+		Member: string(member),
+	}).Err()
+}
+
+func (q *RedisQueue) Lease(now time.Time, lease time.Duration) (Delivery, bool, error) {
+	// Leasing in a script means only one caller attempts each delivery.
+	result, err := q.lease.Run(q.Redis, []string{q.Key}, now.Unix(), now.Add(lease).Unix()).Result()
+	if err == redis.Nil {
+		return Delivery{}, false, nil
+	}
+	if err != nil {
+		return Delivery{}, false, err
+	}
+
+	member, _ := result.(string)
+
+	var delivery Delivery
+	if err := json.Unmarshal([]byte(member), &delivery); err != nil {
+		// Drop the delivery, as it can never be attempted.
+		q.Redis.ZRem(q.Key, member)
+		return Delivery{}, false, fmt.Errorf("invalid queued webhook: %s", err)
+	}
+	delivery.member = member
+
+	return delivery, true, nil
+}
+
+func (q *RedisQueue) Retry(delivery Delivery) error {
+	member, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.Redis.TxPipelined(func(pipe *redis.Pipeline) error {
+		pipe.ZRem(q.Key, delivery.member)
+		pipe.ZAdd(q.Key, redis.Z{
+			Score:  float64(delivery.NextAttempt.Unix()),
+			Member: string(member),
+		})
+		return nil
+	})
+	return err
+}
+
+func (q *RedisQueue) Complete(delivery Delivery) error {
+	return q.Redis.ZRem(q.Key, delivery.member).Err()
+}
+
+// MemoryQueue is a Queue held in memory, losing pending deliveries on restart.
+type MemoryQueue struct {
+	mu      sync.Mutex
+	entries []memoryEntry
+}
+
+// memoryEntry is a queued delivery, and when it's due, which is the lease's expiry while it's leased.
+type memoryEntry struct {
+	delivery Delivery
+	due      time.Time
+}
+
+// NewMemoryQueue creates an empty in-memory queue.
+func NewMemoryQueue() *MemoryQueue {
+	return &MemoryQueue{}
+}
+
+func (q *MemoryQueue) Push(delivery Delivery) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.entries = append(q.entries, memoryEntry{delivery: delivery, due: delivery.NextAttempt})
+	return nil
+}
+
+func (q *MemoryQueue) Lease(now time.Time, lease time.Duration) (Delivery, bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	// Lease the delivery that has been due the longest.
+	next := -1
+	for i, entry := range q.entries {
+		if !entry.due.After(now) && (next == -1 || entry.due.Before(q.entries[next].due)) {
+			next = i
+		}
+	}
+	if next == -1 {
+		return Delivery{}, false, nil
+	}
+
+	q.entries[next].due = now.Add(lease)
+	return q.entries[next].delivery, true, nil
+}
+
+func (q *MemoryQueue) Retry(delivery Delivery) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if i := q.find(delivery.ID); i != -1 {
+		q.entries[i] = memoryEntry{delivery: delivery, due: delivery.NextAttempt}
+	}
+	return nil
+}
+
+func (q *MemoryQueue) Complete(delivery Delivery) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if i := q.find(delivery.ID); i != -1 {
+		q.entries = append(q.entries[:i], q.entries[i+1:]...)
+	}
+	return nil
+}
+
+// find returns the index of the delivery with the ID, or -1 if it isn't queued.
+func (q *MemoryQueue) find(id string) int {
+	for i, entry := range q.entries {
+		if entry.delivery.ID == id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Len returns the number of deliveries in the queue, including the leased deliveries.
+func (q *MemoryQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.entries)
+}
@@ -0,0 +1,311 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"alex-j-butler.com/tf2-booking/audit"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Headers sent with each delivery.
+const (
+	SignatureHeader = "X-Booking-Signature"
+	EventHeader     = "X-Booking-Event"
+	DeliveryHeader  = "X-Booking-Delivery"
+)
+
+// Retry settings used when the dispatcher doesn't set its own.
+const (
+	DefaultMaxAttempts = 8
+	DefaultBackoff     = 10 * time.Second
+	DefaultMaxBackoff  = 30 * time.Minute
+	DefaultTimeout     = 10 * time.Second
+)
+
+// Endpoint is a URL that receives the booking events.
+type Endpoint struct {
+	URL string
+
+	// Secret is the key used to sign the payloads sent to the endpoint.
+	Secret string
+
+	// Events are the event types sent to the endpoint, all events if empty.
+	Events []string
+}
+
+// Wants returns whether the endpoint receives the event type.
+func (e Endpoint) Wants(eventType audit.EventType) bool {
+	if len(e.Events) == 0 {
+		return true
+	}
+
+	for _, event := range e.Events {
+		if event == string(eventType) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Payload is the JSON body sent to the endpoints.
+type Payload struct {
+	ID        string    `json:"id"`
+	Event     string    `json:"event"`
+	Timestamp time.Time `json:"timestamp"`
+
+	UserID   string `json:"user_id,omitempty"`
+	Username string `json:"username,omitempty"`
+	Server   string `json:"server,omitempty"`
+
+	// Duration is in seconds.
+	Duration int64  `json:"duration,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// NewPayload creates the payload of a booking event.
+func NewPayload(id string, event audit.Event) Payload {
+	return Payload{
+		ID:        id,
+		Event:     string(event.Type),
+		Timestamp: event.Time.UTC(),
+		UserID:    event.UserID,
+		Username:  event.Username,
+		Server:    event.Server,
+		Duration:  int64(event.Duration / time.Second),
+		Reason:    event.Reason,
+	}
+}
+
+// Sign returns the signature header value of the body, a hex encoded HMAC-SHA256 with the secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify returns whether the signature header value matches the body, for use by receivers.
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// Dispatcher queues booking events for each endpoint & delivers them, retrying failed deliveries
+// with exponential backoff until MaxAttempts is reached.
+// A nil Dispatcher discards events, for when no webhooks are configured.
+type Dispatcher struct {
+	Endpoints []Endpoint
+	Queue     Queue
+	Client    *http.Client
+
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+
+	// delivering prevents deliveries being attempted concurrently.
+	delivering sync.Mutex
+}
+
+// New creates a Dispatcher that delivers events to the endpoints from the queue.
+func New(endpoints []Endpoint, queue Queue) *Dispatcher {
+	return &Dispatcher{
+		Endpoints:   endpoints,
+		Queue:       queue,
+		Client:      &http.Client{Timeout: DefaultTimeout},
+		MaxAttempts: DefaultMaxAttempts,
+		Backoff:     DefaultBackoff,
+		MaxBackoff:  DefaultMaxBackoff,
+	}
+}
+
+// Log queues a delivery of the event for each endpoint that receives it, and starts delivering them.
+func (d *Dispatcher) Log(event audit.Event) {
+	if d == nil {
+		return
+	}
+
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	queued := false
+	for _, endpoint := range d.Endpoints {
+		if !endpoint.Wants(event.Type) {
+			continue
+		}
+
+		id := newID()
+		body, err := json.Marshal(NewPayload(id, event))
+		if err != nil {
+			log.WithError(err).WithFields(log.Fields{"event": event.Type, "url": endpoint.URL}).Error("Failed to encode webhook payload")
+			continue
+		}
+
+		err = d.Queue.Push(Delivery{
+			ID:          id,
+			URL:         endpoint.URL,
+			Event:       string(event.Type),
+			Body:        body,
+			NextAttempt: event.Time,
+		})
+		if err != nil {
+			log.WithError(err).WithField("url", endpoint.URL).Error("Failed to queue webhook")
+			continue
+		}
+		queued = true
+	}
+
+	if queued {
+		go d.Deliver()
+	}
+}
+
+// Deliver attempts each delivery that is due, requeueing the failed deliveries.
+// Each delivery stays leased in the queue until it's delivered or given up on, so it isn't lost if the bot stops.
+func (d *Dispatcher) Deliver() {
+	if d == nil {
+		return
+	}
+
+	d.delivering.Lock()
+	defer d.delivering.Unlock()
+
+	// The retried deliveries aren't due again at this time, so they aren't attempted twice in one run.
+	now := time.Now()
+	for {
+		delivery, ok, err := d.Queue.Lease(now, d.lease())
+		if err != nil {
+			log.WithError(err).Error("Failed to retrieve queued webhooks")
+			return
+		}
+		if !ok {
+			return
+		}
+
+		endpoint, ok := d.endpoint(delivery.URL)
+		if !ok {
+			// The endpoint has been removed from the configuration since the delivery was queued.
+			delivery.log().Warn("Dropping webhook to removed endpoint")
+			d.complete(delivery)
+			continue
+		}
+
+		err = d.send(endpoint, delivery)
+		if err == nil {
+			d.complete(delivery)
+			continue
+		}
+
+		delivery.Attempts++
+		if delivery.Attempts >= d.MaxAttempts {
+			delivery.log().WithError(err).Error("Giving up on webhook")
+			d.complete(delivery)
+			continue
+		}
+
+		delivery.NextAttempt = time.Now().Add(d.backoff(delivery.Attempts))
+		delivery.log().WithError(err).WithField("next_attempt", delivery.NextAttempt.Format(time.RFC3339)).Warn("Webhook failed, retrying")
+
+		if err := d.Queue.Retry(delivery); err != nil {
+			delivery.log().WithError(err).Error("Failed to requeue webhook")
+		}
+	}
+}
+
+// log returns a log entry with the delivery's fields.
+func (delivery Delivery) log() *log.Entry {
+	return log.WithFields(log.Fields{
+		"delivery_id": delivery.ID,
+		"url":         delivery.URL,
+		"event":       delivery.Event,
+		"attempts":    delivery.Attempts,
+	})
+}
+
+// complete removes the delivery from the queue.
+func (d *Dispatcher) complete(delivery Delivery) {
+	if err := d.Queue.Complete(delivery); err != nil {
+		delivery.log().WithError(err).Error("Failed to remove webhook from the queue")
+	}
+}
+
+// lease returns how long a delivery is leased for while it's attempted, which outlasts the request's timeout.
+func (d *Dispatcher) lease() time.Duration {
+	timeout := DefaultTimeout
+	if d.Client != nil && d.Client.Timeout > 0 {
+		timeout = d.Client.Timeout
+	}
+
+	return 2 * timeout
+}
+
+// send posts the delivery to the endpoint, signed with the endpoint's secret.
+func (d *Dispatcher) send(endpoint Endpoint, delivery Delivery) error {
+	req, err := http.NewRequest(http.MethodPost, endpoint.URL, bytes.NewReader(delivery.Body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	if endpoint.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(endpoint.Secret, delivery.Body))
+	}
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return nil
+}
+
+// endpoint returns the configured endpoint with the URL.
+func (d *Dispatcher) endpoint(url string) (Endpoint, bool) {
+	for _, endpoint := range d.Endpoints {
+		if endpoint.URL == url {
+			return endpoint, true
+		}
+	}
+
+	return Endpoint{}, false
+}
+
+// backoff returns how long to wait before the next attempt, doubling with each failed attempt.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	backoff := d.Backoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= d.MaxBackoff {
+			return d.MaxBackoff
+		}
+	}
+
+	return backoff
+}
+
+// newID returns a random delivery ID.
+func newID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,231 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"alex-j-butler.com/tf2-booking/audit"
+)
+
+// receiver is a local webhook endpoint that records the requests it receives.
+type receiver struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+
+	// failures is the number of requests to fail before succeeding.
+	failures int
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := ioutil.ReadAll(req.Body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (r *receiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.requests)
+}
+
+// eventually fails the test if the condition isn't met within a second.
+func eventually(t *testing.T, message string, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal(message)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDispatcherSendsSignedPayload(t *testing.T) {
+	r := &receiver{}
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	d := New([]Endpoint{{URL: server.URL, Secret: "secret"}}, NewMemoryQueue())
+	d.Log(audit.Event{
+		Type:     audit.Book,
+		UserID:   "1234",
+		Username: "user",
+		Server:   "Server 1",
+		Duration: 2 * time.Hour,
+		Reason:   "ultiduo",
+	})
+
+	eventually(t, "Expected the webhook to be delivered", func() bool { return r.count() == 1 })
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	req, body := r.requests[0], r.bodies[0]
+	if !Verify("secret", body, req.Header.Get(SignatureHeader)) {
+		t.Errorf("Expected a valid signature, got '%s'", req.Header.Get(SignatureHeader))
+	}
+	if Verify("other", body, req.Header.Get(SignatureHeader)) {
+		t.Errorf("Expected the signature to not verify with another secret")
+	}
+	if event := req.Header.Get(EventHeader); event != "book" {
+		t.Errorf("Expected event header 'book', got '%s'", event)
+	}
+
+	var payload Payload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("Failed to decode payload: %s", err)
+	}
+	if payload.ID != req.Header.Get(DeliveryHeader) {
+		t.Errorf("Expected payload ID '%s' to match the delivery header '%s'", payload.ID, req.Header.Get(DeliveryHeader))
+	}
+	if payload.Event != "book" || payload.UserID != "1234" || payload.Server != "Server 1" || payload.Duration != 7200 || payload.Reason != "ultiduo" {
+		t.Errorf("Unexpected payload %+v", payload)
+	}
+}
+
+func TestDispatcherFiltersEvents(t *testing.T) {
+	all, filtered := &receiver{}, &receiver{}
+	allServer, filteredServer := httptest.NewServer(all), httptest.NewServer(filtered)
+	defer allServer.Close()
+	defer filteredServer.Close()
+
+	d := New([]Endpoint{
+		{URL: allServer.URL},
+		{URL: filteredServer.URL, Events: []string{"unbook"}},
+	}, NewMemoryQueue())
+
+	d.Log(audit.Event{Type: audit.Book, Server: "Server 1"})
+	d.Log(audit.Event{Type: audit.Unbook, Server: "Server 1"})
+
+	eventually(t, "Expected both events to be delivered to the unfiltered endpoint", func() bool { return all.count() == 2 })
+	eventually(t, "Expected the unbook event to be delivered to the filtered endpoint", func() bool { return filtered.count() == 1 })
+
+	filtered.mu.Lock()
+	defer filtered.mu.Unlock()
+
+	if event := filtered.requests[0].Header.Get(EventHeader); event != "unbook" {
+		t.Errorf("Expected only the unbook event, got '%s'", event)
+	}
+}
+
+func TestDispatcherRetriesFailedDeliveries(t *testing.T) {
+	r := &receiver{failures: 2}
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	queue := NewMemoryQueue()
+	d := New([]Endpoint{{URL: server.URL}}, queue)
+	d.Backoff = time.Hour
+
+	// Deliver synchronously, rather than through Log.
+	queue.Push(Delivery{ID: "1", URL: server.URL, Event: "book", Body: []byte("{}")})
+	d.Deliver()
+
+	if r.count() != 1 || queue.Len() != 1 {
+		t.Fatalf("Expected the failed delivery to be requeued, got %d requests & %d queued", r.count(), queue.Len())
+	}
+
+	// The retry isn't due until after the backoff.
+	d.Deliver()
+	if r.count() != 1 {
+		t.Fatalf("Expected no retry before the backoff, got %d requests", r.count())
+	}
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		due, ok, _ := queue.Lease(time.Now().Add(24*time.Hour), time.Minute)
+		if !ok || due.Attempts != attempt {
+			t.Fatalf("Expected a delivery with %d attempts, got %+v", attempt, due)
+		}
+		due.NextAttempt = time.Now()
+		queue.Retry(due)
+		d.Deliver()
+	}
+
+	if r.count() != 3 || queue.Len() != 0 {
+		t.Errorf("Expected the delivery to succeed on the third attempt, got %d requests & %d queued", r.count(), queue.Len())
+	}
+}
+
+func TestDispatcherGivesUp(t *testing.T) {
+	r := &receiver{failures: 10}
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	queue := NewMemoryQueue()
+	d := New([]Endpoint{{URL: server.URL}}, queue)
+	d.Backoff = 0
+	d.MaxAttempts = 3
+
+	queue.Push(Delivery{ID: "1", URL: server.URL, Event: "book", Body: []byte("{}")})
+	for i := 0; i < 5; i++ {
+		d.Deliver()
+	}
+
+	if r.count() != 3 || queue.Len() != 0 {
+		t.Errorf("Expected the delivery to be dropped after 3 attempts, got %d requests & %d queued", r.count(), queue.Len())
+	}
+}
+
+func TestMemoryQueueLease(t *testing.T) {
+	queue := NewMemoryQueue()
+	now := time.Now()
+	queue.Push(Delivery{ID: "1", NextAttempt: now})
+	queue.Push(Delivery{ID: "2", NextAttempt: now.Add(time.Hour)})
+
+	delivery, ok, _ := queue.Lease(now, time.Minute)
+	if !ok || delivery.ID != "1" {
+		t.Fatalf("Expected the due delivery to be leased, got %+v", delivery)
+	}
+	if _, ok, _ := queue.Lease(now, time.Minute); ok {
+		t.Error("Expected the leased delivery not to be leased again")
+	}
+
+	// The lease expires without the delivery being completed, eg. if the bot stopped while attempting it.
+	delivery, ok, _ = queue.Lease(now.Add(time.Minute), time.Minute)
+	if !ok || delivery.ID != "1" {
+		t.Fatalf("Expected the delivery to be due again after the lease, got %+v", delivery)
+	}
+
+	queue.Complete(delivery)
+	if queue.Len() != 1 {
+		t.Errorf("Expected the completed delivery to be removed, got %d queued", queue.Len())
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := New(nil, NewMemoryQueue())
+	d.Backoff = time.Second
+	d.MaxBackoff = 5 * time.Second
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, backoff := range expected {
+		if actual := d.backoff(i + 1); actual != backoff {
+			t.Errorf("Expected backoff %s after %d attempts, got %s", backoff, i+1, actual)
+		}
+	}
+}
+
+func TestNilDispatcher(t *testing.T) {
+	var d *Dispatcher
+	d.Log(audit.Event{Type: audit.Book})
+	d.Deliver()
+}